@@ -6,12 +6,16 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/pakerfeldt/knx-mqtt/internal/bridge"
 	"github.com/pakerfeldt/knx-mqtt/internal/knx"
+	"github.com/pakerfeldt/knx-mqtt/internal/metrics"
 	"github.com/pakerfeldt/knx-mqtt/internal/models"
 	"github.com/pakerfeldt/knx-mqtt/internal/mqtt"
 	"github.com/pakerfeldt/knx-mqtt/internal/parser"
+	"github.com/pakerfeldt/knx-mqtt/internal/secure"
+	"github.com/pakerfeldt/knx-mqtt/internal/state"
 	"github.com/pakerfeldt/knx-mqtt/internal/utils"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -21,7 +25,6 @@ func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
-	var knxItems *models.KNX
 	// Load the configuration
 	var configPath, exists = os.LookupEnv("KNX_MQTT_CONFIG")
 	if !exists {
@@ -34,13 +37,7 @@ func main() {
 	}
 	utils.SetupLogging(cfg.LogLevel, cfg.KNX.EnableLogs)
 
-	if cfg.KNX.ETSExport != "" {
-		knxItems, err = parser.ReadGroupsFromFile(cfg.KNX.ETSExport, cfg.KNX.GaTranslation)
-		if err != nil {
-			log.Fatal().Str("error", fmt.Sprintf("%+v", err)).Msg("Error parsing KNX XML")
-			os.Exit(1)
-		}
-	} else {
+	if len(cfg.KNX.ETSExport) == 0 {
 		if cfg.OutgoingMqttMessage.Type != "bytes" {
 			log.Fatal().Msg("Outgoing MQTT message type can only be 'bytes' when no KNX addresses are imported. Change your config.")
 			os.Exit(1)
@@ -48,14 +45,22 @@ func main() {
 		log.Info().Msg("Outgoing MQTT messages will only be emitted using their address.")
 		cfg.OutgoingMqttMessage.EmitUsingAddress = true
 		cfg.OutgoingMqttMessage.EmitUsingName = false
-		emptyKnx := models.EmptyKNX()
-		knxItems = &emptyKnx
+	}
+
+	knxItems, err := loadKNX(cfg)
+	if err != nil {
+		log.Fatal().Str("error", fmt.Sprintf("%+v", err)).Msg("Error loading group addresses")
+		os.Exit(1)
 	}
 
 	// Create a context that is cancelled on SIGINT (Ctrl+C) or SIGTERM
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	if cfg.Metrics.Listen != "" {
+		go metrics.Serve(ctx, cfg.Metrics.Listen)
+	}
+
 	// Initialize KNX message logger if enabled
 	var knxLogger *knx.KNXLogger
 	if cfg.KNX.KNXLog.Enabled {
@@ -68,18 +73,126 @@ func main() {
 		}
 	}
 
-	knxClient := knx.NewClient(ctx, *cfg, knxItems, knxLogger)
-	mqttClient := mqtt.NewClient(*cfg)
+	var keyring *secure.Keyring
+	if cfg.KNX.Secure.Enabled() {
+		keyring, err = secure.LoadKeyring(cfg.KNX.Secure.Keyring, cfg.KNX.Secure.KeyringPassword)
+		if err != nil {
+			log.Fatal().Str("error", fmt.Sprintf("%+v", err)).Msg("Error loading KNX keyring")
+			os.Exit(1)
+		}
+		log.Info().Str("project", keyring.Project).Msg("KNX keyring loaded")
+	}
+
+	knxClient, err := knx.NewClient(ctx, *cfg, knxItems, keyring, knxLogger)
+	if err != nil {
+		log.Fatal().Str("error", fmt.Sprintf("%+v", err)).Msg("Error setting up KNX client")
+		os.Exit(1)
+	}
+	mqttClient := mqtt.NewClient(*cfg, knxItems)
+
+	cache, err := state.NewCache(cfg.StateCache.File)
+	if err != nil {
+		log.Fatal().Str("error", fmt.Sprintf("%+v", err)).Msg("Error loading state cache")
+		os.Exit(1)
+	}
+	go cache.Run(ctx, cfg.StateCache.PersistInterval)
 
 	// Close upon exiting.
 	defer knxClient.Close()
 	defer mqttClient.Close()
 
-	bridge := bridge.NewBridge(*cfg, knxItems, knxClient, mqttClient)
+	bridge := bridge.NewBridge(*cfg, knxItems, knxClient, mqttClient, cache)
 	bridge.Start()
 
-	<-ctx.Done()
+	reloadOnChange(ctx, configPath, *cfg, bridge)
 
 	stop()
 	log.Info().Msg("Shutting down ...")
+	if err := cache.Save(); err != nil {
+		log.Error().Err(err).Msg("Failed to persist state cache")
+	}
+}
+
+// loadKNX imports the group addresses of the ETS export, applying the overrides, and adds the
+// virtual group addresses.
+func loadKNX(cfg *models.Config) (*models.KNX, error) {
+	if len(cfg.KNX.ETSExport) > 0 {
+		knxItems, err := parser.ReadGroupsFromFiles(cfg.KNX.ETSExport, parser.ImportOptions{
+			GaTranslation:         cfg.KNX.GaTranslation,
+			ProjectPassword:       cfg.KNX.ProjectPassword,
+			Overrides:             cfg.Overrides,
+			DefaultDatapoints:     cfg.KNX.DefaultDatapoints,
+			Validation:            cfg.KNX.Validation,
+			VirtualGroupAddresses: cfg.VirtualGroupAddresses,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ETS export: %w", err)
+		}
+		return knxItems, nil
+	}
+
+	knxItems := models.EmptyKNX()
+	if err := knxItems.AddVirtualGroupAddresses(cfg.VirtualGroupAddresses); err != nil {
+		return nil, fmt.Errorf("error in virtual group addresses: %w", err)
+	}
+	return &knxItems, nil
+}
+
+// watchInterval is how often the ETS export is checked for changes.
+const watchInterval = 5 * time.Second
+
+// reloadOnChange imports the ETS export again on SIGHUP and, if enabled, whenever the file
+// changes, until ctx is done. The settings of the ETS export are read from the config file
+// again, as well as the overrides. All other settings require a restart.
+func reloadOnChange(ctx context.Context, configPath string, cfg models.Config, b *bridge.Bridge) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	// The files are watched again whenever the ETS exports are reloaded from the config file.
+	var changed <-chan struct{}
+	stopWatching := func() {}
+	watch := func() {
+		stopWatching()
+		changed, stopWatching = nil, func() {}
+		if !cfg.KNX.WatchETSExport || len(cfg.KNX.ETSExport) == 0 {
+			return
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		changed, stopWatching = parser.WatchFiles(watchCtx, cfg.KNX.ETSExport.Files(), watchInterval), cancel
+		log.Info().Strs("files", cfg.KNX.ETSExport.Files()).Msg("Watching ETS exports for changes")
+	}
+	watch()
+	defer func() { stopWatching() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			log.Info().Msg("Received SIGHUP, reloading ETS export")
+			reloaded, err := parser.LoadConfig(configPath)
+			if err != nil {
+				log.Error().Str("error", fmt.Sprintf("%+v", err)).Msg("Error loading config, keeping the current group addresses")
+				continue
+			}
+			cfg.KNX.ETSExport = reloaded.KNX.ETSExport
+			cfg.KNX.WatchETSExport = reloaded.KNX.WatchETSExport
+			cfg.KNX.GaTranslation = reloaded.KNX.GaTranslation
+			cfg.KNX.ProjectPassword = reloaded.KNX.ProjectPassword
+			cfg.KNX.DefaultDatapoints = reloaded.KNX.DefaultDatapoints
+			cfg.KNX.Validation = reloaded.KNX.Validation
+			cfg.Overrides = reloaded.Overrides
+			watch()
+		case <-changed:
+			log.Info().Msg("ETS export changed, reloading")
+		}
+
+		knxItems, err := loadKNX(&cfg)
+		if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%+v", err)).Msg("Error reloading group addresses, keeping the current ones")
+			continue
+		}
+		b.Reload(cfg, knxItems)
+	}
 }
@@ -1,27 +1,38 @@
 package bridge
 
 import (
+	"sync/atomic"
+	"time"
+
 	"github.com/pakerfeldt/knx-mqtt/internal/knx"
 	"github.com/pakerfeldt/knx-mqtt/internal/models"
 	"github.com/pakerfeldt/knx-mqtt/internal/mqtt"
 	"github.com/pakerfeldt/knx-mqtt/internal/msg"
+	"github.com/pakerfeldt/knx-mqtt/internal/state"
 	"github.com/rs/zerolog/log"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
 )
 
 type Bridge struct {
 	cfg        *models.Config
-	knxItems   *models.KNX
+	knxItems   atomic.Pointer[models.KNX]
 	knxClient  *knx.KNXClient
 	mqttClient *mqtt.MQTTClient
+	cache      *state.Cache
+	virtual    map[string][]models.VirtualGroupAddress
 }
 
-func NewBridge(config models.Config, knxItems *models.KNX, knxClient *knx.KNXClient, mqttClient *mqtt.MQTTClient) *Bridge {
-	return &Bridge{
+func NewBridge(config models.Config, knxItems *models.KNX, knxClient *knx.KNXClient, mqttClient *mqtt.MQTTClient, cache *state.Cache) *Bridge {
+	bridge := &Bridge{
 		cfg:        &config,
-		knxItems:   knxItems,
 		knxClient:  knxClient,
 		mqttClient: mqttClient,
+		cache:      cache,
+		virtual:    virtualTopics(config.VirtualGroupAddresses),
 	}
+	bridge.knxItems.Store(knxItems)
+	return bridge
 }
 
 func (b *Bridge) Start() {
@@ -30,6 +41,7 @@ func (b *Bridge) Start() {
 	if err != nil {
 		log.Fatal().Err(*err).Msg("Failed to establish connection to MQTT broker")
 	}
+	b.knxClient.OnStatus(b.mqttClient.PublishKNXStatus)
 	err = b.knxClient.Connect(b.handleKNXMessage)
 	if err != nil {
 		log.Fatal().Err(*err).Msg("Error connecting to KNX endpoint")
@@ -37,15 +49,90 @@ func (b *Bridge) Start() {
 }
 
 func (b *Bridge) handleKNXMessage(message *msg.KNXMessage) {
+	if groupAddress, ok := message.GroupAddress(); ok && groupAddress.Ignore {
+		log.Trace().Str("address", message.Destination()).Msg("Ignoring telegram to ignored group address")
+		return
+	}
 	if message.IsResolved() {
 		log.Debug().Str("protocol", "knx").Str("address", message.Destination()).Str("name", message.Name()).Str("value", message.String()).Msg("Incoming")
 	} else {
 		log.Debug().Str("protocol", "knx").Str("address", message.Destination()).Msg("Incoming")
 	}
+	b.updateCache(message)
 	b.mqttClient.Send(*message)
 }
 
 func (b *Bridge) handleMQTTMessage(message *msg.MQTTMessage) {
 	log.Debug().Str("protocol", "mqtt").Str("topic", message.Topic()).Str("payload", string(message.Bytes())).Msgf("Incoming")
+	if b.handleVirtualMessage(message) {
+		return
+	}
+	address, command := message.AddressAndCommand(b.cfg.MQTT.TopicPrefix)
+	if command == "get" {
+		b.answerFromCache(address)
+		return
+	}
 	b.knxClient.Send(*message)
 }
+
+// updateCache records the value of group writes and responses.
+func (b *Bridge) updateCache(message *msg.KNXMessage) {
+	if message.Command() == "GroupValue_Read" {
+		return
+	}
+	address, err := models.ParseGroupAddress(message.Destination())
+	if err != nil {
+		return
+	}
+	b.cache.Update(address, state.Entry{
+		Value:     message.Value(),
+		Bytes:     append([]byte(nil), message.Data()...),
+		Timestamp: time.Now(),
+		Source:    message.Source(),
+	})
+}
+
+// answerFromCache publishes the last known value of address without sending a read to the bus.
+func (b *Bridge) answerFromCache(address string) {
+	event, timestamp, ok := cachedAnswer(b.knxItems.Load(), b.cache, address)
+	if !ok {
+		return
+	}
+	log.Debug().Str("address", address).Time("timestamp", timestamp).Msg("Answering from cache")
+	message := b.knxClient.NewMessage(event)
+	message.SetTimestamp(timestamp)
+	b.mqttClient.SendCached(*message)
+}
+
+// cachedAnswer returns the cached value of address as GroupValue_Response from the device that
+// sent it, and when it was seen on the bus. It returns false if there is no valid cached value.
+func cachedAnswer(knxItems *models.KNX, cache *state.Cache, address string) (knxgo.GroupEvent, time.Time, bool) {
+	var flatAddress models.FlatGroupAddress
+	if groupAddress, exists := knxItems.GetGroupAddress(address); exists {
+		flatAddress = groupAddress.FlatAddress
+	} else {
+		var err error
+		flatAddress, err = models.ParseGroupAddress(address)
+		if err != nil {
+			log.Warn().Str("address", address).Msg("Missing reference to group address")
+			return knxgo.GroupEvent{}, time.Time{}, false
+		}
+	}
+
+	entry, ok := cache.Get(flatAddress)
+	if !ok {
+		log.Info().Str("address", address).Msg("No cached value for group address")
+		return knxgo.GroupEvent{}, time.Time{}, false
+	}
+	source, err := cemi.NewIndividualAddrString(entry.Source)
+	if err != nil {
+		log.Warn().Str("address", address).Str("source", entry.Source).Msg("Invalid source of cached value, not answering")
+		return knxgo.GroupEvent{}, time.Time{}, false
+	}
+	return knxgo.GroupEvent{
+		Command:     knxgo.GroupResponse,
+		Source:      source,
+		Destination: cemi.GroupAddr(flatAddress),
+		Data:        entry.Bytes,
+	}, entry.Timestamp, true
+}
@@ -0,0 +1,45 @@
+package bridge
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/pakerfeldt/knx-mqtt/internal/state"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+func TestCachedAnswer(t *testing.T) {
+	knxItems := models.EmptyKNX()
+	knxItems.AddGroupAddress(models.GroupAddress{FullName: "Lights/Hall", Address: "1/0/1", FlatAddress: 2049, Datapoint: "1.001"})
+	knxItems.AddGroupAddress(models.GroupAddress{FullName: "Lights/Porch", Address: "1/0/2", FlatAddress: 2050, Datapoint: "1.001"})
+	cache, err := state.NewCache("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC)
+	cache.Update(2049, state.Entry{Bytes: []byte{1}, Timestamp: seen, Source: "1.1.5"})
+	cache.Update(2050, state.Entry{Bytes: []byte{0}, Timestamp: seen, Source: "invalid"})
+
+	for _, address := range []string{"1/0/1", "Lights/Hall", "2049"} {
+		event, timestamp, ok := cachedAnswer(&knxItems, cache, address)
+		if !ok {
+			t.Fatalf("cachedAnswer(%s) found no value", address)
+		}
+		if event.Command != knxgo.GroupResponse || event.Destination != cemi.NewGroupAddr3(1, 0, 1) ||
+			event.Source != cemi.NewIndividualAddr3(1, 1, 5) || !bytes.Equal(event.Data, []byte{1}) {
+			t.Errorf("cachedAnswer(%s) = %+v", address, event)
+		}
+		if !timestamp.Equal(seen) {
+			t.Errorf("cachedAnswer(%s) timestamp = %v, want %v", address, timestamp, seen)
+		}
+	}
+
+	for _, address := range []string{"Lights/Porch", "1/0/3", "Lights/Garage"} {
+		if event, _, ok := cachedAnswer(&knxItems, cache, address); ok {
+			t.Errorf("cachedAnswer(%s) = %+v, want no answer", address, event)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package bridge
+
+import (
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// Reload replaces the group addresses of the bridge and both clients with knxItems, imported using
+// the reloaded settings of config, keeping the connections to KNX and MQTT, and publishes a summary
+// of the changes.
+func (b *Bridge) Reload(config models.Config, knxItems *models.KNX) models.KNXDiff {
+	diff := b.knxItems.Load().Diff(knxItems)
+	b.knxItems.Store(knxItems)
+	b.knxClient.SetKNX(knxItems)
+	b.mqttClient.SetConfig(config)
+	b.mqttClient.SetKNX(knxItems)
+
+	log.Info().
+		Int("added", len(diff.Added)).
+		Int("removed", len(diff.Removed)).
+		Int("changed", len(diff.Changed)).
+		Msg("Reloaded group addresses")
+	b.mqttClient.PublishReload(diff)
+	return diff
+}
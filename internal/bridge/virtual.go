@@ -0,0 +1,101 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/pakerfeldt/knx-mqtt/internal/msg"
+	"github.com/rs/zerolog/log"
+)
+
+// virtualTopics maps MQTT topics to the virtual group addresses they feed.
+func virtualTopics(virtual []models.VirtualGroupAddress) map[string][]models.VirtualGroupAddress {
+	topics := make(map[string][]models.VirtualGroupAddress)
+	for _, v := range virtual {
+		topics[v.Topic] = append(topics[v.Topic], v)
+	}
+	return topics
+}
+
+// virtualUpdate is the value of a virtual group address taken from an MQTT message.
+type virtualUpdate struct {
+	address string
+	value   string
+}
+
+// handleVirtualMessage updates the virtual group addresses fed by the topic of message.
+// It returns false if no virtual group address uses the topic.
+func (b *Bridge) handleVirtualMessage(message *msg.MQTTMessage) bool {
+	updates, ok := b.virtualUpdates(message)
+	for _, update := range updates {
+		b.knxClient.UpdateVirtual(update.address, update.value)
+	}
+	return ok
+}
+
+// virtualUpdates returns the values of the virtual group addresses fed by the topic of message.
+// It returns false if no virtual group address uses the topic.
+func (b *Bridge) virtualUpdates(message *msg.MQTTMessage) ([]virtualUpdate, bool) {
+	virtual, ok := b.virtual[message.Topic()]
+	if !ok {
+		return nil, false
+	}
+	updates := make([]virtualUpdate, 0, len(virtual))
+	for _, v := range virtual {
+		value, err := extractValue(message.Bytes(), v.Field)
+		if err != nil {
+			log.Warn().Err(err).Str("topic", message.Topic()).Str("address", v.Address).Msg("Cannot extract value for virtual group address")
+			continue
+		}
+		updates = append(updates, virtualUpdate{address: v.Address, value: value})
+	}
+	return updates, true
+}
+
+// extractValue returns the payload as string, or the value at the dot separated field
+// path if field is set and the payload is a JSON object. Objects are returned as JSON and
+// numbers in plain notation, e.g. 1000000 rather than 1e+06, as integer types require it.
+func extractValue(payload []byte, field string) (string, error) {
+	if field == "" {
+		return strings.TrimSpace(string(payload)), nil
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("payload is not JSON: %w", err)
+	}
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("field %q not found", field)
+		}
+		if value, ok = object[key]; !ok {
+			return "", fmt.Errorf("field %q not found", field)
+		}
+	}
+	switch v := value.(type) {
+	case json.Number:
+		// Integers are kept exactly, other numbers are written without exponent
+		if _, err := v.Int64(); err == nil {
+			return v.String(), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return "", fmt.Errorf("field %q is not a valid number: %w", field, err)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case map[string]interface{}:
+		// Objects are passed on as JSON, e.g. a color for DPT 232.600
+		data, err := json.Marshal(value)
+		return string(data), err
+	case []interface{}, nil:
+		return "", fmt.Errorf("field %q is not a single value", field)
+	}
+	return fmt.Sprintf("%v", value), nil
+}
@@ -0,0 +1,89 @@
+package bridge
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/pakerfeldt/knx-mqtt/internal/msg"
+)
+
+// fakeMQTTMessage is a received MQTT message used to drive the bridge in tests.
+type fakeMQTTMessage struct {
+	topic   string
+	payload []byte
+}
+
+func (m fakeMQTTMessage) Duplicate() bool   { return false }
+func (m fakeMQTTMessage) Qos() byte         { return 0 }
+func (m fakeMQTTMessage) Retained() bool    { return false }
+func (m fakeMQTTMessage) Topic() string     { return m.topic }
+func (m fakeMQTTMessage) MessageID() uint16 { return 0 }
+func (m fakeMQTTMessage) Payload() []byte   { return m.payload }
+func (m fakeMQTTMessage) Ack()              {}
+
+func TestExtractValue(t *testing.T) {
+	tests := []struct {
+		payload string
+		field   string
+		want    string
+		wantErr bool
+	}{
+		{" 21.5\n", "", "21.5", false},
+		{`{"temperature":21.5}`, "temperature", "21.5", false},
+		{`{"sensor":{"temperature":-3}}`, "sensor.temperature", "-3", false},
+		{`{"energy":1000000}`, "energy", "1000000", false},
+		{`{"energy":1e6}`, "energy", "1000000", false},
+		{`{"counter":9007199254740993}`, "counter", "9007199254740993", false},
+		{`{"small":0.000001}`, "small", "0.000001", false},
+		{`{"open":true}`, "open", "true", false},
+		{`{"mode":"comfort"}`, "mode", "comfort", false},
+		{`{"color":{"red":255,"green":128,"blue":0}}`, "color", `{"blue":0,"green":128,"red":255}`, false},
+		{`{"values":[1,2]}`, "values", "", true},
+		{`{"value":null}`, "value", "", true},
+		{`{"sensor":{"humidity":40}}`, "sensor.temperature", "", true},
+		{`{"temperature":21.5}`, "temperature.value", "", true},
+		{"21.5", "temperature", "", true},
+	}
+	for _, tt := range tests {
+		got, err := extractValue([]byte(tt.payload), tt.field)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("extractValue(%s, %q) error = %v, want error %v", tt.payload, tt.field, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("extractValue(%s, %q) = %q, want %q", tt.payload, tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestVirtualUpdates(t *testing.T) {
+	b := &Bridge{virtual: virtualTopics([]models.VirtualGroupAddress{
+		{Address: "5/0/1", Topic: "zigbee/garden", Field: "temperature"},
+		{Address: "5/0/2", Topic: "zigbee/garden", Field: "battery.level"},
+		{Address: "5/0/3", Topic: "weather/wind"},
+	})}
+
+	updates, ok := b.virtualUpdates(msg.NewMQTT(fakeMQTTMessage{"zigbee/garden", []byte(`{"temperature":12.5,"battery":{"level":1e2}}`)}))
+	want := []virtualUpdate{{address: "5/0/1", value: "12.5"}, {address: "5/0/2", value: "100"}}
+	if !ok || !reflect.DeepEqual(updates, want) {
+		t.Errorf("virtualUpdates(zigbee/garden) = %v, %v, want %v", updates, ok, want)
+	}
+
+	// A value missing from the payload skips only its group address
+	updates, ok = b.virtualUpdates(msg.NewMQTT(fakeMQTTMessage{"zigbee/garden", []byte(`{"temperature":13}`)}))
+	want = []virtualUpdate{{address: "5/0/1", value: "13"}}
+	if !ok || !reflect.DeepEqual(updates, want) {
+		t.Errorf("virtualUpdates(zigbee/garden) = %v, %v, want %v", updates, ok, want)
+	}
+
+	updates, ok = b.virtualUpdates(msg.NewMQTT(fakeMQTTMessage{"weather/wind", []byte("4.2")}))
+	want = []virtualUpdate{{address: "5/0/3", value: "4.2"}}
+	if !ok || !reflect.DeepEqual(updates, want) {
+		t.Errorf("virtualUpdates(weather/wind) = %v, %v, want %v", updates, ok, want)
+	}
+
+	if updates, ok := b.virtualUpdates(msg.NewMQTT(fakeMQTTMessage{"knx/1/2/3/write", []byte("1")})); ok {
+		t.Errorf("virtualUpdates(knx/1/2/3/write) = %v, want no virtual group address", updates)
+	}
+}
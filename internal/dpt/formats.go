@@ -0,0 +1,290 @@
+package dpt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vapourismo/knx-go/knx/dpt"
+)
+
+// Valuer is implemented by the datapoint types of the local registry. Value returns the value
+// published in JSON, e.g. a number, a name or a structured value.
+type Valuer interface {
+	Value() interface{}
+}
+
+// The formats below are the same as in knx-go: values of up to 6 bits are packed into the
+// first byte, longer values follow after a leading zero byte.
+
+func packB6(b uint8) []byte {
+	return []byte{b & 0x3F}
+}
+
+func unpackB6(data []byte, b *uint8) error {
+	if len(data) != 1 {
+		return dpt.ErrInvalidLength
+	}
+	*b = data[0] & 0x3F
+	return nil
+}
+
+func packU8(i uint8) []byte {
+	return []byte{0, i}
+}
+
+func unpackU8(data []byte, i *uint8) error {
+	if len(data) != 2 {
+		return dpt.ErrInvalidLength
+	}
+	*i = data[1]
+	return nil
+}
+
+func packU16(i uint16) []byte {
+	buffer := []byte{0, 0, 0}
+	binary.BigEndian.PutUint16(buffer[1:], i)
+	return buffer
+}
+
+func unpackU16(data []byte, i *uint16) error {
+	if len(data) != 3 {
+		return dpt.ErrInvalidLength
+	}
+	*i = binary.BigEndian.Uint16(data[1:])
+	return nil
+}
+
+func packU32(i uint32) []byte {
+	buffer := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(buffer[1:], i)
+	return buffer
+}
+
+func unpackU32(data []byte, i *uint32) error {
+	if len(data) != 5 {
+		return dpt.ErrInvalidLength
+	}
+	*i = binary.BigEndian.Uint32(data[1:])
+	return nil
+}
+
+func packV64(i int64) []byte {
+	buffer := make([]byte, 9)
+	binary.BigEndian.PutUint64(buffer[1:], uint64(i))
+	return buffer
+}
+
+func unpackV64(data []byte, i *int64) error {
+	if len(data) != 9 {
+		return dpt.ErrInvalidLength
+	}
+	*i = int64(binary.BigEndian.Uint64(data[1:]))
+	return nil
+}
+
+// enumeration names the values of an enumeration datapoint type, e.g. "comfort" for 1 of 20.102.
+// Values without a name are reserved.
+type enumeration []string
+
+// value returns the name of v, or v itself if it is reserved.
+func (e enumeration) value(v uint8) interface{} {
+	if int(v) < len(e) && e[v] != "" {
+		return e[v]
+	}
+	return v
+}
+
+func (e enumeration) format(v uint8) string {
+	return fmt.Sprint(e.value(v))
+}
+
+// parse accepts a name, ignoring case, the name as JSON string or the number of a named value.
+func (e enumeration) parse(text string) (uint8, error) {
+	text = strings.TrimSpace(text)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+	for v, name := range e {
+		if name != "" && strings.EqualFold(name, text) {
+			return uint8(v), nil
+		}
+	}
+	if v, err := strconv.ParseUint(text, 10, 8); err == nil && int(v) < len(e) && e[v] != "" {
+		return uint8(v), nil
+	}
+
+	names := make([]string, 0, len(e))
+	for _, name := range e {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return 0, fmt.Errorf("unknown value \"%s\", expected one of %s", text, strings.Join(names, ", "))
+}
+
+// EnumerationNames returns the names of the values of an enumeration datapoint type in the order of
+// their numbers, e.g. the HVAC modes of 20.102. It returns nil for other datapoint types.
+func EnumerationNames(name string) []string {
+	d, ok := Produce(name)
+	if !ok {
+		return nil
+	}
+	e, ok := d.(interface{ names() enumeration })
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, n := range e.names() {
+		if n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// enum8 is an enumeration datapoint type, e.g. 20.102. Its value is emitted and written by name.
+// The value follows a leading zero byte, or is held by the masked bits of a single byte, e.g. the
+// 2 bits of the 23.x types.
+type enum8 struct {
+	enumeration *enumeration
+	mask        uint8 // 0 if the value follows a leading zero byte
+	value       uint8
+}
+
+// enum8Type returns the constructor of the enumeration datapoint type with the given names,
+// registered in place of a new instance.
+func enum8Type(e *enumeration, mask uint8) func() dpt.Datapoint {
+	return func() dpt.Datapoint {
+		return &enum8{enumeration: e, mask: mask}
+	}
+}
+
+func (d enum8) Pack() []byte {
+	if d.mask != 0 {
+		return packB6(d.value & d.mask)
+	}
+	return packU8(d.value)
+}
+
+func (d *enum8) Unpack(data []byte) error {
+	if d.mask == 0 {
+		return unpackU8(data, &d.value)
+	}
+	var value uint8
+	if err := unpackB6(data, &value); err != nil {
+		return err
+	}
+	d.value = value & d.mask
+	return nil
+}
+
+func (d enum8) Unit() string {
+	return ""
+}
+
+func (d enum8) Value() interface{} {
+	return d.enumeration.value(d.value)
+}
+
+func (d enum8) String() string {
+	return d.enumeration.format(d.value)
+}
+
+func (d *enum8) UnmarshalText(text []byte) error {
+	value, err := d.enumeration.parse(string(text))
+	if err != nil {
+		return err
+	}
+	d.value = value
+	return nil
+}
+
+func (d enum8) names() enumeration {
+	return *d.enumeration
+}
+
+// flags names the bits of a bitset datapoint type, starting with bit 0. Reserved bits have no name.
+type flags []string
+
+// value returns the state of each named bit.
+func (f flags) value(bits uint16) map[string]bool {
+	value := make(map[string]bool, len(f))
+	for i, name := range f {
+		if name != "" {
+			value[name] = bits&(1<<i) != 0
+		}
+	}
+	return value
+}
+
+// format returns the names of the set bits, or "none".
+func (f flags) format(bits uint16) string {
+	var set []string
+	for i, name := range f {
+		if name != "" && bits&(1<<i) != 0 {
+			set = append(set, name)
+		}
+	}
+	if len(set) == 0 {
+		return "none"
+	}
+	return strings.Join(set, ", ")
+}
+
+// parse accepts a JSON object with the state of the bits, e.g. {"fault":true}, where left out bits are
+// not set, or the names of the set bits separated by commas, or "none".
+func (f flags) parse(text string) (uint16, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") {
+		var states map[string]bool
+		if err := json.Unmarshal([]byte(text), &states); err != nil {
+			return 0, err
+		}
+		var names []string
+		for name, set := range states {
+			if set {
+				names = append(names, name)
+			} else if f.bit(name) < 0 {
+				return 0, f.unknown(name)
+			}
+		}
+		sort.Strings(names)
+		text = strings.Join(names, ",")
+	}
+
+	var bits uint16
+	if text == "" || strings.EqualFold(text, "none") {
+		return bits, nil
+	}
+	for _, name := range strings.Split(text, ",") {
+		bit := f.bit(strings.TrimSpace(name))
+		if bit < 0 {
+			return 0, f.unknown(name)
+		}
+		bits |= 1 << bit
+	}
+	return bits, nil
+}
+
+func (f flags) bit(name string) int {
+	for i, n := range f {
+		if n != "" && strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f flags) unknown(name string) error {
+	var names []string
+	for _, n := range f {
+		if n != "" {
+			names = append(names, n)
+		}
+	}
+	return fmt.Errorf("unknown flag \"%s\", expected %s", strings.TrimSpace(name), strings.Join(names, ", "))
+}
@@ -0,0 +1,85 @@
+package dpt
+
+import (
+	"fmt"
+
+	"github.com/vapourismo/knx-go/knx/dpt"
+)
+
+// AccessData is the structured value of DPT 15.000, e.g.
+// {"accessCode":123456,"error":false,"permission":true,"readRightToLeft":false,"encrypted":false,"index":0}.
+type AccessData struct {
+	AccessCode      uint32 `json:"accessCode"`      // 6 decimal digits, 0-999999
+	Error           bool   `json:"error"`           // 0 = no error, 1 = reading access information failed
+	Permission      bool   `json:"permission"`      // 0 = not accepted, 1 = accepted
+	ReadRightToLeft bool   `json:"readRightToLeft"` // 0 = read from left to right, 1 = read from right to left
+	Encrypted       bool   `json:"encrypted"`       // 0 = not encrypted, 1 = encrypted
+	Index           uint8  `json:"index"`           // 0-15
+}
+
+// DPT_15000 represents DPT 15.000 / Access Data.
+type DPT_15000 AccessData
+
+func (d DPT_15000) Pack() []byte {
+	buf := []byte{0, 0, 0, 0, 0}
+	code := d.AccessCode % 1000000
+	// Digits D6 (most significant) to D1 in BCD
+	for i := 3; i >= 1; i-- {
+		buf[i] = uint8(code%10) | uint8(code/10%10)<<4
+		code /= 100
+	}
+	buf[4] = packBit(d.Error)<<7 | packBit(d.Permission)<<6 | packBit(d.ReadRightToLeft)<<5 | packBit(d.Encrypted)<<4 | d.Index&0x0F
+	return buf
+}
+
+func (d *DPT_15000) Unpack(data []byte) error {
+	if len(data) != 5 {
+		return dpt.ErrInvalidLength
+	}
+	var code uint32
+	for _, b := range data[1:4] {
+		high, low := b>>4, b&0x0F
+		if high > 9 || low > 9 {
+			return fmt.Errorf("access code digit is not BCD: %02X", b)
+		}
+		code = code*100 + uint32(high)*10 + uint32(low)
+	}
+	*d = DPT_15000{
+		AccessCode:      code,
+		Error:           data[4]&0x80 != 0,
+		Permission:      data[4]&0x40 != 0,
+		ReadRightToLeft: data[4]&0x20 != 0,
+		Encrypted:       data[4]&0x10 != 0,
+		Index:           data[4] & 0x0F,
+	}
+	return nil
+}
+
+func (d DPT_15000) Unit() string {
+	return ""
+}
+
+func (d DPT_15000) Value() interface{} {
+	return AccessData(d)
+}
+
+func (d DPT_15000) String() string {
+	return fmt.Sprintf("%06d Error: %t Permission: %t ReadRightToLeft: %t Encrypted: %t Index: %d",
+		d.AccessCode, d.Error, d.Permission, d.ReadRightToLeft, d.Encrypted, d.Index)
+}
+
+// UnmarshalText accepts the JSON form.
+func (d *DPT_15000) UnmarshalText(text []byte) error {
+	var value AccessData
+	if err := unmarshalObject(string(text), &value); err != nil {
+		return err
+	}
+	if value.AccessCode > 999999 {
+		return fmt.Errorf("access code %d has more than 6 digits", value.AccessCode)
+	}
+	if value.Index > 15 {
+		return fmt.Errorf("index %d is out of range 0-15", value.Index)
+	}
+	*d = DPT_15000(value)
+	return nil
+}
@@ -0,0 +1,83 @@
+package dpt
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var regexpSceneControl = regexp.MustCompile(`^(?i:(activate|learn)\s+)?(\d+)$`)
+
+// SceneControl is the structured value of DPT 18.001, e.g. {"scene":5,"learn":true}.
+type SceneControl struct {
+	Scene uint8 `json:"scene"` // 0-63
+	Learn bool  `json:"learn"` // Learn (store) the scene instead of activating it
+}
+
+// DPT_18001 represents DPT 18.001 / Scene Control.
+type DPT_18001 SceneControl
+
+func (d DPT_18001) Pack() []byte {
+	value := d.Scene & 0x3F
+	if d.Learn {
+		value |= 0x80
+	}
+	return packU8(value)
+}
+
+func (d *DPT_18001) Unpack(data []byte) error {
+	var value uint8
+	if err := unpackU8(data, &value); err != nil {
+		return err
+	}
+	d.Scene = value & 0x3F
+	d.Learn = value&0x80 != 0
+	return nil
+}
+
+func (d DPT_18001) Unit() string {
+	return ""
+}
+
+func (d DPT_18001) Value() interface{} {
+	return SceneControl(d)
+}
+
+func (d DPT_18001) String() string {
+	if d.Learn {
+		return fmt.Sprintf("learn %d", d.Scene)
+	}
+	return fmt.Sprintf("activate %d", d.Scene)
+}
+
+// UnmarshalText accepts the JSON form, the text form, e.g. "learn 5", or the scene number to activate.
+// For compatibility the raw value with the learn bit is accepted as well, e.g. 133 to learn scene 5.
+func (d *DPT_18001) UnmarshalText(text []byte) error {
+	value := SceneControl{}
+	trimmed := strings.TrimSpace(string(text))
+	if isJSONObject(trimmed) {
+		if err := unmarshalObject(trimmed, &value); err != nil {
+			return err
+		}
+	} else {
+		matches := regexpSceneControl.FindStringSubmatch(trimmed)
+		if matches == nil {
+			return fmt.Errorf("expected e.g. \"5\", \"learn 5\" or {\"scene\":5,\"learn\":true}")
+		}
+		scene, err := strconv.ParseUint(matches[2], 10, 8)
+		if err != nil {
+			return fmt.Errorf("scene %s is out of range 0-63", matches[2])
+		}
+		value.Scene = uint8(scene)
+		value.Learn = strings.EqualFold(matches[1], "learn")
+		if matches[1] == "" && scene >= 0x80 && scene&0x40 == 0 {
+			value.Scene, value.Learn = uint8(scene)&0x3F, true
+		}
+	}
+	if value.Scene > 63 {
+		return fmt.Errorf("scene %d is out of range 0-63", value.Scene)
+	}
+	*d = DPT_18001(value)
+	return nil
+}
@@ -0,0 +1,302 @@
+package dpt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BoolControl is the value of the DPT 2.x types: a DPT 1.x value with a control bit. Without
+// control, the value is ignored by the receiver. It is emitted as JSON object like
+// {"control":true,"value":true}.
+type BoolControl struct {
+	Control bool `json:"control"` // 0 = no control, 1 = control
+	Value   bool `json:"value"`   // meaning as in the corresponding DPT 1.x
+}
+
+func (d BoolControl) Pack() []byte {
+	return packB6(uint8(packBit(d.Control)<<1 | packBit(d.Value)))
+}
+
+func (d *BoolControl) Unpack(data []byte) error {
+	var value uint8
+	if err := unpackB6(data, &value); err != nil {
+		return err
+	}
+	d.Control = value&0x02 != 0
+	d.Value = value&0x01 != 0
+	return nil
+}
+
+func (d BoolControl) Unit() string {
+	return ""
+}
+
+// format returns "no control" or the label of the value.
+func (d BoolControl) format(labels [2]string) string {
+	if !d.Control {
+		return "no control"
+	}
+	return labels[packBit(d.Value)]
+}
+
+// parse accepts "no control", a label like "on", a boolean like "true", optionally preceded by "control",
+// or the JSON form with the value given as boolean or label.
+func (d *BoolControl) parse(text string, labels [2]string) error {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") {
+		var v struct {
+			Control bool        `json:"control"`
+			Value   interface{} `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return err
+		}
+		d.Control = v.Control
+		switch value := v.Value.(type) {
+		case bool:
+			d.Value = value
+			return nil
+		case string:
+			return d.parseValue(value, labels)
+		case nil:
+			d.Value = false
+			return nil
+		}
+		return fmt.Errorf("value %v is neither a boolean nor a label", v.Value)
+	}
+
+	if strings.EqualFold(text, "no control") {
+		*d = BoolControl{}
+		return nil
+	}
+	d.Control = true
+	return d.parseValue(strings.TrimSpace(strings.TrimPrefix(strings.ToLower(text), "control")), labels)
+}
+
+func (d *BoolControl) parseValue(text string, labels [2]string) error {
+	for i, label := range labels {
+		if strings.EqualFold(label, text) {
+			d.Value = i == 1
+			return nil
+		}
+	}
+	value, err := strconv.ParseBool(text)
+	if err != nil {
+		return fmt.Errorf("unknown value \"%s\", expected %s or %s", text, labels[0], labels[1])
+	}
+	d.Value = value
+	return nil
+}
+
+func packBit(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+var labels2001 = [2]string{"off", "on"}
+
+// DPT_2001 represents DPT 2.001 / Switch Control.
+type DPT_2001 struct{ BoolControl }
+
+func (d DPT_2001) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2001) String() string {
+	return d.format(labels2001)
+}
+
+func (d *DPT_2001) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2001)
+}
+
+var labels2002 = [2]string{"false", "true"}
+
+// DPT_2002 represents DPT 2.002 / Bool Control.
+type DPT_2002 struct{ BoolControl }
+
+func (d DPT_2002) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2002) String() string {
+	return d.format(labels2002)
+}
+
+func (d *DPT_2002) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2002)
+}
+
+var labels2003 = [2]string{"disable", "enable"}
+
+// DPT_2003 represents DPT 2.003 / Enable Control.
+type DPT_2003 struct{ BoolControl }
+
+func (d DPT_2003) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2003) String() string {
+	return d.format(labels2003)
+}
+
+func (d *DPT_2003) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2003)
+}
+
+var labels2004 = [2]string{"no ramp", "ramp"}
+
+// DPT_2004 represents DPT 2.004 / Ramp Control.
+type DPT_2004 struct{ BoolControl }
+
+func (d DPT_2004) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2004) String() string {
+	return d.format(labels2004)
+}
+
+func (d *DPT_2004) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2004)
+}
+
+var labels2005 = [2]string{"no alarm", "alarm"}
+
+// DPT_2005 represents DPT 2.005 / Alarm Control.
+type DPT_2005 struct{ BoolControl }
+
+func (d DPT_2005) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2005) String() string {
+	return d.format(labels2005)
+}
+
+func (d *DPT_2005) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2005)
+}
+
+var labels2006 = [2]string{"low", "high"}
+
+// DPT_2006 represents DPT 2.006 / Binary Value Control.
+type DPT_2006 struct{ BoolControl }
+
+func (d DPT_2006) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2006) String() string {
+	return d.format(labels2006)
+}
+
+func (d *DPT_2006) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2006)
+}
+
+var labels2007 = [2]string{"decrease", "increase"}
+
+// DPT_2007 represents DPT 2.007 / Step Control.
+type DPT_2007 struct{ BoolControl }
+
+func (d DPT_2007) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2007) String() string {
+	return d.format(labels2007)
+}
+
+func (d *DPT_2007) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2007)
+}
+
+var labels2008 = [2]string{"up", "down"}
+
+// DPT_2008 represents DPT 2.008 / Direction1 Control.
+type DPT_2008 struct{ BoolControl }
+
+func (d DPT_2008) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2008) String() string {
+	return d.format(labels2008)
+}
+
+func (d *DPT_2008) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2008)
+}
+
+var labels2009 = [2]string{"open", "close"}
+
+// DPT_2009 represents DPT 2.009 / Direction2 Control.
+type DPT_2009 struct{ BoolControl }
+
+func (d DPT_2009) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2009) String() string {
+	return d.format(labels2009)
+}
+
+func (d *DPT_2009) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2009)
+}
+
+var labels2010 = [2]string{"stop", "start"}
+
+// DPT_2010 represents DPT 2.010 / Start Control.
+type DPT_2010 struct{ BoolControl }
+
+func (d DPT_2010) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2010) String() string {
+	return d.format(labels2010)
+}
+
+func (d *DPT_2010) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2010)
+}
+
+var labels2011 = [2]string{"inactive", "active"}
+
+// DPT_2011 represents DPT 2.011 / State Control.
+type DPT_2011 struct{ BoolControl }
+
+func (d DPT_2011) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2011) String() string {
+	return d.format(labels2011)
+}
+
+func (d *DPT_2011) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2011)
+}
+
+var labels2012 = [2]string{"not inverted", "inverted"}
+
+// DPT_2012 represents DPT 2.012 / Invert Control.
+type DPT_2012 struct{ BoolControl }
+
+func (d DPT_2012) Value() interface{} {
+	return d.BoolControl
+}
+
+func (d DPT_2012) String() string {
+	return d.format(labels2012)
+}
+
+func (d *DPT_2012) UnmarshalText(text []byte) error {
+	return d.parse(string(text), labels2012)
+}
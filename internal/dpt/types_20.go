@@ -0,0 +1,172 @@
+package dpt
+
+// The DPT 20.x types are enumerations of 8 bits. They are emitted and written by the name of the value,
+// e.g. "comfort" for 20.102. Writing the number of a value is accepted as well.
+
+// enum20001 names the values of DPT 20.001 / SCLO Mode.
+var enum20001 = enumeration{"autonomous", "slave", "master"}
+
+// enum20002 names the values of DPT 20.002 / Building Mode.
+var enum20002 = enumeration{"buildingInUse", "buildingNotUsed", "buildingProtection"}
+
+// enum20003 names the values of DPT 20.003 / Occupancy Mode.
+var enum20003 = enumeration{"occupied", "standby", "notOccupied"}
+
+// enum20004 names the values of DPT 20.004 / Priority.
+var enum20004 = enumeration{"high", "medium", "low", "void"}
+
+// enum20005 names the values of DPT 20.005 / Light Application Mode.
+var enum20005 = enumeration{"normal", "presenceSimulation", "nightRound"}
+
+// enum20006 names the values of DPT 20.006 / Application Area.
+var enum20006 = enumeration{0: "noFault", 1: "commonInterest", 10: "hvacGeneral", 11: "hvacHotWaterHeating", 12: "hvacDirectElectricalHeating", 13: "hvacTerminalUnits", 14: "hvacVAC", 20: "lighting", 30: "security", 40: "loadManagement", 50: "shuttersAndBlinds"}
+
+// enum20007 names the values of DPT 20.007 / Alarm Class Type.
+var enum20007 = enumeration{1: "simpleAlarm", 2: "basicAlarm", 3: "extendedAlarm"}
+
+// enum20008 names the values of DPT 20.008 / PSU Mode.
+var enum20008 = enumeration{"disabled", "enabled", "auto"}
+
+// enum20011 names the values of DPT 20.011 / Error Class System.
+var enum20011 = enumeration{"noFault", "generalDeviceFault", "communicationFault", "configurationFault", "hardwareFault", "softwareFault", "insufficientNonVolatileMemory", "insufficientVolatileMemory", "memoryAllocationSizeZero", "crcError", "watchdogReset", "invalidOpcode", "generalProtectionFault", "maximalTableLengthExceeded", "undefinedLoadCommand", "groupAddressTableNotSorted", "invalidConnectionNumber", "invalidGroupObjectNumber", "groupObjectTypeExceeds"}
+
+// enum20012 names the values of DPT 20.012 / Error Class HVAC.
+var enum20012 = enumeration{"noFault", "sensorFault", "processFault", "actuatorFault", "otherFault"}
+
+// enum20013 names the values of DPT 20.013 / Time Delay.
+var enum20013 = enumeration{"notActive", "1s", "2s", "3s", "5s", "10s", "15s", "20s", "30s", "45s", "1min", "1.25min", "1.5min", "2min", "2.5min", "3min", "5min", "15min", "20min", "30min", "1h", "2h", "3h", "5h", "12h", "24h"}
+
+// enum20014 names the values of DPT 20.014 / Wind Force Scale Beaufort.
+var enum20014 = enumeration{"calm", "lightAir", "lightBreeze", "gentleBreeze", "moderateBreeze", "freshBreeze", "strongBreeze", "nearGale", "gale", "strongGale", "storm", "violentStorm", "hurricane"}
+
+// enum20017 names the values of DPT 20.017 / Sensor Select.
+var enum20017 = enumeration{"inactive", "digitalInputNotInverted", "digitalInputInverted", "analogInput", "temperatureSensorInput"}
+
+// enum20020 names the values of DPT 20.020 / Actuator Connect Type.
+var enum20020 = enumeration{1: "sensorConnection", 2: "controllerConnection"}
+
+// enum20021 names the values of DPT 20.021 / Cloud Cover.
+var enum20021 = enumeration{"cloudless", "sunny", "sunshiny", "lightlyCloudy", "scatteredClouds", "cloudy", "overcast", "considerablyOvercast", "heavilyOvercast", "skyObstructed"}
+
+// enum20022 names the values of DPT 20.022 / Power Return Mode.
+var enum20022 = enumeration{"doNotSend", "sendAlways", "sendIfValueChanged"}
+
+// enum20100 names the values of DPT 20.100 / Fuel Type.
+var enum20100 = enumeration{"auto", "oil", "gas", "solidStateFuel"}
+
+// enum20101 names the values of DPT 20.101 / Burner Type.
+var enum20101 = enumeration{1: "oneStage", 2: "twoStage", 3: "modulating"}
+
+// enum20102 names the values of DPT 20.102 / HVAC Mode.
+var enum20102 = enumeration{"auto", "comfort", "standby", "economy", "buildingProtection"}
+
+// enum20103 names the values of DPT 20.103 / DHW Mode.
+var enum20103 = enumeration{"auto", "legioProtect", "normal", "reduced", "offFrostProtect"}
+
+// enum20104 names the values of DPT 20.104 / Load Priority.
+var enum20104 = enumeration{"none", "shiftLoadPriority", "absoluteLoadPriority"}
+
+// enum20105 names the values of DPT 20.105 / HVAC Control Mode.
+var enum20105 = enumeration{0: "auto", 1: "heat", 2: "morningWarmup", 3: "cool", 4: "nightPurge", 5: "precool", 6: "off", 7: "test", 8: "emergencyHeat", 9: "fanOnly", 10: "freeCool", 11: "ice", 12: "maximumHeating", 13: "economicHeatCool", 14: "dehumidification", 15: "calibration", 16: "emergencyCool", 17: "emergencySteam", 20: "noDem"}
+
+// enum20106 names the values of DPT 20.106 / HVAC Emergency Mode.
+var enum20106 = enumeration{"normal", "emergPressure", "emergDepressure", "emergPurge", "emergShutdown", "emergFire"}
+
+// enum20107 names the values of DPT 20.107 / Changeover Mode.
+var enum20107 = enumeration{"auto", "coolingOnly", "heatingOnly"}
+
+// enum20108 names the values of DPT 20.108 / Valve Mode.
+var enum20108 = enumeration{1: "heatStageA", 2: "heatStageB", 3: "coolStageA", 4: "coolStageB", 5: "heatCoolChangeover"}
+
+// enum20109 names the values of DPT 20.109 / Damper Mode.
+var enum20109 = enumeration{1: "freshAir", 2: "supplyAir", 3: "extractAir", 4: "extractAirVAV"}
+
+// enum20110 names the values of DPT 20.110 / Heater Mode.
+var enum20110 = enumeration{1: "heatStageAOnOff", 2: "heatStageAProportional", 3: "heatStageBProportional"}
+
+// enum20111 names the values of DPT 20.111 / Fan Mode.
+var enum20111 = enumeration{"notRunning", "permanentlyRunning", "runningInIntervals"}
+
+// enum20112 names the values of DPT 20.112 / Master Slave Mode.
+var enum20112 = enumeration{"autonomous", "master", "slave"}
+
+// enum20113 names the values of DPT 20.113 / Status Room Setpoint.
+var enum20113 = enumeration{"normalSetpoint", "alternativeSetpoint", "buildingProtectionSetpoint"}
+
+// enum20115 names the values of DPT 20.115 / Humidification Dehumidification Mode.
+var enum20115 = enumeration{"inactive", "humidification", "dehumidification"}
+
+// enum20116 names the values of DPT 20.116 / Enable HC Stage.
+var enum20116 = enumeration{"disabled", "enableStageA", "enableStageB", "enableBothStages"}
+
+// enum20120 names the values of DPT 20.120 / ADA Type.
+var enum20120 = enumeration{1: "airDamper", 2: "vav"}
+
+// enum20121 names the values of DPT 20.121 / Backup Mode.
+var enum20121 = enumeration{"backupValue", "keepLastState"}
+
+// enum20122 names the values of DPT 20.122 / Start Synchronization.
+var enum20122 = enumeration{"positionUnchanged", "singleClose", "singleOpen"}
+
+// enum20600 names the values of DPT 20.600 / Behaviour Lock Unlock.
+var enum20600 = enumeration{"off", "on", "noChange", "additionalParameter", "memoryFunction", "updatedValue", "valueBeforeLocking"}
+
+// enum20601 names the values of DPT 20.601 / Behaviour Bus Power Up Down.
+var enum20601 = enumeration{"off", "on", "noChange", "additionalParameter", "last"}
+
+// enum20602 names the values of DPT 20.602 / DALI Fade Time.
+var enum20602 = enumeration{"0s", "0.7s", "1s", "1.4s", "2s", "2.8s", "4s", "5.7s", "8s", "11.3s", "16s", "22.6s", "32s", "45.3s", "64s", "90.5s"}
+
+// enum20603 names the values of DPT 20.603 / Blinking Mode.
+var enum20603 = enumeration{"disabled", "withoutAcknowledge", "withAcknowledge"}
+
+// enum20604 names the values of DPT 20.604 / Light Control Mode.
+var enum20604 = enumeration{"automatic", "manual"}
+
+// enum20605 names the values of DPT 20.605 / Switch Push Button Model.
+var enum20605 = enumeration{1: "onePushButton", 2: "twoPushButtons"}
+
+// enum20606 names the values of DPT 20.606 / Push Button Action.
+var enum20606 = enumeration{"inactive", "switchOff", "switchOn", "toggle"}
+
+// enum20607 names the values of DPT 20.607 / Dimming Push Button Model.
+var enum20607 = enumeration{1: "onePushButton", 2: "twoPushButtons", 3: "onePushButtonShortSwitchOff", 4: "onePushButtonShortSwitchOn"}
+
+// enum20608 names the values of DPT 20.608 / Switch On Mode.
+var enum20608 = enumeration{"lastActualValue", "additionalParameter", "lastReceivedSetValue"}
+
+// enum20609 names the values of DPT 20.609 / Load Type Set.
+var enum20609 = enumeration{"universal", "resistiveCapacitive", "inductive"}
+
+// enum20610 names the values of DPT 20.610 / Load Type Detected.
+var enum20610 = enumeration{"undefined", "resistiveCapacitive", "inductive", "detectionFailed"}
+
+// enum20801 names the values of DPT 20.801 / Sun Protection Exception Behaviour.
+var enum20801 = enumeration{"up", "down", "noChange", "additionalParameter", "stop"}
+
+// enum20802 names the values of DPT 20.802 / Sun Protection Behaviour Lock Unlock.
+var enum20802 = enumeration{"up", "down", "noChange", "additionalParameter", "stop", "updatedValue", "valueBeforeLocking"}
+
+// enum20803 names the values of DPT 20.803 / Sun Protection Push Button Mode.
+var enum20803 = enumeration{1: "onePushButton", 2: "onePushButtonBinaryInput", 3: "twoPushButtons"}
+
+// enum20804 names the values of DPT 20.804 / Blinds Control Mode.
+var enum20804 = enumeration{"automatic", "manual"}
+
+// enum201000 names the values of DPT 20.1000 / Communication Mode.
+var enum201000 = enumeration{0: "dataLinkLayer", 1: "dataLinkLayerBusMonitor", 2: "dataLinkLayerRawFrames", 6: "cemiTransportLayer", 255: "noLayer"}
+
+// enum201002 names the values of DPT 20.1002 / RF Mode Select.
+var enum201002 = enumeration{"asynchronous", "asynchronousBiBatMaster", "asynchronousBiBatSlave"}
+
+// enum201003 names the values of DPT 20.1003 / RF Filter Select.
+var enum201003 = enumeration{"noFiltering", "filteringByDoA", "filteringBySerialNumber", "filteringByDoAAndSerialNumber"}
+
+// enum201004 names the values of DPT 20.1004 / Medium.
+var enum201004 = enumeration{0: "tp1", 1: "pl110", 2: "rf", 5: "knxip"}
+
+// enum201200 names the values of DPT 20.1200 / M-Bus Breaker Valve State.
+var enum201200 = enumeration{0: "closed", 1: "open", 2: "released", 255: "invalid"}
+
+// enum201202 names the values of DPT 20.1202 / Gas Measurement Condition.
+var enum201202 = enumeration{"unknown", "temperatureConverted", "baseCondition", "measurementCondition"}
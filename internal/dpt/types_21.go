@@ -0,0 +1,379 @@
+package dpt
+
+// The DPT 21.x types are sets of 8 flags. They are emitted as JSON object with the state of each flag,
+// e.g. {"fault":true,"outOfService":false,...}, and written as the same object or as list of the set flags,
+// e.g. "fault, inAlarm".
+
+var flags21001 = flags{"outOfService", "fault", "overridden", "inAlarm", "alarmUnAck"}
+
+// DPT_21001 represents DPT 21.001 / Status Gen.
+type DPT_21001 uint8
+
+func (d DPT_21001) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21001) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21001) Unit() string {
+	return ""
+}
+
+func (d DPT_21001) Value() interface{} {
+	return flags21001.value(uint16(d))
+}
+
+func (d DPT_21001) String() string {
+	return flags21001.format(uint16(d))
+}
+
+func (d *DPT_21001) UnmarshalText(text []byte) error {
+	value, err := flags21001.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21001(value)
+	return nil
+}
+
+var flags21002 = flags{"userStopped", "ownIA", "verifyMode"}
+
+// DPT_21002 represents DPT 21.002 / Device Control.
+type DPT_21002 uint8
+
+func (d DPT_21002) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21002) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21002) Unit() string {
+	return ""
+}
+
+func (d DPT_21002) Value() interface{} {
+	return flags21002.value(uint16(d))
+}
+
+func (d DPT_21002) String() string {
+	return flags21002.format(uint16(d))
+}
+
+func (d *DPT_21002) UnmarshalText(text []byte) error {
+	value, err := flags21002.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21002(value)
+	return nil
+}
+
+var flags21100 = flags{"forceRequest", "protection", "oversupply", "overrun", "dhwNorm", "dhwLegio", "roomHComf", "roomHMax"}
+
+// DPT_21100 represents DPT 21.100 / Forcing Signal.
+type DPT_21100 uint8
+
+func (d DPT_21100) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21100) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21100) Unit() string {
+	return ""
+}
+
+func (d DPT_21100) Value() interface{} {
+	return flags21100.value(uint16(d))
+}
+
+func (d DPT_21100) String() string {
+	return flags21100.format(uint16(d))
+}
+
+func (d *DPT_21100) UnmarshalText(text []byte) error {
+	value, err := flags21100.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21100(value)
+	return nil
+}
+
+var flags21101 = flags{"forceRequest"}
+
+// DPT_21101 represents DPT 21.101 / Forcing Signal Cool.
+type DPT_21101 uint8
+
+func (d DPT_21101) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21101) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21101) Unit() string {
+	return ""
+}
+
+func (d DPT_21101) Value() interface{} {
+	return flags21101.value(uint16(d))
+}
+
+func (d DPT_21101) String() string {
+	return flags21101.format(uint16(d))
+}
+
+func (d *DPT_21101) UnmarshalText(text []byte) error {
+	value, err := flags21101.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21101(value)
+	return nil
+}
+
+var flags21102 = flags{"fault", "statusEcoH", "tempFlowLimit", "tempReturnLimit", "statusMorningBoostH", "statusStartOptim", "statusStopOptim", "summerMode"}
+
+// DPT_21102 represents DPT 21.102 / Room Heating Controller Status.
+type DPT_21102 uint8
+
+func (d DPT_21102) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21102) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21102) Unit() string {
+	return ""
+}
+
+func (d DPT_21102) Value() interface{} {
+	return flags21102.value(uint16(d))
+}
+
+func (d DPT_21102) String() string {
+	return flags21102.format(uint16(d))
+}
+
+func (d *DPT_21102) UnmarshalText(text []byte) error {
+	value, err := flags21102.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21102(value)
+	return nil
+}
+
+var flags21103 = flags{"fault", "sdhwLoadActive", "solarLoadSufficient"}
+
+// DPT_21103 represents DPT 21.103 / Solar DHW Controller Status.
+type DPT_21103 uint8
+
+func (d DPT_21103) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21103) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21103) Unit() string {
+	return ""
+}
+
+func (d DPT_21103) Value() interface{} {
+	return flags21103.value(uint16(d))
+}
+
+func (d DPT_21103) String() string {
+	return flags21103.format(uint16(d))
+}
+
+func (d *DPT_21103) UnmarshalText(text []byte) error {
+	value, err := flags21103.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21103(value)
+	return nil
+}
+
+var flags21104 = flags{"oil", "gas", "solidState"}
+
+// DPT_21104 represents DPT 21.104 / Fuel Type Set.
+type DPT_21104 uint8
+
+func (d DPT_21104) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21104) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21104) Unit() string {
+	return ""
+}
+
+func (d DPT_21104) Value() interface{} {
+	return flags21104.value(uint16(d))
+}
+
+func (d DPT_21104) String() string {
+	return flags21104.format(uint16(d))
+}
+
+func (d *DPT_21104) UnmarshalText(text []byte) error {
+	value, err := flags21104.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21104(value)
+	return nil
+}
+
+var flags21105 = flags{"fault"}
+
+// DPT_21105 represents DPT 21.105 / Room Cooling Controller Status.
+type DPT_21105 uint8
+
+func (d DPT_21105) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21105) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21105) Unit() string {
+	return ""
+}
+
+func (d DPT_21105) Value() interface{} {
+	return flags21105.value(uint16(d))
+}
+
+func (d DPT_21105) String() string {
+	return flags21105.format(uint16(d))
+}
+
+func (d *DPT_21105) UnmarshalText(text []byte) error {
+	value, err := flags21105.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21105(value)
+	return nil
+}
+
+var flags21106 = flags{"fault", "fan", "cool", "heat"}
+
+// DPT_21106 represents DPT 21.106 / Ventilation Controller Status.
+type DPT_21106 uint8
+
+func (d DPT_21106) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21106) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21106) Unit() string {
+	return ""
+}
+
+func (d DPT_21106) Value() interface{} {
+	return flags21106.value(uint16(d))
+}
+
+func (d DPT_21106) String() string {
+	return flags21106.format(uint16(d))
+}
+
+func (d *DPT_21106) UnmarshalText(text []byte) error {
+	value, err := flags21106.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21106(value)
+	return nil
+}
+
+var flags21601 = flags{"loadDetectionError", "undervoltage", "overcurrent", "underload", "defectiveLoad", "lampFailure", "overheat"}
+
+// DPT_21601 represents DPT 21.601 / Light Actuator Error Info.
+type DPT_21601 uint8
+
+func (d DPT_21601) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_21601) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_21601) Unit() string {
+	return ""
+}
+
+func (d DPT_21601) Value() interface{} {
+	return flags21601.value(uint16(d))
+}
+
+func (d DPT_21601) String() string {
+	return flags21601.format(uint16(d))
+}
+
+func (d *DPT_21601) UnmarshalText(text []byte) error {
+	value, err := flags21601.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_21601(value)
+	return nil
+}
+
+var flags211010 = flags{"channel1", "channel2", "channel3", "channel4", "channel5", "channel6", "channel7", "channel8"}
+
+// DPT_211010 represents DPT 21.1010 / Channel Activation 8.
+type DPT_211010 uint8
+
+func (d DPT_211010) Pack() []byte {
+	return packU8(uint8(d))
+}
+
+func (d *DPT_211010) Unpack(data []byte) error {
+	return unpackU8(data, (*uint8)(d))
+}
+
+func (d DPT_211010) Unit() string {
+	return ""
+}
+
+func (d DPT_211010) Value() interface{} {
+	return flags211010.value(uint16(d))
+}
+
+func (d DPT_211010) String() string {
+	return flags211010.format(uint16(d))
+}
+
+func (d *DPT_211010) UnmarshalText(text []byte) error {
+	value, err := flags211010.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_211010(value)
+	return nil
+}
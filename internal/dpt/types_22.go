@@ -0,0 +1,139 @@
+package dpt
+
+// The DPT 22.x types are sets of 16 flags, emitted and written like the DPT 21.x types.
+
+var flags22100 = flags{"fault", "dhwLoadActive", "legioProtActive", "dhwPushActive", "otherEnergySourceActive", "solarEnergyOnly", "solarEnergySupport", "tempOK"}
+
+// DPT_22100 represents DPT 22.100 / DHW Controller Status.
+type DPT_22100 uint16
+
+func (d DPT_22100) Pack() []byte {
+	return packU16(uint16(d))
+}
+
+func (d *DPT_22100) Unpack(data []byte) error {
+	return unpackU16(data, (*uint16)(d))
+}
+
+func (d DPT_22100) Unit() string {
+	return ""
+}
+
+func (d DPT_22100) Value() interface{} {
+	return flags22100.value(uint16(d))
+}
+
+func (d DPT_22100) String() string {
+	return flags22100.format(uint16(d))
+}
+
+func (d *DPT_22100) UnmarshalText(text []byte) error {
+	value, err := flags22100.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_22100(value)
+	return nil
+}
+
+var flags22101 = flags{"fault", "statusEcoH", "tempFlowLimit", "tempReturnLimit", "statusMorningBoostH", "statusStartOptim", "statusStopOptim", "heatingDisabled", "heatMode", "statusEcoC", "statusPreCool", "coolingDisabled", "dewPointStatus", "frostAlarm", "overheatAlarm"}
+
+// DPT_22101 represents DPT 22.101 / Room Heating Cooling Controller Status.
+type DPT_22101 uint16
+
+func (d DPT_22101) Pack() []byte {
+	return packU16(uint16(d))
+}
+
+func (d *DPT_22101) Unpack(data []byte) error {
+	return unpackU16(data, (*uint16)(d))
+}
+
+func (d DPT_22101) Unit() string {
+	return ""
+}
+
+func (d DPT_22101) Value() interface{} {
+	return flags22101.value(uint16(d))
+}
+
+func (d DPT_22101) String() string {
+	return flags22101.format(uint16(d))
+}
+
+func (d *DPT_22101) UnmarshalText(text []byte) error {
+	value, err := flags22101.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_22101(value)
+	return nil
+}
+
+var flags221000 = flags{"", "tp1", "pl110", "", "rf", "knxip"}
+
+// DPT_221000 represents DPT 22.1000 / Media.
+type DPT_221000 uint16
+
+func (d DPT_221000) Pack() []byte {
+	return packU16(uint16(d))
+}
+
+func (d *DPT_221000) Unpack(data []byte) error {
+	return unpackU16(data, (*uint16)(d))
+}
+
+func (d DPT_221000) Unit() string {
+	return ""
+}
+
+func (d DPT_221000) Value() interface{} {
+	return flags221000.value(uint16(d))
+}
+
+func (d DPT_221000) String() string {
+	return flags221000.format(uint16(d))
+}
+
+func (d *DPT_221000) UnmarshalText(text []byte) error {
+	value, err := flags221000.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_221000(value)
+	return nil
+}
+
+var flags221010 = flags{"channel1", "channel2", "channel3", "channel4", "channel5", "channel6", "channel7", "channel8", "channel9", "channel10", "channel11", "channel12", "channel13", "channel14", "channel15", "channel16"}
+
+// DPT_221010 represents DPT 22.1010 / Channel Activation 16.
+type DPT_221010 uint16
+
+func (d DPT_221010) Pack() []byte {
+	return packU16(uint16(d))
+}
+
+func (d *DPT_221010) Unpack(data []byte) error {
+	return unpackU16(data, (*uint16)(d))
+}
+
+func (d DPT_221010) Unit() string {
+	return ""
+}
+
+func (d DPT_221010) Value() interface{} {
+	return flags221010.value(uint16(d))
+}
+
+func (d DPT_221010) String() string {
+	return flags221010.format(uint16(d))
+}
+
+func (d *DPT_221010) UnmarshalText(text []byte) error {
+	value, err := flags221010.parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = DPT_221010(value)
+	return nil
+}
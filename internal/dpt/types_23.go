@@ -0,0 +1,16 @@
+package dpt
+
+// The DPT 23.x types are enumerations of 2 bits. They are emitted and written by the name of the value,
+// e.g. "offOn".
+
+// enum23001 names the values of DPT 23.001 / On Off Action.
+var enum23001 = enumeration{"off", "on", "offOn", "onOff"}
+
+// enum23002 names the values of DPT 23.002 / Alarm Reaction.
+var enum23002 = enumeration{"noAlarm", "alarmPositionUp", "alarmPositionDown"}
+
+// enum23003 names the values of DPT 23.003 / Up Down Action.
+var enum23003 = enumeration{"up", "down", "upDown", "downUp"}
+
+// enum23102 names the values of DPT 23.102 / HVAC Push Button Action.
+var enum23102 = enumeration{"comfortEconomy", "comfortNothing", "economyNothing", "buildingProtectionAuto"}
@@ -0,0 +1,58 @@
+package dpt
+
+import "fmt"
+
+// SceneInfo is the structured value of DPT 26.001, e.g. {"scene":5,"active":true}.
+type SceneInfo struct {
+	Scene  uint8 `json:"scene"`  // 0-63
+	Active bool  `json:"active"` // Scene is active or inactive
+}
+
+// DPT_26001 represents DPT 26.001 / Scene Info.
+type DPT_26001 SceneInfo
+
+func (d DPT_26001) Pack() []byte {
+	value := d.Scene & 0x3F
+	if !d.Active {
+		value |= 0x40
+	}
+	return packU8(value)
+}
+
+func (d *DPT_26001) Unpack(data []byte) error {
+	var value uint8
+	if err := unpackU8(data, &value); err != nil {
+		return err
+	}
+	d.Scene = value & 0x3F
+	d.Active = value&0x40 == 0
+	return nil
+}
+
+func (d DPT_26001) Unit() string {
+	return ""
+}
+
+func (d DPT_26001) Value() interface{} {
+	return SceneInfo(d)
+}
+
+func (d DPT_26001) String() string {
+	if d.Active {
+		return fmt.Sprintf("%d active", d.Scene)
+	}
+	return fmt.Sprintf("%d inactive", d.Scene)
+}
+
+// UnmarshalText accepts the JSON form.
+func (d *DPT_26001) UnmarshalText(text []byte) error {
+	value := SceneInfo{Active: true}
+	if err := unmarshalObject(string(text), &value); err != nil {
+		return err
+	}
+	if value.Scene > 63 {
+		return fmt.Errorf("scene %d is out of range 0-63", value.Scene)
+	}
+	*d = DPT_26001(value)
+	return nil
+}
@@ -0,0 +1,78 @@
+package dpt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DPT_27001 represents DPT 27.001 / Combined Info On Off.
+// It holds the on/off state of up to 16 outputs, of which only the ones in Mask are valid.
+type DPT_27001 struct {
+	Mask  uint16 // Bit n = 1: the state of output n+1 is valid
+	State uint16 // Bit n = 1: output n+1 is on
+}
+
+func (d DPT_27001) Pack() []byte {
+	return packU32(uint32(d.Mask)<<16 | uint32(d.State))
+}
+
+func (d *DPT_27001) Unpack(data []byte) error {
+	var value uint32
+	if err := unpackU32(data, &value); err != nil {
+		return err
+	}
+	d.Mask = uint16(value >> 16)
+	d.State = uint16(value)
+	return nil
+}
+
+func (d DPT_27001) Unit() string {
+	return ""
+}
+
+// Value returns the state of the valid outputs by output number, e.g. {"1":true,"3":false}.
+func (d DPT_27001) Value() interface{} {
+	value := make(map[string]bool)
+	for i := 0; i < 16; i++ {
+		if d.Mask&(1<<i) != 0 {
+			value[strconv.Itoa(i+1)] = d.State&(1<<i) != 0
+		}
+	}
+	return value
+}
+
+func (d DPT_27001) String() string {
+	var outputs []string
+	for i := 0; i < 16; i++ {
+		if d.Mask&(1<<i) == 0 {
+			continue
+		}
+		state := "off"
+		if d.State&(1<<i) != 0 {
+			state = "on"
+		}
+		outputs = append(outputs, fmt.Sprintf("%d: %s", i+1, state))
+	}
+	return strings.Join(outputs, ", ")
+}
+
+// UnmarshalText accepts the JSON form, outputs that are left out are not valid.
+func (d *DPT_27001) UnmarshalText(text []byte) error {
+	var outputs map[string]bool
+	if err := unmarshalObject(string(text), &outputs); err != nil {
+		return err
+	}
+	*d = DPT_27001{}
+	for output, on := range outputs {
+		n, err := strconv.Atoi(output)
+		if err != nil || n < 1 || n > 16 {
+			return fmt.Errorf("output \"%s\" is not a number 1-16", output)
+		}
+		d.Mask |= 1 << (n - 1)
+		if on {
+			d.State |= 1 << (n - 1)
+		}
+	}
+	return nil
+}
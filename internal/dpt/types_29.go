@@ -0,0 +1,97 @@
+package dpt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DPT_29010 represents DPT 29.010 / Active Energy (V64) in Wh.
+type DPT_29010 int64
+
+func (d DPT_29010) Pack() []byte {
+	return packV64(int64(d))
+}
+
+func (d *DPT_29010) Unpack(data []byte) error {
+	return unpackV64(data, (*int64)(d))
+}
+
+func (d DPT_29010) Unit() string {
+	return "Wh"
+}
+
+func (d DPT_29010) Value() interface{} {
+	return int64(d)
+}
+
+func (d DPT_29010) String() string {
+	return fmt.Sprintf("%d Wh", int64(d))
+}
+
+func (d *DPT_29010) UnmarshalText(text []byte) error {
+	return parseV64(string(text), (*int64)(d))
+}
+
+// DPT_29011 represents DPT 29.011 / Apparent Energy (V64) in VAh.
+type DPT_29011 int64
+
+func (d DPT_29011) Pack() []byte {
+	return packV64(int64(d))
+}
+
+func (d *DPT_29011) Unpack(data []byte) error {
+	return unpackV64(data, (*int64)(d))
+}
+
+func (d DPT_29011) Unit() string {
+	return "VAh"
+}
+
+func (d DPT_29011) Value() interface{} {
+	return int64(d)
+}
+
+func (d DPT_29011) String() string {
+	return fmt.Sprintf("%d VAh", int64(d))
+}
+
+func (d *DPT_29011) UnmarshalText(text []byte) error {
+	return parseV64(string(text), (*int64)(d))
+}
+
+// DPT_29012 represents DPT 29.012 / Reactive Energy (V64) in VARh.
+type DPT_29012 int64
+
+func (d DPT_29012) Pack() []byte {
+	return packV64(int64(d))
+}
+
+func (d *DPT_29012) Unpack(data []byte) error {
+	return unpackV64(data, (*int64)(d))
+}
+
+func (d DPT_29012) Unit() string {
+	return "VARh"
+}
+
+func (d DPT_29012) Value() interface{} {
+	return int64(d)
+}
+
+func (d DPT_29012) String() string {
+	return fmt.Sprintf("%d VARh", int64(d))
+}
+
+func (d *DPT_29012) UnmarshalText(text []byte) error {
+	return parseV64(string(text), (*int64)(d))
+}
+
+func parseV64(text string, i *int64) error {
+	value, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	if err != nil {
+		return fmt.Errorf("could not convert to int64: %s", text)
+	}
+	*i = value
+	return nil
+}
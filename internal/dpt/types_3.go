@@ -0,0 +1,209 @@
+package dpt
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vapourismo/knx-go/knx/dpt"
+)
+
+var regexpStepControl = regexp.MustCompile(`^(up|down|increase|decrease)\s+(?:1/(\d+)|(\d))$`)
+
+// StepControl is the readable form of DPT 3.007 and 3.008, e.g. "up 1/4" or "stop".
+// It is emitted as JSON object like {"direction":"up","stepCode":3}.
+type StepControl struct {
+	Direction string `json:"direction"` // up, down or stop
+	StepCode  uint8  `json:"stepCode"`  // 0 = stop, 1-7 = step of 1/2^(StepCode-1) of the full range
+}
+
+// ParseStepControl parses a step control value given as text, e.g. "up 1/4", "down 3" or "stop",
+// or as JSON object, e.g. {"direction":"up","stepCode":3}.
+func ParseStepControl(value string) (StepControl, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") {
+		var s StepControl
+		if err := json.Unmarshal([]byte(value), &s); err != nil {
+			return StepControl{}, err
+		}
+		return s.normalize()
+	}
+
+	value = strings.ToLower(value)
+	if value == "stop" {
+		return StepControl{Direction: "stop"}, nil
+	}
+
+	matches := regexpStepControl.FindStringSubmatch(value)
+	if matches == nil {
+		return StepControl{}, fmt.Errorf("expected e.g. \"up 1/4\", \"down 3\" or \"stop\"")
+	}
+
+	s := StepControl{Direction: matches[1]}
+	if matches[2] != "" {
+		// Fractions of the full range are 1/1, 1/2, 1/4, ... 1/64
+		denominator, _ := strconv.ParseUint(matches[2], 10, 8)
+		for code := uint8(1); code <= 7; code++ {
+			if denominator == 1<<(code-1) {
+				s.StepCode = code
+			}
+		}
+		if s.StepCode == 0 {
+			return StepControl{}, fmt.Errorf("step 1/%s is not one of 1/1, 1/2, 1/4 ... 1/64", matches[2])
+		}
+	} else {
+		code, _ := strconv.ParseUint(matches[3], 10, 8)
+		s.StepCode = uint8(code)
+	}
+	return s.normalize()
+}
+
+// normalize checks the step code and maps the aliases increase and decrease to up and down.
+func (s StepControl) normalize() (StepControl, error) {
+	switch strings.ToLower(s.Direction) {
+	case "up", "increase":
+		s.Direction = "up"
+	case "down", "decrease":
+		s.Direction = "down"
+	case "stop":
+		s.Direction = "stop"
+		s.StepCode = 0
+	default:
+		return StepControl{}, fmt.Errorf("unknown direction \"%s\"", s.Direction)
+	}
+	if s.StepCode > 7 {
+		return StepControl{}, fmt.Errorf("step code %d is out of range 0-7", s.StepCode)
+	}
+	if s.StepCode == 0 {
+		s.Direction = "stop"
+	}
+	return s, nil
+}
+
+// Dimming returns the value as DPT 3.007.
+func (s StepControl) Dimming() DPT_3007 {
+	return DPT_3007{Increase: s.Direction == "up", StepCode: s.StepCode}
+}
+
+// Blind returns the value as DPT 3.008.
+func (s StepControl) Blind() DPT_3008 {
+	return DPT_3008{Down: s.Direction == "down", StepCode: s.StepCode}
+}
+
+func (s StepControl) String() string {
+	if s.StepCode == 0 {
+		return "stop"
+	}
+	return fmt.Sprintf("%s 1/%d", s.Direction, 1<<(s.StepCode-1))
+}
+
+func packStepControl(control bool, stepCode uint8) []byte {
+	value := stepCode & 0x07
+	if control {
+		value |= 0x08
+	}
+	return []byte{value}
+}
+
+func unpackStepControl(data []byte, control *bool, stepCode *uint8) error {
+	if len(data) != 1 {
+		return dpt.ErrInvalidLength
+	}
+	*control = data[0]&0x08 != 0
+	*stepCode = data[0] & 0x07
+	return nil
+}
+
+// DPT_3007 represents DPT 3.007 / Dimming Control.
+type DPT_3007 struct {
+	Increase bool  // 0 = decrease, 1 = increase
+	StepCode uint8 // 0 = break, 1-7 = number of intervals as 2^(StepCode-1)
+}
+
+func (d DPT_3007) Pack() []byte {
+	return packStepControl(d.Increase, d.StepCode)
+}
+
+func (d *DPT_3007) Unpack(data []byte) error {
+	return unpackStepControl(data, &d.Increase, &d.StepCode)
+}
+
+func (d DPT_3007) Unit() string {
+	return ""
+}
+
+// stepControl returns the readable form, with increase as up and decrease as down.
+func (d DPT_3007) stepControl() StepControl {
+	if d.StepCode == 0 {
+		return StepControl{Direction: "stop"}
+	}
+	if d.Increase {
+		return StepControl{Direction: "up", StepCode: d.StepCode}
+	}
+	return StepControl{Direction: "down", StepCode: d.StepCode}
+}
+
+func (d DPT_3007) Value() interface{} {
+	return d.stepControl()
+}
+
+func (d DPT_3007) String() string {
+	return d.stepControl().String()
+}
+
+func (d *DPT_3007) UnmarshalText(text []byte) error {
+	s, err := ParseStepControl(string(text))
+	if err != nil {
+		return err
+	}
+	*d = s.Dimming()
+	return nil
+}
+
+// DPT_3008 represents DPT 3.008 / Blind Control.
+type DPT_3008 struct {
+	Down     bool  // 0 = up, 1 = down
+	StepCode uint8 // 0 = break, 1-7 = number of intervals as 2^(StepCode-1)
+}
+
+func (d DPT_3008) Pack() []byte {
+	return packStepControl(d.Down, d.StepCode)
+}
+
+func (d *DPT_3008) Unpack(data []byte) error {
+	return unpackStepControl(data, &d.Down, &d.StepCode)
+}
+
+func (d DPT_3008) Unit() string {
+	return ""
+}
+
+// stepControl returns the readable form.
+func (d DPT_3008) stepControl() StepControl {
+	if d.StepCode == 0 {
+		return StepControl{Direction: "stop"}
+	}
+	if d.Down {
+		return StepControl{Direction: "down", StepCode: d.StepCode}
+	}
+	return StepControl{Direction: "up", StepCode: d.StepCode}
+}
+
+func (d DPT_3008) Value() interface{} {
+	return d.stepControl()
+}
+
+func (d DPT_3008) String() string {
+	return d.stepControl().String()
+}
+
+func (d *DPT_3008) UnmarshalText(text []byte) error {
+	s, err := ParseStepControl(string(text))
+	if err != nil {
+		return err
+	}
+	*d = s.Blind()
+	return nil
+}
@@ -0,0 +1,123 @@
+package dpt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDPT_3007_PackUnpack(t *testing.T) {
+	tests := []struct {
+		value DPT_3007
+		data  byte
+		text  string
+	}{
+		{DPT_3007{Increase: true, StepCode: 3}, 0x0B, "up 1/4"},
+		{DPT_3007{Increase: false, StepCode: 1}, 0x01, "down 1/1"},
+		{DPT_3007{Increase: true, StepCode: 7}, 0x0F, "up 1/64"},
+		{DPT_3007{}, 0x00, "stop"},
+	}
+	for _, tt := range tests {
+		data := tt.value.Pack()
+		if len(data) != 1 || data[0] != tt.data {
+			t.Errorf("%+v packed to %v, want [%d]", tt.value, data, tt.data)
+		}
+		var unpacked DPT_3007
+		if err := unpacked.Unpack(data); err != nil {
+			t.Fatalf("Unpack(%v) failed: %v", data, err)
+		}
+		if unpacked != tt.value {
+			t.Errorf("Unpack(%v) = %+v, want %+v", data, unpacked, tt.value)
+		}
+		if unpacked.String() != tt.text {
+			t.Errorf("String() = %q, want %q", unpacked.String(), tt.text)
+		}
+	}
+
+	var d DPT_3007
+	if err := d.Unpack([]byte{0, 0x0B}); err == nil {
+		t.Error("Expected an error when unpacking 2 bytes")
+	}
+}
+
+func TestDPT_3008_PackUnpack(t *testing.T) {
+	tests := []struct {
+		value DPT_3008
+		data  byte
+		text  string
+	}{
+		{DPT_3008{Down: true, StepCode: 1}, 0x09, "down 1/1"},
+		{DPT_3008{Down: false, StepCode: 2}, 0x02, "up 1/2"},
+		{DPT_3008{Down: true}, 0x08, "stop"},
+	}
+	for _, tt := range tests {
+		data := tt.value.Pack()
+		if len(data) != 1 || data[0] != tt.data {
+			t.Errorf("%+v packed to %v, want [%d]", tt.value, data, tt.data)
+		}
+		var unpacked DPT_3008
+		if err := unpacked.Unpack(data); err != nil {
+			t.Fatalf("Unpack(%v) failed: %v", data, err)
+		}
+		if unpacked != tt.value {
+			t.Errorf("Unpack(%v) = %+v, want %+v", data, unpacked, tt.value)
+		}
+		if unpacked.String() != tt.text {
+			t.Errorf("String() = %q, want %q", unpacked.String(), tt.text)
+		}
+	}
+}
+
+func TestParseStepControl(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    StepControl
+		wantErr bool
+	}{
+		{"up 1/4", StepControl{"up", 3}, false},
+		{"Down 1/1", StepControl{"down", 1}, false},
+		{"increase 5", StepControl{"up", 5}, false},
+		{"decrease 1/64", StepControl{"down", 7}, false},
+		{"stop", StepControl{"stop", 0}, false},
+		{"up 0", StepControl{"stop", 0}, false},
+		{`{"direction":"down","stepCode":2}`, StepControl{"down", 2}, false},
+		{`{"direction":"stop"}`, StepControl{"stop", 0}, false},
+		{"up 1/3", StepControl{}, true},
+		{"up 8", StepControl{}, true},
+		{"left 1/2", StepControl{}, true},
+		{`{"direction":"up","stepCode":9}`, StepControl{}, true},
+		{`{"direction":`, StepControl{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStepControl(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStepControl(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStepControl(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestStepControlRoundTrip(t *testing.T) {
+	for _, text := range []string{"up 1/4", "down 1/64", "stop"} {
+		s, err := ParseStepControl(text)
+		if err != nil {
+			t.Fatalf("ParseStepControl(%q) failed: %v", text, err)
+		}
+		if got := s.Dimming().String(); got != text {
+			t.Errorf("Dimming() of %q = %q", text, got)
+		}
+		if got := s.Blind().String(); got != text {
+			t.Errorf("Blind() of %q = %q", text, got)
+		}
+	}
+
+	data, err := json.Marshal(DPT_3008{Down: true, StepCode: 3}.Value())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"direction":"down","stepCode":3}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+}
@@ -0,0 +1,107 @@
+package dpt
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// DPT_4001 represents DPT 4.001 / Char ASCII.
+// The character should be ASCII, other characters are replaced with a space = 0x20.
+type DPT_4001 string
+
+func (d DPT_4001) Pack() []byte {
+	return packU8(packChar(string(d), unicode.MaxASCII))
+}
+
+func (d *DPT_4001) Unpack(data []byte) error {
+	var value uint8
+	if err := unpackU8(data, &value); err != nil {
+		return err
+	}
+	*d = DPT_4001(unpackChar(value & unicode.MaxASCII))
+	return nil
+}
+
+func (d DPT_4001) Unit() string {
+	return ""
+}
+
+func (d DPT_4001) Value() interface{} {
+	return string(d)
+}
+
+func (d DPT_4001) String() string {
+	return string(d)
+}
+
+func (d *DPT_4001) UnmarshalText(text []byte) error {
+	value, err := parseChar(string(text))
+	*d = DPT_4001(value)
+	return err
+}
+
+// DPT_4002 represents DPT 4.002 / Char 8859-1.
+// The character must be ISO-8859-1, other characters are replaced with a space = 0x20.
+type DPT_4002 string
+
+func (d DPT_4002) Pack() []byte {
+	return packU8(packChar(string(d), unicode.MaxLatin1))
+}
+
+func (d *DPT_4002) Unpack(data []byte) error {
+	var value uint8
+	if err := unpackU8(data, &value); err != nil {
+		return err
+	}
+	*d = DPT_4002(unpackChar(value))
+	return nil
+}
+
+func (d DPT_4002) Unit() string {
+	return ""
+}
+
+func (d DPT_4002) Value() interface{} {
+	return string(d)
+}
+
+func (d DPT_4002) String() string {
+	return string(d)
+}
+
+func (d *DPT_4002) UnmarshalText(text []byte) error {
+	value, err := parseChar(string(text))
+	*d = DPT_4002(value)
+	return err
+}
+
+// packChar returns the first character of s, 0 if s is empty.
+func packChar(s string, max rune) uint8 {
+	for _, r := range s {
+		if r > max {
+			return 0x20
+		}
+		return uint8(r)
+	}
+	return 0
+}
+
+// unpackChar returns the character c, or an empty string for 0.
+func unpackChar(c uint8) string {
+	if c == 0 {
+		return ""
+	}
+	return string(rune(c))
+}
+
+// parseChar accepts a single character, optionally as JSON string.
+func parseChar(text string) (string, error) {
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+	if len([]rune(text)) > 1 {
+		return "", fmt.Errorf("\"%s\" is more than one character", text)
+	}
+	return text, nil
+}
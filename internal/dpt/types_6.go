@@ -0,0 +1,75 @@
+package dpt
+
+import "fmt"
+
+// StatusMode is the structured value of DPT 6.020, e.g. {"a":false,"b":true,"c":false,"d":false,"e":false,"mode":1}.
+type StatusMode struct {
+	A    bool  `json:"a"` // Status bits A-E: 0 = set, 1 = clear
+	B    bool  `json:"b"`
+	C    bool  `json:"c"`
+	D    bool  `json:"d"`
+	E    bool  `json:"e"`
+	Mode uint8 `json:"mode"` // 0-2
+}
+
+// DPT_6020 represents DPT 6.020 / Status with Mode.
+type DPT_6020 StatusMode
+
+func (d DPT_6020) Pack() []byte {
+	var value uint8
+	for i, bit := range []bool{d.E, d.D, d.C, d.B, d.A} {
+		value |= packBit(bit) << (3 + i)
+	}
+	if d.Mode <= 2 {
+		value |= 1 << d.Mode
+	}
+	return packU8(value)
+}
+
+func (d *DPT_6020) Unpack(data []byte) error {
+	var value uint8
+	if err := unpackU8(data, &value); err != nil {
+		return err
+	}
+	switch value & 0x07 {
+	case 1:
+		d.Mode = 0
+	case 2:
+		d.Mode = 1
+	case 4:
+		d.Mode = 2
+	default:
+		return fmt.Errorf("invalid mode bits %03b", value&0x07)
+	}
+	d.A = value&0x80 != 0
+	d.B = value&0x40 != 0
+	d.C = value&0x20 != 0
+	d.D = value&0x10 != 0
+	d.E = value&0x08 != 0
+	return nil
+}
+
+func (d DPT_6020) Unit() string {
+	return ""
+}
+
+func (d DPT_6020) Value() interface{} {
+	return StatusMode(d)
+}
+
+func (d DPT_6020) String() string {
+	return fmt.Sprintf("A: %t B: %t C: %t D: %t E: %t Mode: %d", d.A, d.B, d.C, d.D, d.E, d.Mode)
+}
+
+// UnmarshalText accepts the JSON form.
+func (d *DPT_6020) UnmarshalText(text []byte) error {
+	var value StatusMode
+	if err := unmarshalObject(string(text), &value); err != nil {
+		return err
+	}
+	if value.Mode > 2 {
+		return fmt.Errorf("mode %d is out of range 0-2", value.Mode)
+	}
+	*d = DPT_6020(value)
+	return nil
+}
@@ -2,13 +2,140 @@ package dpt
 
 import (
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/vapourismo/knx-go/knx/dpt"
 )
 
 var dptTypes = map[string]interface{}{
+	// 2.xxx
+	"2.001": new(DPT_2001),
+	"2.002": new(DPT_2002),
+	"2.003": new(DPT_2003),
+	"2.004": new(DPT_2004),
+	"2.005": new(DPT_2005),
+	"2.006": new(DPT_2006),
+	"2.007": new(DPT_2007),
+	"2.008": new(DPT_2008),
+	"2.009": new(DPT_2009),
+	"2.010": new(DPT_2010),
+	"2.011": new(DPT_2011),
+	"2.012": new(DPT_2012),
+
+	// 3.xxx
+	"3.007": new(DPT_3007),
+	"3.008": new(DPT_3008),
+
+	// 4.xxx
+	"4.001": new(DPT_4001),
+	"4.002": new(DPT_4002),
+
+	// 6.xxx
+	"6.020": new(DPT_6020),
+
+	// 15.xxx
+	"15.000": new(DPT_15000),
+
+	// 18.xxx
+	"18.001": new(DPT_18001),
+
 	// 19.xxx
 	"19.001": new(DPT_19001),
+
+	// 20.xxx
+	"20.001":  enum8Type(&enum20001, 0),
+	"20.002":  enum8Type(&enum20002, 0),
+	"20.003":  enum8Type(&enum20003, 0),
+	"20.004":  enum8Type(&enum20004, 0),
+	"20.005":  enum8Type(&enum20005, 0),
+	"20.006":  enum8Type(&enum20006, 0),
+	"20.007":  enum8Type(&enum20007, 0),
+	"20.008":  enum8Type(&enum20008, 0),
+	"20.011":  enum8Type(&enum20011, 0),
+	"20.012":  enum8Type(&enum20012, 0),
+	"20.013":  enum8Type(&enum20013, 0),
+	"20.014":  enum8Type(&enum20014, 0),
+	"20.017":  enum8Type(&enum20017, 0),
+	"20.020":  enum8Type(&enum20020, 0),
+	"20.021":  enum8Type(&enum20021, 0),
+	"20.022":  enum8Type(&enum20022, 0),
+	"20.100":  enum8Type(&enum20100, 0),
+	"20.101":  enum8Type(&enum20101, 0),
+	"20.102":  enum8Type(&enum20102, 0),
+	"20.103":  enum8Type(&enum20103, 0),
+	"20.104":  enum8Type(&enum20104, 0),
+	"20.105":  enum8Type(&enum20105, 0),
+	"20.106":  enum8Type(&enum20106, 0),
+	"20.107":  enum8Type(&enum20107, 0),
+	"20.108":  enum8Type(&enum20108, 0),
+	"20.109":  enum8Type(&enum20109, 0),
+	"20.110":  enum8Type(&enum20110, 0),
+	"20.111":  enum8Type(&enum20111, 0),
+	"20.112":  enum8Type(&enum20112, 0),
+	"20.113":  enum8Type(&enum20113, 0),
+	"20.115":  enum8Type(&enum20115, 0),
+	"20.116":  enum8Type(&enum20116, 0),
+	"20.120":  enum8Type(&enum20120, 0),
+	"20.121":  enum8Type(&enum20121, 0),
+	"20.122":  enum8Type(&enum20122, 0),
+	"20.600":  enum8Type(&enum20600, 0),
+	"20.601":  enum8Type(&enum20601, 0),
+	"20.602":  enum8Type(&enum20602, 0),
+	"20.603":  enum8Type(&enum20603, 0),
+	"20.604":  enum8Type(&enum20604, 0),
+	"20.605":  enum8Type(&enum20605, 0),
+	"20.606":  enum8Type(&enum20606, 0),
+	"20.607":  enum8Type(&enum20607, 0),
+	"20.608":  enum8Type(&enum20608, 0),
+	"20.609":  enum8Type(&enum20609, 0),
+	"20.610":  enum8Type(&enum20610, 0),
+	"20.801":  enum8Type(&enum20801, 0),
+	"20.802":  enum8Type(&enum20802, 0),
+	"20.803":  enum8Type(&enum20803, 0),
+	"20.804":  enum8Type(&enum20804, 0),
+	"20.1000": enum8Type(&enum201000, 0),
+	"20.1002": enum8Type(&enum201002, 0),
+	"20.1003": enum8Type(&enum201003, 0),
+	"20.1004": enum8Type(&enum201004, 0),
+	"20.1200": enum8Type(&enum201200, 0),
+	"20.1202": enum8Type(&enum201202, 0),
+
+	// 21.xxx
+	"21.001":  new(DPT_21001),
+	"21.002":  new(DPT_21002),
+	"21.100":  new(DPT_21100),
+	"21.101":  new(DPT_21101),
+	"21.102":  new(DPT_21102),
+	"21.103":  new(DPT_21103),
+	"21.104":  new(DPT_21104),
+	"21.105":  new(DPT_21105),
+	"21.106":  new(DPT_21106),
+	"21.601":  new(DPT_21601),
+	"21.1010": new(DPT_211010),
+
+	// 22.xxx
+	"22.100":  new(DPT_22100),
+	"22.101":  new(DPT_22101),
+	"22.1000": new(DPT_221000),
+	"22.1010": new(DPT_221010),
+
+	// 23.xxx
+	"23.001": enum8Type(&enum23001, 0x03),
+	"23.002": enum8Type(&enum23002, 0x03),
+	"23.003": enum8Type(&enum23003, 0x03),
+	"23.102": enum8Type(&enum23102, 0x03),
+
+	// 26.xxx
+	"26.001": new(DPT_26001),
+
+	// 27.xxx
+	"27.001": new(DPT_27001),
+
+	// 29.xxx
+	"29.010": new(DPT_29010),
+	"29.011": new(DPT_29011),
+	"29.012": new(DPT_29012),
 }
 
 // Produce returns a new instance of the specified datapoint type.
@@ -17,6 +144,11 @@ var dptTypes = map[string]interface{}{
 func Produce(name string) (dpt.Datapoint, bool) {
 	// First, check if the datapoint type is registered in the local registry
 	if x, ok := dptTypes[name]; ok {
+		// Types sharing one implementation, e.g. the enumerations, are registered by constructor
+		if produce, ok := x.(func() dpt.Datapoint); ok {
+			return produce(), true
+		}
+
 		d_type := reflect.TypeOf(x).Elem()
 		d := reflect.New(d_type).Interface()
 
@@ -55,3 +187,32 @@ func ListSupportedTypes() []string {
 
 	return knxGoTypes
 }
+
+// preferredDatapoints are the default subtypes of main types whose lowest numbered subtype is rarely used.
+var preferredDatapoints = map[string]string{
+	"20": "20.102", // HVAC Mode
+}
+
+// DefaultDatapoint returns the datapoint type used for a main type given without subtype,
+// which is the supported subtype with the lowest number, e.g. 9.001 for 9, unless another
+// subtype is preferred, e.g. 20.102 for 20.
+func DefaultDatapoint(mainType string) (string, bool) {
+	if name, ok := preferredDatapoints[mainType]; ok {
+		return name, true
+	}
+	best, bestSubtype := "", -1
+	for _, name := range ListSupportedTypes() {
+		main, sub, ok := strings.Cut(name, ".")
+		if !ok || main != mainType {
+			continue
+		}
+		subtype, err := strconv.Atoi(sub)
+		if err != nil {
+			continue
+		}
+		if bestSubtype < 0 || subtype < bestSubtype {
+			best, bestSubtype = name, subtype
+		}
+	}
+	return best, best != ""
+}
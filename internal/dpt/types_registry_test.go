@@ -0,0 +1,24 @@
+package dpt
+
+import "testing"
+
+func TestDefaultDatapoint(t *testing.T) {
+	tests := []struct {
+		mainType string
+		want     string
+		ok       bool
+	}{
+		{"1", "1.001", true},
+		{"6", "6.010", true},
+		{"9", "9.001", true},
+		{"14", "14.000", true},
+		{"20", "20.102", true},
+		{"999", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := DefaultDatapoint(tt.mainType)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("DefaultDatapoint(%q) = %q, %v, want %q, %v", tt.mainType, got, ok, tt.want, tt.ok)
+		}
+	}
+}
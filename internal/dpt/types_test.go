@@ -0,0 +1,206 @@
+package dpt
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/vapourismo/knx-go/knx/dpt"
+)
+
+// datapoint is implemented by the datapoint types of the local registry.
+type datapoint interface {
+	dpt.Datapoint
+	Valuer
+	encoding.TextUnmarshaler
+}
+
+func TestDatapointRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value dpt.Datapoint
+		data  []byte
+		text  string // String()
+		write bool   // Whether text is accepted by UnmarshalText, the JSON form always is
+		json  string // Value() as JSON
+	}{
+		{"2.001", &DPT_2001{BoolControl{Control: true, Value: true}}, []byte{3}, "on", true, `{"control":true,"value":true}`},
+		{"2.008", &DPT_2008{BoolControl{Control: true}}, []byte{2}, "up", true, `{"control":true,"value":false}`},
+		{"2.010", &DPT_2010{}, []byte{0}, "no control", true, `{"control":false,"value":false}`},
+		{"3.007", &DPT_3007{Increase: true, StepCode: 3}, []byte{0x0B}, "up 1/4", true, `{"direction":"up","stepCode":3}`},
+		{"4.001", ptr(DPT_4001("A")), []byte{0, 'A'}, "A", true, `"A"`},
+		{"4.002", ptr(DPT_4002("é")), []byte{0, 0xE9}, "é", true, `"é"`},
+		{"6.020", &DPT_6020{A: true, E: true, Mode: 2}, []byte{0, 0x8C}, "A: true B: false C: false D: false E: true Mode: 2", false, `{"a":true,"b":false,"c":false,"d":false,"e":true,"mode":2}`},
+		{"15.000", &DPT_15000{AccessCode: 123456, Permission: true, Index: 5}, []byte{0, 0x12, 0x34, 0x56, 0x45},
+			"123456 Error: false Permission: true ReadRightToLeft: false Encrypted: false Index: 5", false,
+			`{"accessCode":123456,"error":false,"permission":true,"readRightToLeft":false,"encrypted":false,"index":5}`},
+		{"18.001", &DPT_18001{Scene: 5, Learn: true}, []byte{0, 0x85}, "learn 5", true, `{"scene":5,"learn":true}`},
+		{"18.001", &DPT_18001{Scene: 63}, []byte{0, 0x3F}, "activate 63", true, `{"scene":63,"learn":false}`},
+		{"20.102", &enum8{&enum20102, 0, 1}, []byte{0, 1}, "comfort", true, `"comfort"`},
+		{"20.105", &enum8{&enum20105, 0, 20}, []byte{0, 20}, "noDem", true, `"noDem"`},
+		{"20.1000", &enum8{&enum201000, 0, 255}, []byte{0, 0xFF}, "noLayer", true, `"noLayer"`},
+		{"21.001", ptr(DPT_21001(0x0A)), []byte{0, 0x0A}, "fault, inAlarm", true, `{"alarmUnAck":false,"fault":true,"inAlarm":true,"outOfService":false,"overridden":false}`},
+		{"21.104", ptr(DPT_21104(0)), []byte{0, 0}, "none", true, `{"gas":false,"oil":false,"solidState":false}`},
+		{"22.1000", ptr(DPT_221000(0x22)), []byte{0, 0, 0x22}, "tp1, knxip", true, `{"knxip":true,"pl110":false,"rf":false,"tp1":true}`},
+		{"22.101", ptr(DPT_22101(0x0101)), []byte{0, 0x01, 0x01}, "fault, heatMode", true, ""},
+		{"23.001", &enum8{&enum23001, 0x03, 2}, []byte{2}, "offOn", true, `"offOn"`},
+		{"23.102", &enum8{&enum23102, 0x03, 3}, []byte{3}, "buildingProtectionAuto", true, `"buildingProtectionAuto"`},
+		{"26.001", &DPT_26001{Scene: 5, Active: true}, []byte{0, 5}, "5 active", false, `{"scene":5,"active":true}`},
+		{"26.001", &DPT_26001{Scene: 63}, []byte{0, 0x7F}, "63 inactive", false, `{"scene":63,"active":false}`},
+		{"27.001", &DPT_27001{Mask: 0x0005, State: 0x0001}, []byte{0, 0, 0x05, 0, 0x01}, "1: on, 3: off", false, `{"1":true,"3":false}`},
+		{"29.010", ptr(DPT_29010(-1234567890123)), []byte{0, 0xFF, 0xFF, 0xFE, 0xE0, 0x8E, 0x04, 0xFB, 0x35}, "-1234567890123 Wh", false, "-1234567890123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.value.Pack(); !bytes.Equal(got, tt.data) {
+				t.Errorf("Pack() = %v, want %v", got, tt.data)
+			}
+
+			produced, ok := Produce(tt.name)
+			if !ok {
+				t.Fatalf("Produce(%q) failed", tt.name)
+			}
+			if err := produced.Unpack(tt.data); err != nil {
+				t.Fatalf("Unpack(%v) failed: %v", tt.data, err)
+			}
+			if !reflect.DeepEqual(produced, tt.value) {
+				t.Errorf("Unpack(%v) = %+v, want %+v", tt.data, produced, tt.value)
+			}
+			if produced.String() != tt.text {
+				t.Errorf("String() = %q, want %q", produced.String(), tt.text)
+			}
+
+			d := produced.(datapoint)
+			data, err := json.Marshal(d.Value())
+			if err != nil {
+				t.Fatalf("Marshal(%v) failed: %v", d.Value(), err)
+			}
+			if tt.json != "" && string(data) != tt.json {
+				t.Errorf("Value() = %s, want %s", data, tt.json)
+			}
+
+			texts := []string{string(data)}
+			if tt.write {
+				texts = append(texts, tt.text)
+			}
+			for _, text := range texts {
+				parsed, _ := Produce(tt.name)
+				if err := parsed.(datapoint).UnmarshalText([]byte(text)); err != nil {
+					t.Errorf("UnmarshalText(%q) failed: %v", text, err)
+					continue
+				}
+				if !bytes.Equal(parsed.Pack(), tt.data) {
+					t.Errorf("UnmarshalText(%q) packed to %v, want %v", text, parsed.Pack(), tt.data)
+				}
+			}
+		})
+	}
+}
+
+func TestDatapointUnmarshalTextErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"2.001", "maybe"},
+		{"18.001", "64"},
+		{"18.001", "store 5"},
+		{"18.001", `{"scene":5,"learn":true,"active":true}`},
+		{"20.102", "heat"},
+		{"20.102", "5"},
+		{"4.001", "AB"},
+		{"6.020", `{"mode":3}`},
+		{"15.000", `{"accessCode":1234567}`},
+		{"21.001", "fault, burning"},
+		{"21.001", `{"burning":false}`},
+		{"23.003", "sideways"},
+		{"23.003", "7"},
+		{"26.001", `{"scene":64}`},
+		{"27.001", `{"17":true}`},
+		{"29.010", "12.5"},
+	}
+	for _, tt := range tests {
+		d, _ := Produce(tt.name)
+		if err := d.(datapoint).UnmarshalText([]byte(tt.text)); err == nil {
+			t.Errorf("UnmarshalText(%q) for %s succeeded, want an error", tt.text, tt.name)
+		}
+	}
+}
+
+func TestDatapointTextForms(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		data []byte
+	}{
+		{"2.001", "control off", []byte{2}},
+		{"2.001", "true", []byte{3}},
+		{"2.001", `{"control":true,"value":"on"}`, []byte{3}},
+		{"18.001", "5", []byte{0, 5}},
+		{"18.001", "Learn 5", []byte{0, 0x85}},
+		{"18.001", "133", []byte{0, 0x85}},
+		{"18.001", `{"scene":5}`, []byte{0, 5}},
+		{"20.102", "ECONOMY", []byte{0, 3}},
+		{"20.102", "2", []byte{0, 2}},
+		{"20.102", `"standby"`, []byte{0, 2}},
+		{"21.001", `{"fault":true}`, []byte{0, 0x02}},
+		{"21.1010", "channel1, Channel8", []byte{0, 0x81}},
+		{"23.003", "1", []byte{1}},
+		{"23.003", "DownUp", []byte{3}},
+		{"26.001", `{"scene":7}`, []byte{0, 7}},
+	}
+	for _, tt := range tests {
+		d, _ := Produce(tt.name)
+		if err := d.(datapoint).UnmarshalText([]byte(tt.text)); err != nil {
+			t.Errorf("UnmarshalText(%q) for %s failed: %v", tt.text, tt.name, err)
+			continue
+		}
+		if !bytes.Equal(d.Pack(), tt.data) {
+			t.Errorf("UnmarshalText(%q) for %s packed to %v, want %v", tt.text, tt.name, d.Pack(), tt.data)
+		}
+	}
+}
+
+func TestEnumerationNames(t *testing.T) {
+	want := []string{"auto", "comfort", "standby", "economy", "buildingProtection"}
+	if got := EnumerationNames("20.102"); !reflect.DeepEqual(got, want) {
+		t.Errorf("EnumerationNames(20.102) = %v, want %v", got, want)
+	}
+	if got := EnumerationNames("20.105"); len(got) != 19 || got[18] != "noDem" {
+		t.Errorf("EnumerationNames(20.105) = %v", got)
+	}
+	if got := EnumerationNames("9.001"); got != nil {
+		t.Errorf("EnumerationNames(9.001) = %v, want nil", got)
+	}
+}
+
+func TestEnumerationUnmarshalTextKeepsValueOnError(t *testing.T) {
+	d, _ := Produce("20.102")
+	if err := d.(datapoint).UnmarshalText([]byte("economy")); err != nil {
+		t.Fatalf("UnmarshalText(economy) failed: %v", err)
+	}
+	if err := d.(datapoint).UnmarshalText([]byte("heat")); err == nil {
+		t.Fatal("UnmarshalText(heat) succeeded, want an error")
+	}
+	if d.String() != "economy" {
+		t.Errorf("String() = %q after a failed UnmarshalText, want %q", d.String(), "economy")
+	}
+}
+
+func TestLocalDatapointsImplementValuerAndTextUnmarshaler(t *testing.T) {
+	for name := range dptTypes {
+		if name == "19.001" {
+			continue
+		}
+		d, _ := Produce(name)
+		if _, ok := d.(datapoint); !ok {
+			t.Errorf("%s does not implement Valuer and encoding.TextUnmarshaler", name)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
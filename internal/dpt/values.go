@@ -0,0 +1,237 @@
+package dpt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vapourismo/knx-go/knx/dpt"
+)
+
+// Structured values of the composite datapoint types of knx-go. They are emitted as JSON objects
+// and accepted in the same form when writing. Colors, times and dates are also accepted in their
+// usual text form, e.g. "#FF8000", "Monday 14:30:00" and "2024-05-01".
+
+var regexpRgb = regexp.MustCompile(`^#([A-Fa-f0-9]{2})([A-Fa-f0-9]{2})([A-Fa-f0-9]{2})$`)
+var regexpTimeOfDay = regexp.MustCompile(`^(Monday|Tuesday|Wednesday|Thursday|Friday|Saturday|Sunday)?\s*(\d{2}):(\d{2}):(\d{2})$`)
+var regexpDate = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+
+var weekdayNames = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// isJSONObject reports whether the value looks like a JSON object rather than a text form.
+func isJSONObject(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), "{")
+}
+
+// unmarshalObject decodes a JSON object into v, rejecting unknown fields so that typos are not silently ignored.
+func unmarshalObject(value string, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// ColorRGB is the structured value of DPT 232.600, e.g. {"r":255,"g":128,"b":0}.
+type ColorRGB struct {
+	R uint8 `json:"r"`
+	G uint8 `json:"g"`
+	B uint8 `json:"b"`
+}
+
+func NewColorRGB(d dpt.DPT_232600) ColorRGB {
+	return ColorRGB{R: d.Red, G: d.Green, B: d.Blue}
+}
+
+// ParseColorRGB parses a JSON object or a hex color like "#FF8000".
+func ParseColorRGB(value string) (ColorRGB, error) {
+	var c ColorRGB
+	if isJSONObject(value) {
+		err := unmarshalObject(value, &c)
+		return c, err
+	}
+
+	matches := regexpRgb.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return c, fmt.Errorf("expected a JSON object or a hex color like #FF8000")
+	}
+	red, _ := strconv.ParseUint(matches[1], 16, 8)
+	green, _ := strconv.ParseUint(matches[2], 16, 8)
+	blue, _ := strconv.ParseUint(matches[3], 16, 8)
+	return ColorRGB{R: uint8(red), G: uint8(green), B: uint8(blue)}, nil
+}
+
+func (c ColorRGB) Datapoint() dpt.DPT_232600 {
+	return dpt.DPT_232600{Red: c.R, Green: c.G, Blue: c.B}
+}
+
+// ColorXyY is the structured value of DPT 242.600, e.g. {"x":20000,"y":30000,"brightness":255,"colorValid":true,"brightnessValid":true}.
+type ColorXyY struct {
+	X               uint16 `json:"x"`
+	Y               uint16 `json:"y"`
+	Brightness      uint8  `json:"brightness"`
+	ColorValid      bool   `json:"colorValid"`
+	BrightnessValid bool   `json:"brightnessValid"`
+}
+
+func NewColorXyY(d dpt.DPT_242600) ColorXyY {
+	return ColorXyY{X: d.X, Y: d.Y, Brightness: d.YBrightness, ColorValid: d.ColorValid, BrightnessValid: d.BrightnessValid}
+}
+
+// ParseColorXyY parses a JSON object. The valid flags default to true when left out.
+func ParseColorXyY(value string) (ColorXyY, error) {
+	c := ColorXyY{ColorValid: true, BrightnessValid: true}
+	if !isJSONObject(value) {
+		return c, fmt.Errorf("expected a JSON object")
+	}
+	err := unmarshalObject(value, &c)
+	return c, err
+}
+
+func (c ColorXyY) Datapoint() dpt.DPT_242600 {
+	return dpt.DPT_242600{X: c.X, Y: c.Y, YBrightness: c.Brightness, ColorValid: c.ColorValid, BrightnessValid: c.BrightnessValid}
+}
+
+// ColorRGBW is the structured value of DPT 251.600, e.g. {"r":255,"g":128,"b":0,"w":0,"redValid":true,...}.
+type ColorRGBW struct {
+	R          uint8 `json:"r"`
+	G          uint8 `json:"g"`
+	B          uint8 `json:"b"`
+	W          uint8 `json:"w"`
+	RedValid   bool  `json:"redValid"`
+	GreenValid bool  `json:"greenValid"`
+	BlueValid  bool  `json:"blueValid"`
+	WhiteValid bool  `json:"whiteValid"`
+}
+
+func NewColorRGBW(d dpt.DPT_251600) ColorRGBW {
+	return ColorRGBW{
+		R: d.Red, G: d.Green, B: d.Blue, W: d.White,
+		RedValid: d.RedValid, GreenValid: d.GreenValid, BlueValid: d.BlueValid, WhiteValid: d.WhiteValid,
+	}
+}
+
+// ParseColorRGBW parses a JSON object. The valid flags default to true when left out.
+func ParseColorRGBW(value string) (ColorRGBW, error) {
+	c := ColorRGBW{RedValid: true, GreenValid: true, BlueValid: true, WhiteValid: true}
+	if !isJSONObject(value) {
+		return c, fmt.Errorf("expected a JSON object")
+	}
+	err := unmarshalObject(value, &c)
+	return c, err
+}
+
+func (c ColorRGBW) Datapoint() dpt.DPT_251600 {
+	return dpt.DPT_251600{
+		Red: c.R, Green: c.G, Blue: c.B, White: c.W,
+		RedValid: c.RedValid, GreenValid: c.GreenValid, BlueValid: c.BlueValid, WhiteValid: c.WhiteValid,
+	}
+}
+
+// TimeOfDay is the structured value of DPT 10.001, e.g. {"weekday":"Monday","hour":14,"minutes":30,"seconds":0}.
+// The weekday is left out for any day.
+type TimeOfDay struct {
+	Weekday string `json:"weekday,omitempty"`
+	Hour    uint8  `json:"hour"`
+	Minutes uint8  `json:"minutes"`
+	Seconds uint8  `json:"seconds"`
+}
+
+func NewTimeOfDay(d dpt.DPT_10001) TimeOfDay {
+	t := TimeOfDay{Hour: d.Hour, Minutes: d.Minutes, Seconds: d.Seconds}
+	if d.Weekday >= 1 && d.Weekday <= 7 {
+		t.Weekday = weekdayNames[d.Weekday-1]
+	}
+	return t
+}
+
+// ParseTimeOfDay parses a JSON object or a time like "Monday 14:30:00" or "14:30:00".
+func ParseTimeOfDay(value string) (TimeOfDay, error) {
+	var t TimeOfDay
+	if isJSONObject(value) {
+		if err := unmarshalObject(value, &t); err != nil {
+			return t, err
+		}
+	} else {
+		matches := regexpTimeOfDay.FindStringSubmatch(strings.TrimSpace(value))
+		if matches == nil {
+			return t, fmt.Errorf("expected a JSON object or a time like \"Monday 14:30:00\"")
+		}
+		hour, _ := strconv.ParseUint(matches[2], 10, 8)
+		minutes, _ := strconv.ParseUint(matches[3], 10, 8)
+		seconds, _ := strconv.ParseUint(matches[4], 10, 8)
+		t = TimeOfDay{Weekday: matches[1], Hour: uint8(hour), Minutes: uint8(minutes), Seconds: uint8(seconds)}
+	}
+
+	if _, err := t.Datapoint(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// Datapoint returns the value as DPT 10.001, failing for unknown weekdays and times out of range.
+func (t TimeOfDay) Datapoint() (dpt.DPT_10001, error) {
+	d := dpt.DPT_10001{Hour: t.Hour, Minutes: t.Minutes, Seconds: t.Seconds}
+	if t.Weekday != "" {
+		for i, name := range weekdayNames {
+			if strings.EqualFold(name, t.Weekday) {
+				d.Weekday = uint8(i + 1)
+			}
+		}
+		if d.Weekday == 0 {
+			return d, fmt.Errorf("unknown weekday \"%s\"", t.Weekday)
+		}
+	}
+	if !d.IsValid() {
+		return d, fmt.Errorf("time %02d:%02d:%02d is out of range", t.Hour, t.Minutes, t.Seconds)
+	}
+	return d, nil
+}
+
+// Date is the structured value of DPT 11.001, e.g. {"year":2024,"month":5,"day":1}.
+type Date struct {
+	Year  uint16 `json:"year"`
+	Month uint8  `json:"month"`
+	Day   uint8  `json:"day"`
+}
+
+func NewDate(d dpt.DPT_11001) Date {
+	return Date{Year: d.Year, Month: d.Month, Day: d.Day}
+}
+
+// ParseDate parses a JSON object or a date like "2024-05-01".
+func ParseDate(value string) (Date, error) {
+	var d Date
+	if isJSONObject(value) {
+		if err := unmarshalObject(value, &d); err != nil {
+			return d, err
+		}
+	} else {
+		matches := regexpDate.FindStringSubmatch(strings.TrimSpace(value))
+		if matches == nil {
+			return d, fmt.Errorf("expected a JSON object or a date like \"2024-05-01\"")
+		}
+		year, _ := strconv.ParseUint(matches[1], 10, 16)
+		month, _ := strconv.ParseUint(matches[2], 10, 8)
+		day, _ := strconv.ParseUint(matches[3], 10, 8)
+		d = Date{Year: uint16(year), Month: uint8(month), Day: uint8(day)}
+	}
+
+	if _, err := d.Datapoint(); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// Datapoint returns the value as DPT 11.001, failing for dates KNX cannot represent.
+func (d Date) Datapoint() (dpt.DPT_11001, error) {
+	datapoint := dpt.DPT_11001{Year: d.Year, Month: d.Month, Day: d.Day}
+	if d.Year < 1990 || d.Year > 2089 {
+		return datapoint, fmt.Errorf("year %d is out of range 1990-2089", d.Year)
+	}
+	if !datapoint.IsValid() {
+		return datapoint, fmt.Errorf("date %04d-%02d-%02d is not valid", d.Year, d.Month, d.Day)
+	}
+	return datapoint, nil
+}
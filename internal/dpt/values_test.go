@@ -0,0 +1,153 @@
+package dpt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/vapourismo/knx-go/knx/dpt"
+)
+
+func TestColorRGB(t *testing.T) {
+	for _, value := range []string{`{"r":255,"g":128,"b":0}`, "#FF8000", "#ff8000"} {
+		c, err := ParseColorRGB(value)
+		if err != nil {
+			t.Fatalf("ParseColorRGB(%q) failed: %v", value, err)
+		}
+		if c.Datapoint() != (dpt.DPT_232600{Red: 255, Green: 128, Blue: 0}) {
+			t.Errorf("ParseColorRGB(%q) = %+v", value, c)
+		}
+	}
+
+	data, _ := json.Marshal(NewColorRGB(dpt.DPT_232600{Red: 12, Green: 34, Blue: 56}))
+	if string(data) != `{"r":12,"g":34,"b":56}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+
+	for _, value := range []string{`{"r":256,"g":0,"b":0}`, `{"red":1}`, "Red: 1 Green: 2 Blue: 3", "#FF80"} {
+		if _, err := ParseColorRGB(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestColorXyY(t *testing.T) {
+	c, err := ParseColorXyY(`{"x":20000,"y":30000,"brightness":200}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dpt.DPT_242600{X: 20000, Y: 30000, YBrightness: 200, ColorValid: true, BrightnessValid: true}
+	if c.Datapoint() != want {
+		t.Errorf("Datapoint() = %+v, want %+v", c.Datapoint(), want)
+	}
+
+	c, err = ParseColorXyY(`{"x":1,"y":2,"brightness":3,"colorValid":true,"brightnessValid":false}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.BrightnessValid || !c.ColorValid {
+		t.Errorf("Valid flags not taken from JSON: %+v", c)
+	}
+
+	data, _ := json.Marshal(NewColorXyY(want))
+	if string(data) != `{"x":20000,"y":30000,"brightness":200,"colorValid":true,"brightnessValid":true}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+
+	if _, err := ParseColorXyY("x: 1 y: 2 Y: 3 ColorValid: true, BrightnessValid: true"); err == nil {
+		t.Error("Expected an error for the text form")
+	}
+}
+
+func TestColorRGBW(t *testing.T) {
+	c, err := ParseColorRGBW(`{"r":1,"g":2,"b":3,"w":4,"whiteValid":false}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dpt.DPT_251600{Red: 1, Green: 2, Blue: 3, White: 4, RedValid: true, GreenValid: true, BlueValid: true}
+	if c.Datapoint() != want {
+		t.Errorf("Datapoint() = %+v, want %+v", c.Datapoint(), want)
+	}
+	if NewColorRGBW(want) != c {
+		t.Errorf("NewColorRGBW(%+v) = %+v, want %+v", want, NewColorRGBW(want), c)
+	}
+
+	if _, err := ParseColorRGBW(`{"r":1,"g":2,"b":3,"w":4,"amber":5}`); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
+
+func TestTimeOfDay(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    dpt.DPT_10001
+		wantErr bool
+	}{
+		{"Monday 14:30:05", dpt.DPT_10001{Weekday: 1, Hour: 14, Minutes: 30, Seconds: 5}, false},
+		{"07:00:00", dpt.DPT_10001{Hour: 7}, false},
+		{`{"weekday":"sunday","hour":23,"minutes":59,"seconds":59}`, dpt.DPT_10001{Weekday: 7, Hour: 23, Minutes: 59, Seconds: 59}, false},
+		{`{"hour":6,"minutes":15}`, dpt.DPT_10001{Hour: 6, Minutes: 15}, false},
+		{`{"weekday":"Someday","hour":6}`, dpt.DPT_10001{}, true},
+		{"25:00:00", dpt.DPT_10001{}, true},
+		{"noon", dpt.DPT_10001{}, true},
+	}
+	for _, tt := range tests {
+		timeOfDay, err := ParseTimeOfDay(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTimeOfDay(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		got, _ := timeOfDay.Datapoint()
+		if got != tt.want {
+			t.Errorf("ParseTimeOfDay(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+		if back, _ := NewTimeOfDay(got).Datapoint(); back != got {
+			t.Errorf("NewTimeOfDay(%+v) does not round-trip, got %+v", got, back)
+		}
+	}
+
+	data, _ := json.Marshal(NewTimeOfDay(dpt.DPT_10001{Weekday: 1, Hour: 14, Minutes: 30}))
+	if string(data) != `{"weekday":"Monday","hour":14,"minutes":30,"seconds":0}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	data, _ = json.Marshal(NewTimeOfDay(dpt.DPT_10001{Hour: 14, Minutes: 30}))
+	if string(data) != `{"hour":14,"minutes":30,"seconds":0}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+}
+
+func TestDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Date
+		wantErr bool
+	}{
+		{"2024-05-01", Date{2024, 5, 1}, false},
+		{`{"year":2024,"month":2,"day":29}`, Date{2024, 2, 29}, false},
+		{`{"year":2023,"month":2,"day":29}`, Date{}, true},
+		{"1989-12-31", Date{}, true},
+		{"01.05.2024", Date{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseDate(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+
+	datapoint := dpt.DPT_11001{Year: 2024, Month: 5, Day: 1}
+	var unpacked dpt.DPT_11001
+	if err := unpacked.Unpack(datapoint.Pack()); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(NewDate(unpacked))
+	if string(data) != `{"year":2024,"month":5,"day":1}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+}
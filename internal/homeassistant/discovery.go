@@ -0,0 +1,247 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	localdpt "github.com/pakerfeldt/knx-mqtt/internal/dpt"
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+const DefaultDiscoveryPrefix = "homeassistant"
+const DefaultNodeID = "knx_mqtt"
+
+var regexpInvalidID = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Message is a discovery message to be published retained.
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// sensorClass describes a numeric datapoint type exposed as a sensor.
+type sensorClass struct {
+	deviceClass string
+	unit        string
+	stateClass  string
+}
+
+var sensorClasses = map[string]sensorClass{
+	"9.001":  {"temperature", "°C", "measurement"},
+	"9.004":  {"illuminance", "lx", "measurement"},
+	"9.005":  {"wind_speed", "m/s", "measurement"},
+	"9.006":  {"pressure", "Pa", "measurement"},
+	"9.007":  {"humidity", "%", "measurement"},
+	"9.008":  {"carbon_dioxide", "ppm", "measurement"},
+	"13.010": {"energy", "Wh", "total_increasing"},
+	"13.013": {"energy", "kWh", "total_increasing"},
+	"14.019": {"current", "A", "measurement"},
+	"14.027": {"voltage", "V", "measurement"},
+	"14.056": {"power", "W", "measurement"},
+}
+
+var binarySensorClasses = map[string]string{
+	"1.009": "opening",
+	"1.019": "window",
+}
+
+// Discovery builds the Home Assistant MQTT discovery messages for all group addresses.
+// State topics point at the topics the bridge publishes to and command topics at the
+// corresponding /write topics. Group addresses without a matching component are skipped.
+func Discovery(cfg models.Config, knxItems *models.KNX) []Message {
+	var valueExpr string
+	switch cfg.OutgoingMqttMessage.Type {
+	case models.JsonType:
+		if !cfg.OutgoingMqttMessage.IncludedJsonFields.IncludeValue {
+			log.Warn().Msg("Home Assistant discovery requires the JSON field 'value' to be included")
+			return nil
+		}
+		valueExpr = "value_json.value"
+	case models.ValueType:
+		valueExpr = "value"
+	default:
+		log.Warn().Str("type", cfg.OutgoingMqttMessage.Type).Msg("Home Assistant discovery requires outgoing MQTT messages of type 'json' or 'value'")
+		return nil
+	}
+	if cfg.OutgoingMqttMessage.EmitValueAsString {
+		log.Warn().Msg("Home Assistant discovery expects typed values, set emitValueAsString to false")
+	}
+
+	discoveryPrefix := prefix(cfg.HomeAssistant)
+	nodeID := cfg.HomeAssistant.NodeID
+	if nodeID == "" {
+		nodeID = DefaultNodeID
+	}
+	device := map[string]interface{}{
+		"identifiers":  []string{nodeID},
+		"name":         "KNX",
+		"manufacturer": "knx-mqtt",
+	}
+
+	messages := make([]Message, 0, len(knxItems.GroupAddresses))
+	skipped := 0
+	for _, ga := range knxItems.GroupAddresses {
+		if ga.Ignore {
+			skipped++
+			continue
+		}
+		component, config := entityConfig(ga, valueExpr)
+		if config == nil {
+			skipped++
+			continue
+		}
+		if component == "sensor" && ga.Unit != "" {
+			config["unit_of_measurement"] = ga.Unit
+		}
+
+		objectID := regexpInvalidID.ReplaceAllString(ga.Address, "_")
+		config["name"] = ga.Name
+		config["unique_id"] = nodeID + "_" + objectID
+		config["device"] = device
+		if _, ok := config["state_topic"]; ok {
+			config["state_topic"] = stateTopic(cfg, ga)
+		}
+		for _, key := range []string{"command_topic", "brightness_command_topic"} {
+			if _, ok := config[key]; ok {
+				config[key] = commandTopic(cfg, ga)
+			}
+		}
+		if _, ok := config["brightness_state_topic"]; ok {
+			config["brightness_state_topic"] = stateTopic(cfg, ga)
+		}
+
+		payload, err := json.Marshal(config)
+		if err != nil {
+			log.Error().Err(err).Str("address", ga.Address).Msg("Failed to create Home Assistant discovery message")
+			continue
+		}
+		messages = append(messages, Message{
+			Topic:   fmt.Sprintf("%s/%s/%s/%s/config", discoveryPrefix, component, nodeID, objectID),
+			Payload: payload,
+		})
+	}
+
+	log.Info().Int("entities", len(messages)).Int("skipped", skipped).Msg("Created Home Assistant discovery messages")
+	return messages
+}
+
+// StatusTopic returns the topic Home Assistant publishes its birth and last will messages to.
+func StatusTopic(cfg models.HomeAssistantConfig) string {
+	return prefix(cfg) + "/status"
+}
+
+func prefix(cfg models.HomeAssistantConfig) string {
+	if cfg.DiscoveryPrefix == "" {
+		return DefaultDiscoveryPrefix
+	}
+	return cfg.DiscoveryPrefix
+}
+
+// entityConfig returns the Home Assistant component and its configuration for a group address.
+// Topics are set to placeholders and filled in by the caller. A nil configuration means the
+// datapoint type has no matching component. Read-only group addresses are exposed as sensors
+// without command topic.
+func entityConfig(ga models.GroupAddress, valueExpr string) (string, map[string]interface{}) {
+	onOff := fmt.Sprintf("{{ 'ON' if (%s|string|lower) == 'true' else 'OFF' }}", valueExpr)
+
+	switch {
+	case ga.Datapoint == "1.001" && !ga.ReadOnly:
+		return "switch", map[string]interface{}{
+			"state_topic":    "",
+			"command_topic":  "",
+			"value_template": onOff,
+			"state_on":       "ON",
+			"state_off":      "OFF",
+			"payload_on":     "true",
+			"payload_off":    "false",
+		}
+	case ga.Datapoint == "1.008" && !ga.ReadOnly:
+		// Up is encoded as false and Down as true. There is no state, only movement commands.
+		return "cover", map[string]interface{}{
+			"command_topic": "",
+			"payload_open":  "false",
+			"payload_close": "true",
+			"payload_stop":  nil,
+			"optimistic":    true,
+		}
+	case strings.HasPrefix(ga.Datapoint, "1."):
+		config := map[string]interface{}{
+			"state_topic":    "",
+			"value_template": onOff,
+		}
+		if deviceClass, ok := binarySensorClasses[ga.Datapoint]; ok {
+			config["device_class"] = deviceClass
+		}
+		return "binary_sensor", config
+	case ga.Datapoint == "5.001" && !ga.ReadOnly:
+		// A dimmer with a single brightness group address.
+		return "light", map[string]interface{}{
+			"state_topic":               "",
+			"state_value_template":      fmt.Sprintf("{{ 'ON' if (%s|float(0)) > 0 else 'OFF' }}", valueExpr),
+			"command_topic":             "",
+			"payload_off":               "0",
+			"on_command_type":           "brightness",
+			"brightness_state_topic":    "",
+			"brightness_command_topic":  "",
+			"brightness_value_template": fmt.Sprintf("{{ %s|float(0)|round|int }}", valueExpr),
+			"brightness_scale":          100,
+		}
+	case localdpt.EnumerationNames(ga.Datapoint) != nil:
+		// Enumerations like the HVAC mode of 20.102 are published and written by name
+		if ga.ReadOnly {
+			return "sensor", map[string]interface{}{
+				"state_topic":    "",
+				"device_class":   "enum",
+				"options":        localdpt.EnumerationNames(ga.Datapoint),
+				"value_template": fmt.Sprintf("{{ %s }}", valueExpr),
+			}
+		}
+		return "select", map[string]interface{}{
+			"state_topic":    "",
+			"command_topic":  "",
+			"options":        localdpt.EnumerationNames(ga.Datapoint),
+			"value_template": fmt.Sprintf("{{ %s }}", valueExpr),
+		}
+	case isNumeric(ga.Datapoint):
+		config := map[string]interface{}{
+			"state_topic":    "",
+			"value_template": fmt.Sprintf("{{ %s }}", valueExpr),
+		}
+		if class, ok := sensorClasses[ga.Datapoint]; ok {
+			config["device_class"] = class.deviceClass
+			config["unit_of_measurement"] = class.unit
+			config["state_class"] = class.stateClass
+		} else if datapoint, ok := localdpt.Produce(ga.Datapoint); ok && datapoint.Unit() != "" {
+			config["unit_of_measurement"] = datapoint.Unit()
+			config["state_class"] = "measurement"
+		}
+		return "sensor", config
+	}
+	return "", nil
+}
+
+// isNumeric reports whether the datapoint type carries a single number.
+func isNumeric(datapoint string) bool {
+	switch strings.SplitN(datapoint, ".", 2)[0] {
+	case "5", "6", "7", "8", "9", "12", "13", "14":
+		return true
+	}
+	return false
+}
+
+// stateTopic returns the topic the bridge publishes values of ga to.
+func stateTopic(cfg models.Config, ga models.GroupAddress) string {
+	if cfg.OutgoingMqttMessage.EmitUsingAddress {
+		return cfg.MQTT.TopicPrefix + ga.Address
+	}
+	return cfg.MQTT.TopicPrefix + ga.FullName
+}
+
+// commandTopic returns the topic on which the bridge accepts writes to ga. The bridge subscribes
+// to the command topics of each group address by its address, whatever its style or name.
+func commandTopic(cfg models.Config, ga models.GroupAddress) string {
+	return cfg.MQTT.TopicPrefix + ga.Address + "/write"
+}
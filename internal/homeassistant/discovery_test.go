@@ -0,0 +1,193 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+)
+
+func testKNX() *models.KNX {
+	knxItems := models.EmptyKNX()
+	for _, ga := range []models.GroupAddress{
+		{Name: "Light", FullName: "Lights/Kitchen/Light", Address: "1/2/3", Datapoint: "1.001"},
+		{Name: "Dimmer", FullName: "Lights/Kitchen/Dimmer", Address: "1/2/4", Datapoint: "5.001"},
+		{Name: "Temperature", FullName: "Climate/Kitchen/Temperature", Address: "2/0/1", Datapoint: "9.001"},
+		{Name: "Blind", FullName: "Blinds/Kitchen/Blind", Address: "3/0/1", Datapoint: "1.008"},
+		{Name: "Mode", FullName: "Climate/Kitchen/Mode", Address: "2/0/2", Datapoint: "20.102"},
+		{Name: "Window", FullName: "Security/Kitchen/Window", Address: "4/0/1", Datapoint: "1.019"},
+		{Name: "Clock", FullName: "Central/Time/Clock", Address: "5/0/1", Datapoint: "10.001"},
+	} {
+		knxItems.AddGroupAddress(ga)
+	}
+	return &knxItems
+}
+
+func testConfig() models.Config {
+	return models.Config{
+		OutgoingMqttMessage: models.OutgoingMqttMessage{
+			Type:               models.JsonType,
+			EmitUsingAddress:   true,
+			IncludedJsonFields: models.IncludedJsonFields{IncludeValue: true},
+		},
+		MQTT:          models.MQTTConfig{TopicPrefix: "knx/"},
+		HomeAssistant: models.HomeAssistantConfig{Discovery: true},
+	}
+}
+
+func discoveryByTopic(t *testing.T, messages []Message) map[string]map[string]interface{} {
+	t.Helper()
+	configs := make(map[string]map[string]interface{})
+	for _, m := range messages {
+		var config map[string]interface{}
+		if err := json.Unmarshal(m.Payload, &config); err != nil {
+			t.Fatalf("invalid payload on %s: %v", m.Topic, err)
+		}
+		configs[m.Topic] = config
+	}
+	return configs
+}
+
+func TestDiscovery(t *testing.T) {
+	configs := discoveryByTopic(t, Discovery(testConfig(), testKNX()))
+
+	tests := []struct {
+		topic  string
+		fields map[string]interface{}
+	}{
+		{"homeassistant/switch/knx_mqtt/1_2_3/config", map[string]interface{}{
+			"name":          "Light",
+			"unique_id":     "knx_mqtt_1_2_3",
+			"state_topic":   "knx/1/2/3",
+			"command_topic": "knx/1/2/3/write",
+			"payload_on":    "true",
+		}},
+		{"homeassistant/light/knx_mqtt/1_2_4/config", map[string]interface{}{
+			"brightness_state_topic":   "knx/1/2/4",
+			"brightness_command_topic": "knx/1/2/4/write",
+			"brightness_scale":         float64(100),
+		}},
+		{"homeassistant/sensor/knx_mqtt/2_0_1/config", map[string]interface{}{
+			"device_class":        "temperature",
+			"unit_of_measurement": "°C",
+			"value_template":      "{{ value_json.value }}",
+		}},
+		{"homeassistant/cover/knx_mqtt/3_0_1/config", map[string]interface{}{
+			"command_topic": "knx/3/0/1/write",
+			"payload_open":  "false",
+			"payload_close": "true",
+			"payload_stop":  nil,
+		}},
+		{"homeassistant/select/knx_mqtt/2_0_2/config", map[string]interface{}{
+			"command_topic":  "knx/2/0/2/write",
+			"value_template": "{{ value_json.value }}",
+		}},
+		{"homeassistant/binary_sensor/knx_mqtt/4_0_1/config", map[string]interface{}{
+			"device_class": "window",
+		}},
+	}
+
+	if len(configs) != len(tests) {
+		t.Errorf("got %d discovery messages, want %d", len(configs), len(tests))
+	}
+	for _, tt := range tests {
+		config, ok := configs[tt.topic]
+		if !ok {
+			t.Errorf("missing discovery message %s", tt.topic)
+			continue
+		}
+		for key, want := range tt.fields {
+			got, ok := config[key]
+			if !ok || got != want {
+				t.Errorf("%s: %s = %v, want %v", tt.topic, key, got, want)
+			}
+		}
+	}
+}
+
+func TestDiscoveryReadOnly(t *testing.T) {
+	knxItems := models.EmptyKNX()
+	for _, ga := range []models.GroupAddress{
+		{Name: "Light", FullName: "Lights/Kitchen/Light", Address: "1/2/3", Datapoint: "1.001", ReadOnly: true},
+		{Name: "Dimmer", FullName: "Lights/Kitchen/Dimmer", Address: "1/2/4", Datapoint: "5.001", ReadOnly: true},
+		{Name: "Blind", FullName: "Blinds/Kitchen/Blind", Address: "3/0/1", Datapoint: "1.008", ReadOnly: true},
+		{Name: "Mode", FullName: "Climate/Kitchen/Mode", Address: "2/0/2", Datapoint: "20.102", ReadOnly: true},
+	} {
+		knxItems.AddGroupAddress(ga)
+	}
+	configs := discoveryByTopic(t, Discovery(testConfig(), &knxItems))
+
+	want := []string{
+		"homeassistant/binary_sensor/knx_mqtt/1_2_3/config",
+		"homeassistant/sensor/knx_mqtt/1_2_4/config",
+		"homeassistant/binary_sensor/knx_mqtt/3_0_1/config",
+		"homeassistant/sensor/knx_mqtt/2_0_2/config",
+	}
+	if len(configs) != len(want) {
+		t.Errorf("got %d discovery messages, want %d: %v", len(configs), len(want), configs)
+	}
+	for _, topic := range want {
+		config, ok := configs[topic]
+		if !ok {
+			t.Errorf("missing discovery message %s", topic)
+			continue
+		}
+		for key := range config {
+			if strings.HasSuffix(key, "command_topic") {
+				t.Errorf("%s: unexpected %s for a read-only group address", topic, key)
+			}
+		}
+	}
+	if mode := configs["homeassistant/sensor/knx_mqtt/2_0_2/config"]; mode["device_class"] != "enum" || mode["state_topic"] != "knx/2/0/2" {
+		t.Errorf("read-only enumeration = %v, want an enum sensor", mode)
+	}
+}
+
+func TestDiscoveryTopics(t *testing.T) {
+	cfg := testConfig()
+	cfg.OutgoingMqttMessage.Type = models.ValueType
+	cfg.OutgoingMqttMessage.EmitUsingAddress = false
+	cfg.OutgoingMqttMessage.EmitUsingName = true
+	cfg.HomeAssistant.DiscoveryPrefix = "ha"
+	cfg.HomeAssistant.NodeID = "house"
+
+	configs := discoveryByTopic(t, Discovery(cfg, testKNX()))
+	config, ok := configs["ha/switch/house/1_2_3/config"]
+	if !ok {
+		t.Fatalf("missing discovery message, got %v", configs)
+	}
+	if config["state_topic"] != "knx/Lights/Kitchen/Light" {
+		t.Errorf("state_topic = %v, want topic using the name", config["state_topic"])
+	}
+	if config["command_topic"] != "knx/1/2/3/write" {
+		t.Errorf("command_topic = %v, want topic using the address", config["command_topic"])
+	}
+	if config["value_template"] != "{{ 'ON' if (value|string|lower) == 'true' else 'OFF' }}" {
+		t.Errorf("unexpected value_template %v", config["value_template"])
+	}
+
+	knxItems := models.EmptyKNX()
+	knxItems.AddGroupAddress(models.GroupAddress{Name: "Light", FullName: "House/Ground floor/Kitchen/Light", Address: "1/515", Datapoint: "1.001"})
+	config = discoveryByTopic(t, Discovery(cfg, &knxItems))["ha/switch/house/1_515/config"]
+	if config["command_topic"] != "knx/1/515/write" {
+		t.Errorf("command_topic = %v, want topic using the 2-level address", config["command_topic"])
+	}
+	if StatusTopic(cfg.HomeAssistant) != "ha/status" {
+		t.Errorf("StatusTopic() = %s, want ha/status", StatusTopic(cfg.HomeAssistant))
+	}
+}
+
+func TestDiscoveryRequiresValue(t *testing.T) {
+	cfg := testConfig()
+	cfg.OutgoingMqttMessage.Type = models.BytesType
+	if messages := Discovery(cfg, testKNX()); len(messages) != 0 {
+		t.Errorf("expected no discovery for raw bytes, got %d messages", len(messages))
+	}
+
+	cfg = testConfig()
+	cfg.OutgoingMqttMessage.IncludedJsonFields.IncludeValue = false
+	if messages := Discovery(cfg, testKNX()); len(messages) != 0 {
+		t.Errorf("expected no discovery without JSON value, got %d messages", len(messages))
+	}
+}
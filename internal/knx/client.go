@@ -2,12 +2,18 @@ package knx
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/pakerfeldt/knx-mqtt/internal/metrics"
 	"github.com/pakerfeldt/knx-mqtt/internal/models"
 	"github.com/pakerfeldt/knx-mqtt/internal/msg"
+	"github.com/pakerfeldt/knx-mqtt/internal/secure"
 	"github.com/pakerfeldt/knx-mqtt/internal/utils"
 	"github.com/rs/zerolog/log"
 	knxgo "github.com/vapourismo/knx-go/knx"
@@ -16,129 +22,325 @@ import (
 	localdpt "github.com/pakerfeldt/knx-mqtt/internal/dpt"
 )
 
+// reconnectInterval is the delay between attempts to re-establish a lost KNX connection.
+const reconnectInterval = 5 * time.Second
+
 type KNXClient struct {
-	ctx       context.Context
-	cancel    context.CancelFunc
-	cfg       *models.Config
-	knxItems  *models.KNX
-	tunnel    *knxgo.GroupTunnel
-	router    *knxgo.GroupRouter
-	knxLogger *KNXLogger
+	ctx               context.Context
+	cancel            context.CancelFunc
+	cfg               *models.Config
+	knxItems          atomic.Pointer[models.KNX]
+	dial              Dialer
+	reconnectInterval time.Duration
+	mu                sync.RWMutex
+	transport         Transport
+	dataSecure        *secure.DataSecure
+	virtual           *virtualGroupAddresses
+	poller            *poller
+	queue             *sendQueue
+	statusHandler     func(models.KNXStatus)
+	knxLogger         *KNXLogger
+}
+
+func NewClient(ctx context.Context, config models.Config, knxItems *models.KNX, keyring *secure.Keyring, logger *KNXLogger) (*KNXClient, error) {
+	dial, err := NewDialer(config.KNX, keyring)
+	if err != nil {
+		return nil, err
+	}
+	client := newClient(ctx, config, knxItems, logger, dial)
+	if keyring != nil && len(keyring.GroupKeys) > 0 {
+		client.dataSecure, err = newDataSecure(config.KNX, keyring)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
 }
 
-func NewClient(ctx context.Context, config models.Config, knxItems *models.KNX, logger *KNXLogger) *KNXClient {
+// newDataSecure sets up KNX Data Secure for the group keys in keyring. Telegrams are sent
+// from the configured individual address or, in tunnel mode, from the keyring's tunnel.
+func newDataSecure(cfg models.KNXConfig, keyring *secure.Keyring) (*secure.DataSecure, error) {
+	source := cfg.Secure.IndividualAddress
+	if source == "" && cfg.TunnelMode {
+		if iface, err := keyring.Tunnel(cfg.Secure.TunnelAddress); err == nil {
+			source = iface.IndividualAddress
+		}
+	}
+	if source == "" {
+		log.Warn().Msg("No individual address configured, Data Secure group addresses can only be received")
+	}
+	if cfg.Secure.StateFile == "" {
+		log.Warn().Msg("No Data Secure state file configured, replay protection is reset on restart")
+	}
+	dataSecure, err := secure.NewDataSecure(keyring, source, cfg.Secure.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Int("groupAddresses", len(keyring.GroupKeys)).Str("source", source).Msg("KNX Data Secure enabled")
+	return dataSecure, nil
+}
+
+func newClient(ctx context.Context, config models.Config, knxItems *models.KNX, logger *KNXLogger, dial Dialer) *KNXClient {
 	childCtx, cancel := context.WithCancel(ctx)
 	client := KNXClient{
-		ctx:       childCtx,
-		cancel:    cancel,
-		cfg:       &config,
-		knxItems:  knxItems,
-		knxLogger: logger,
+		ctx:               childCtx,
+		cancel:            cancel,
+		cfg:               &config,
+		dial:              dial,
+		reconnectInterval: reconnectInterval,
+		virtual:           newVirtualGroupAddresses(knxItems, config.VirtualGroupAddresses),
+		poller:            newPoller(config.ReadSchedule, knxItems),
+		queue:             newSendQueue(config.KNX.SendQueue, knxItems),
+		knxLogger:         logger,
 	}
+	client.knxItems.Store(knxItems)
 	return &client
 }
 
+// items returns the group addresses currently in use.
+func (c *KNXClient) items() *models.KNX {
+	return c.knxItems.Load()
+}
+
+// SetKNX replaces the group addresses, e.g. after the ETS export was reloaded, without
+// dropping the connection.
+func (c *KNXClient) SetKNX(knxItems *models.KNX) {
+	c.knxItems.Store(knxItems)
+	c.poller.setKNX(knxItems)
+	c.queue.setKNX(knxItems)
+	c.knxLogger.SetKNX(knxItems)
+}
+
 func (c *KNXClient) Connect(callback func(*msg.KNXMessage)) *error {
 	err := c.connect()
 	if err != nil {
 		return err
 	}
 	c.subscribe(callback)
+	go c.queue.run(c.ctx, c.send)
+	if c.dataSecure != nil {
+		go c.dataSecure.Run(c.ctx, c.cfg.KNX.Secure.PersistInterval)
+	}
+	if len(c.poller.rules) > 0 {
+		go c.poller.run(c.ctx, c.queueRead)
+	}
 	return nil
 }
 
+// connect dials a new transport and replaces the current one, closing it if present.
 func (c *KNXClient) connect() *error {
-	if c.cfg.KNX.TunnelMode {
-		tunnel, err := knxgo.NewGroupTunnel(c.cfg.KNX.Endpoint, knxgo.DefaultTunnelConfig)
-		if err != nil {
-			return &err
-		}
-		c.tunnel = &tunnel
-	} else {
-		router, err := knxgo.NewGroupRouter(c.cfg.KNX.Endpoint, knxgo.DefaultRouterConfig)
-		if err != nil {
-			return &err
-		}
-		c.router = &router
+	transport, err := c.dial()
+	if err != nil {
+		return &err
+	}
+
+	c.mu.Lock()
+	previous := c.transport
+	c.transport = transport
+	c.mu.Unlock()
+
+	if previous != nil {
+		previous.Close()
 	}
 	return nil
 }
 
-func (c *KNXClient) newMessage(event knxgo.GroupEvent) *msg.KNXMessage {
+func (c *KNXClient) currentTransport() Transport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.transport
+}
+
+// NewMessage resolves the group address and datapoint of event and the device that sent it.
+func (c *KNXClient) NewMessage(event knxgo.GroupEvent) *msg.KNXMessage {
+	knxItems := c.items()
+	message := resolveMessage(knxItems, event)
+	if device, exists := knxItems.GetDevice(event.Source.String()); exists {
+		message.SetSourceName(device.Name)
+	}
+	return message
+}
+
+// resolveMessage resolves the group address and datapoint of event.
+func resolveMessage(knxItems *models.KNX, event knxgo.GroupEvent) *msg.KNXMessage {
 	destination := event.Destination.String()
 	flatDestination, err := models.ParseGroupAddress(destination)
 	if err != nil {
 		log.Error().Err(err).Str("address", destination).Msg("Failed to parse KNX group address")
 		return msg.NewKNX(event, nil, nil)
 	}
-	index, exists := c.knxItems.GadToIndex[flatDestination]
+	index, exists := knxItems.GadToIndex[flatDestination]
 	if !exists {
 		return msg.NewKNX(event, nil, nil)
 	}
-	groupAddress := c.knxItems.GroupAddresses[index]
+	groupAddress := knxItems.GroupAddresses[index]
 	datapoint, ok := localdpt.Produce(groupAddress.Datapoint)
 	if !ok {
 		log.Error().Msgf("Failed to create datapoint %s, payload: %x", groupAddress.Datapoint, event.Data)
+		metrics.DecodeFailed(groupAddress.Address, groupAddress.Datapoint)
 		return msg.NewKNX(event, nil, nil)
 	}
 	err = datapoint.Unpack(event.Data)
 	if err != nil {
 		log.Error().Err(err).Str("group address", groupAddress.Address).Hex("payload", event.Data).Msg("Failed to unpack KNX payload")
+		metrics.DecodeFailed(groupAddress.Address, groupAddress.Datapoint)
 	}
 	return msg.NewKNX(event, &datapoint, &groupAddress)
 }
 
 func (c *KNXClient) subscribe(callback func(*msg.KNXMessage)) {
 	go func() {
-	Listening:
 		for {
-			log.Info().Msg("Subscribed to KNX")
-
-		ReadEvent:
-			for {
-				select {
-				case <-c.ctx.Done():
-					log.Info().Msg("Stopping KNX subscription...")
-					return
-				case event, ok := <-c.tunnel.Inbound():
-					if !ok {
-						break ReadEvent
-					}
-
-					message := c.newMessage(event)
-
-					// Log incoming message if logger is enabled
-					if c.knxLogger != nil {
-						if err := c.knxLogger.LogIncoming(message); err != nil {
-							log.Error().Err(err).Msg("Failed to log incoming KNX message")
-						}
-					}
-
-					callback(message)
-				}
+			transport := c.currentTransport()
+			if transport == nil {
+				return
+			}
+			log.Info().Str("transport", TransportName(c.cfg.KNX)).Msg("Subscribed to KNX")
+			c.setStatus(models.KNXConnected, nil)
+			c.queue.resume()
+			c.poller.connected()
+			c.listen(transport.Inbound(), callback)
+
+			select {
+			case <-c.ctx.Done():
+				log.Info().Msg("Stopping KNX subscription...")
+				return
+			default:
 			}
 
 			log.Error().Msg("Lost connection to KNX, trying to reconnect ...")
-			for {
-				select {
-				case <-c.ctx.Done():
-					log.Info().Msg("Stopping KNX reconnection...")
-					return
-				default:
-					err := c.connect()
-					if err == nil {
-						continue Listening
-					}
-					log.Error().Err(*err).Msg("Failed to connect to KNX, retrying in 5s...")
-					time.Sleep(5 * time.Second)
-				}
+			c.setStatus(models.KNXReconnecting, nil)
+			c.queue.pause()
+			if !c.reconnect() {
+				log.Info().Msg("Stopping KNX reconnection...")
+				return
 			}
 		}
 	}()
 }
 
+// listen delivers events from inbound to callback until the channel is closed or the client is stopped.
+func (c *KNXClient) listen(inbound <-chan knxgo.GroupEvent, callback func(*msg.KNXMessage)) {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case event, ok := <-inbound:
+			if !ok {
+				return
+			}
+
+			event, ok = c.unsecure(event)
+			if !ok {
+				continue
+			}
+			metrics.TelegramReceived(utils.KNXCommandToString(event.Command), event.Destination.String())
+			message := c.NewMessage(event)
+
+			// Log incoming message if logger is enabled
+			if c.knxLogger != nil {
+				if err := c.knxLogger.LogIncoming(message); err != nil {
+					log.Error().Err(err).Msg("Failed to log incoming KNX message")
+				}
+			}
+
+			c.respondVirtual(event)
+			callback(message)
+		}
+	}
+}
+
+// unsecure decrypts KNX Data Secure telegrams. It returns false for telegrams that
+// must be discarded, i.e. secure telegrams failing authentication or replay protection
+// and plain telegrams to group addresses that are secured.
+func (c *KNXClient) unsecure(event knxgo.GroupEvent) (knxgo.GroupEvent, bool) {
+	if secure.IsSecureEvent(event) {
+		if c.dataSecure == nil {
+			log.Debug().Str("address", event.Destination.String()).Msg("Discarding KNX Data Secure telegram, no keyring configured")
+			return event, false
+		}
+		plain, err := c.dataSecure.Decrypt(event)
+		if err != nil {
+			log.Warn().Err(err).Str("address", event.Destination.String()).Str("source", event.Source.String()).Msg("Discarding KNX Data Secure telegram")
+			return event, false
+		}
+		return plain, true
+	}
+	if c.dataSecure != nil && c.dataSecure.IsSecure(event.Destination) {
+		log.Warn().Str("address", event.Destination.String()).Str("source", event.Source.String()).Msg("Discarding unsecured telegram to Data Secure group address")
+		return event, false
+	}
+	return event, true
+}
+
+// OnStatus registers handler to be called whenever the state of the connection to KNX changes.
+// It must be called before Connect.
+func (c *KNXClient) OnStatus(handler func(models.KNXStatus)) {
+	c.statusHandler = handler
+}
+
+func (c *KNXClient) setStatus(state string, err error) {
+	metrics.SetConnected("knx", state == models.KNXConnected)
+	if c.statusHandler == nil {
+		return
+	}
+	status := models.KNXStatus{State: state, Endpoint: c.cfg.KNX.Endpoint}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	c.statusHandler(status)
+}
+
+// reconnect retries connecting until it succeeds or the client is stopped.
+// It returns false if the client was stopped before a connection could be made.
+func (c *KNXClient) reconnect() bool {
+	for {
+		err := c.connect()
+		if err == nil {
+			metrics.Reconnected("knx")
+			return true
+		}
+		log.Error().Err(*err).Msgf("Failed to connect to KNX, retrying in %s...", c.reconnectInterval)
+		c.setStatus(models.KNXFailed, *err)
+		select {
+		case <-c.ctx.Done():
+			return false
+		case <-time.After(c.reconnectInterval):
+		}
+	}
+}
+
+// packPayload packs a value written from MQTT, given as string or as JSON object with the value and its unit.
+// A unit must be the one of the group address, except "raw" for the raw bus value of scaled datapoint types.
+func packPayload(groupAddress models.GroupAddress, payload []byte) ([]byte, error) {
+	value, unit, err := utils.ParseCommandPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	if unit == utils.RawUnit {
+		return utils.PackRaw(groupAddress.Datapoint, value)
+	}
+	if unit != "" {
+		expected := groupAddress.Unit
+		if expected == "" {
+			if datapoint, ok := localdpt.Produce(groupAddress.Datapoint); ok {
+				expected = datapoint.Unit()
+			}
+		}
+		if !strings.EqualFold(strings.TrimSpace(unit), expected) {
+			return nil, fmt.Errorf("unit \"%s\" does not match \"%s\" of %s", unit, expected, groupAddress.Datapoint)
+		}
+	}
+
+	value, err = groupAddress.Transform.Reverse(value)
+	if err != nil {
+		return nil, err
+	}
+	return utils.PackString(groupAddress.Datapoint, value)
+}
+
 func (c *KNXClient) createWriteEvent(payload []byte, address string, writeRawBinary bool, isResponse bool) *knxgo.GroupEvent {
-	groupAddress, exists := c.knxItems.GetGroupAddress(address)
+	groupAddress, exists := c.items().GetGroupAddress(address)
 	isRegularAddress := utils.IsRegularGroupAddress(address)
 
 	if !isRegularAddress && !exists {
@@ -167,9 +369,10 @@ func (c *KNXClient) createWriteEvent(payload []byte, address string, writeRawBin
 	if writeRawBinary {
 		packedBytes = payload
 	} else if exists {
-		packedBytes, err = utils.PackString(groupAddress.Datapoint, string(payload))
+		packedBytes, err = packPayload(*groupAddress, payload)
 		if err != nil {
-			log.Error().Err(err).Msg("Cannot pack payload")
+			log.Error().Err(err).Str("address", groupAddress.Address).Msg("Cannot pack payload")
+			metrics.PackFailed(groupAddress.Address, groupAddress.Datapoint)
 			return nil
 		}
 	} else {
@@ -195,7 +398,7 @@ func (c *KNXClient) createWriteEvent(payload []byte, address string, writeRawBin
 }
 
 func (c *KNXClient) createReadEvent(address string) *knxgo.GroupEvent {
-	groupAddress, exists := c.knxItems.GetGroupAddress(address)
+	groupAddress, exists := c.items().GetGroupAddress(address)
 	isRegularAddress := utils.IsRegularGroupAddress(address)
 
 	if !isRegularAddress && !exists {
@@ -226,10 +429,33 @@ func (c *KNXClient) createReadEvent(address string) *knxgo.GroupEvent {
 	}
 }
 
-func (c *KNXClient) Send(message msg.MQTTMessage) {
-	address := strings.TrimPrefix(message.Topic()[:strings.LastIndex(message.Topic(), "/")], c.cfg.MQTT.TopicPrefix)
-	command := message.Topic()[strings.LastIndex(message.Topic(), "/")+1:]
+// queueRead queues a GroupValue_Read to address with low priority.
+func (c *KNXClient) queueRead(address string) error {
+	event := c.createReadEvent(address)
+	if event == nil {
+		return fmt.Errorf("failed to create KNX read event")
+	}
+	log.Debug().Str("protocol", "knx").Str("address", address).Msg("Outgoing scheduled read")
+	c.queue.push(*event, models.PriorityLow)
+	return nil
+}
 
+// enqueue queues event with the priority configured for its destination.
+func (c *KNXClient) enqueue(event knxgo.GroupEvent) {
+	c.queue.push(event, c.queue.priority(event.Destination))
+}
+
+// QueueStats returns a snapshot of the queue of telegrams waiting to be sent.
+func (c *KNXClient) QueueStats() QueueStats {
+	return c.queue.stats()
+}
+
+func (c *KNXClient) Send(message msg.MQTTMessage) {
+	address, command := message.AddressAndCommand(c.cfg.MQTT.TopicPrefix)
+	if c.items().IsAmbiguous(address) {
+		log.Warn().Str("name", address).Msg("Name is shared by several group addresses, use the address instead")
+		return
+	}
 	var event *knxgo.GroupEvent
 	if command == "write" || command == "write-bytes" || command == "response" || command == "response-bytes" {
 		writeBytes := command == "write-bytes" || command == "response-bytes"
@@ -255,14 +481,30 @@ func (c *KNXClient) Send(message msg.MQTTMessage) {
 		return
 	}
 
-	err := c.send(*event)
-	if err != nil {
-		log.Error().Str("error", fmt.Sprintf("%s", err)).Msgf("Error writing to KNX")
+	if !c.permitted(*event) {
+		return
 	}
+	c.enqueue(*event)
 }
 
-func (c *KNXClient) Router() *knxgo.GroupRouter {
-	return c.router
+// permitted reports whether event may be sent to its destination, whatever form of the address or
+// name it was sent to. Ignored group addresses are never sent to and read-only ones are only read.
+func (c *KNXClient) permitted(event knxgo.GroupEvent) bool {
+	knxItems := c.items()
+	index, exists := knxItems.GadToIndex[models.FlatGroupAddress(event.Destination)]
+	if !exists {
+		return true
+	}
+	groupAddress := knxItems.GroupAddresses[index]
+	if groupAddress.Ignore {
+		log.Warn().Str("address", groupAddress.Address).Msg("Not sending to ignored group address")
+		return false
+	}
+	if groupAddress.ReadOnly && event.Command != knxgo.GroupRead {
+		log.Warn().Str("address", groupAddress.Address).Str("command", utils.KNXCommandToString(event.Command)).Msg("Group address is not writable")
+		return false
+	}
+	return true
 }
 
 func (c *KNXClient) send(event knxgo.GroupEvent) error {
@@ -273,21 +515,47 @@ func (c *KNXClient) send(event knxgo.GroupEvent) error {
 		}
 	}
 
-	if c.tunnel != nil {
-		return c.tunnel.Send(event)
+	command := utils.KNXCommandToString(event.Command)
+	if c.dataSecure != nil && c.dataSecure.IsSecure(event.Destination) {
+		var err error
+		event, err = c.dataSecure.Encrypt(event)
+		if err != nil {
+			return fmt.Errorf("failed to secure telegram: %w", err)
+		}
 	}
-	if c.router != nil {
-		return c.router.Send(event)
+
+	transport := c.currentTransport()
+	if transport == nil {
+		return errNotConnected
+	}
+	if err := transport.Send(event); err != nil {
+		if connectionLost(err) {
+			return fmt.Errorf("%w: %w", errNotConnected, err)
+		}
+		return err
 	}
-	return fmt.Errorf("no valid KNX client initialized")
+	metrics.TelegramSent(command, event.Destination.String())
+	return nil
 }
 
-func (c *KNXClient) Inbound() <-chan knxgo.GroupEvent {
-	if c.tunnel != nil {
-		return c.tunnel.Inbound()
+// connectionLost reports whether err of sending through a transport means that the connection is
+// closed or down, as opposed to a telegram that failed to encode or was rejected by the gateway.
+func connectionLost(err error) bool {
+	var netErr net.Error
+	if errors.Is(err, net.ErrClosed) || errors.As(err, &netErr) {
+		return true
+	}
+	// knx-go does not export the errors of a tunnel whose gateway stopped responding
+	switch err.Error() {
+	case "response timeout reached", "connection server has terminated":
+		return true
 	}
-	if c.router != nil {
-		return c.router.Inbound()
+	return false
+}
+
+func (c *KNXClient) Inbound() <-chan knxgo.GroupEvent {
+	if transport := c.currentTransport(); transport != nil {
+		return transport.Inbound()
 	}
 
 	// Should never happen, but just return closed channel
@@ -297,11 +565,21 @@ func (c *KNXClient) Inbound() <-chan knxgo.GroupEvent {
 }
 
 func (c *KNXClient) Close() {
-	if c.tunnel != nil {
-		c.tunnel.Close()
+	c.cancel()
+
+	c.mu.Lock()
+	transport := c.transport
+	c.transport = nil
+	c.mu.Unlock()
+
+	if transport != nil {
+		transport.Close()
 	}
-	if c.router != nil {
-		c.router.Close()
+
+	if c.dataSecure != nil {
+		if err := c.dataSecure.Save(); err != nil {
+			log.Error().Err(err).Msg("Failed to write Data Secure state")
+		}
 	}
 
 	// Close logger if initialized
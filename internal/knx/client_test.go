@@ -0,0 +1,687 @@
+package knx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	localdpt "github.com/pakerfeldt/knx-mqtt/internal/dpt"
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/pakerfeldt/knx-mqtt/internal/msg"
+	"github.com/pakerfeldt/knx-mqtt/internal/secure"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// fakeTransport is an in-memory Transport used to drive KNXClient in tests.
+type fakeTransport struct {
+	mu      sync.Mutex
+	inbound chan knxgo.GroupEvent
+	sent    []knxgo.GroupEvent
+	closed  bool
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{inbound: make(chan knxgo.GroupEvent)}
+}
+
+func (f *fakeTransport) Send(event knxgo.GroupEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return net.ErrClosed
+	}
+	f.sent = append(f.sent, event)
+	return nil
+}
+
+func (f *fakeTransport) Inbound() <-chan knxgo.GroupEvent {
+	return f.inbound
+}
+
+func (f *fakeTransport) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+}
+
+// drop simulates a lost connection by closing the inbound channel.
+func (f *fakeTransport) drop() {
+	close(f.inbound)
+}
+
+func (f *fakeTransport) sentEvents() []knxgo.GroupEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]knxgo.GroupEvent(nil), f.sent...)
+}
+
+func (f *fakeTransport) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// fakeDialer hands out the given transports in order and fails once they are exhausted.
+type fakeDialer struct {
+	mu         sync.Mutex
+	transports []*fakeTransport
+	dials      int
+}
+
+func (d *fakeDialer) dial() (Transport, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dials >= len(d.transports) {
+		d.dials++
+		return nil, errors.New("no transport available")
+	}
+	t := d.transports[d.dials]
+	d.dials++
+	return t, nil
+}
+
+func (d *fakeDialer) dialCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dials
+}
+
+func newTestClient(t *testing.T, dialer *fakeDialer) *KNXClient {
+	t.Helper()
+	knxItems := models.EmptyKNX()
+	knxItems.AddGroupAddress(models.GroupAddress{
+		Name:        "Light",
+		FullName:    "Main/Middle/Light",
+		Address:     "1/2/3",
+		FlatAddress: models.FlatGroupAddress(1<<11 | 2<<8 | 3),
+		Datapoint:   "1.001",
+	})
+	config := models.Config{MQTT: models.MQTTConfig{TopicPrefix: "knx/"}}
+	client := newClient(context.Background(), config, &knxItems, nil, dialer.dial)
+	client.reconnectInterval = 10 * time.Millisecond
+	t.Cleanup(client.Close)
+	return client
+}
+
+func waitForMessage(t *testing.T, messages <-chan *msg.KNXMessage) *msg.KNXMessage {
+	t.Helper()
+	select {
+	case m := <-messages:
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for KNX message")
+		return nil
+	}
+}
+
+func TestKNXClientDeliversInboundEvents(t *testing.T) {
+	for _, tunnelMode := range []bool{true, false} {
+		conn := &fakeMessageConn{inbound: make(chan cemi.Message)}
+		stubDialers(t, conn)
+		dial, err := NewDialer(models.KNXConfig{Endpoint: "224.0.23.12:3671", TunnelMode: tunnelMode}, nil)
+		if err != nil {
+			t.Fatalf("NewDialer() error = %v", err)
+		}
+		client := newTestClient(t, &fakeDialer{})
+		client.dial = dial
+
+		messages := make(chan *msg.KNXMessage, 1)
+		if err := client.Connect(func(m *msg.KNXMessage) { messages <- m }); err != nil {
+			t.Fatalf("Connect() error = %v", *err)
+		}
+
+		conn.inbound <- groupInd(cemi.GroupValueWrite, []byte{1})
+
+		m := waitForMessage(t, messages)
+		if !m.IsResolved() {
+			t.Fatalf("tunnelMode=%v: expected message to be resolved", tunnelMode)
+		}
+		if m.Name() != "Light" {
+			t.Errorf("tunnelMode=%v: Name() = %q, want %q", tunnelMode, m.Name(), "Light")
+		}
+		if m.String() != "On" {
+			t.Errorf("tunnelMode=%v: String() = %q, want %q", tunnelMode, m.String(), "On")
+		}
+
+		// Tunnels send L_Data.req, routers L_Data.ind.
+		client.Send(*msg.NewMQTT(fakeMQTTMessage{topic: "knx/1/2/3/write", payload: []byte("false")}))
+		var sent []cemi.Message
+		for deadline := time.Now().Add(time.Second); len(sent) == 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+			sent = conn.sentMessages()
+		}
+		if len(sent) != 1 {
+			t.Fatalf("tunnelMode=%v: got %d sent messages, want 1", tunnelMode, len(sent))
+		}
+		switch sent[0].(type) {
+		case *cemi.LDataReq:
+			if !tunnelMode {
+				t.Errorf("router sent L_Data.req, want L_Data.ind")
+			}
+		case *cemi.LDataInd:
+			if tunnelMode {
+				t.Errorf("tunnel sent L_Data.ind, want L_Data.req")
+			}
+		default:
+			t.Errorf("tunnelMode=%v: sent %T", tunnelMode, sent[0])
+		}
+	}
+}
+
+func TestKNXClientReconnectsAfterLostConnection(t *testing.T) {
+	first := newFakeTransport()
+	second := newFakeTransport()
+	dialer := &fakeDialer{transports: []*fakeTransport{first, second}}
+	client := newTestClient(t, dialer)
+
+	messages := make(chan *msg.KNXMessage, 1)
+	if err := client.Connect(func(m *msg.KNXMessage) { messages <- m }); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+
+	first.drop()
+
+	second.inbound <- knxgo.GroupEvent{
+		Command:     knxgo.GroupWrite,
+		Destination: cemi.NewGroupAddr3(1, 2, 3),
+		Data:        []byte{0},
+	}
+	m := waitForMessage(t, messages)
+	if m.String() != "Off" {
+		t.Errorf("String() = %q, want %q", m.String(), "Off")
+	}
+	if !first.isClosed() {
+		t.Error("expected the lost transport to be closed")
+	}
+	if dialer.dialCount() != 2 {
+		t.Errorf("dial count = %d, want 2", dialer.dialCount())
+	}
+}
+
+func TestKNXClientRetriesFailedReconnects(t *testing.T) {
+	first := newFakeTransport()
+	dialer := &fakeDialer{transports: []*fakeTransport{first}}
+	client := newTestClient(t, dialer)
+
+	if err := client.Connect(func(m *msg.KNXMessage) {}); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+	first.drop()
+
+	deadline := time.Now().Add(time.Second)
+	for dialer.dialCount() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("dial count = %d, expected repeated reconnect attempts", dialer.dialCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestKNXClientSendUsesCurrentTransport(t *testing.T) {
+	first := newFakeTransport()
+	second := newFakeTransport()
+	dialer := &fakeDialer{transports: []*fakeTransport{first, second}}
+	client := newTestClient(t, dialer)
+
+	if err := client.Connect(func(m *msg.KNXMessage) {}); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+
+	event := client.createWriteEvent([]byte("true"), "1/2/3", false, false)
+	if event == nil {
+		t.Fatal("createWriteEvent() returned nil")
+	}
+	if err := client.send(*event); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if got := len(first.sentEvents()); got != 1 {
+		t.Fatalf("first transport sent %d events, want 1", got)
+	}
+
+	first.drop()
+	deadline := time.Now().Add(time.Second)
+	for !first.isClosed() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for reconnect")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := client.send(*event); err != nil {
+		t.Fatalf("send() after reconnect error = %v", err)
+	}
+	if got := len(second.sentEvents()); got != 1 {
+		t.Errorf("second transport sent %d events, want 1", got)
+	}
+}
+
+func TestKNXClientSendWithoutTransport(t *testing.T) {
+	client := newTestClient(t, &fakeDialer{})
+	if err := client.send(knxgo.GroupEvent{}); err == nil {
+		t.Error("expected send() without transport to fail")
+	}
+}
+
+// newDataSecureTestClient returns a connected client for which 1/2/3 is a Data Secure group address.
+func newDataSecureTestClient(t *testing.T, transport *fakeTransport, messages chan *msg.KNXMessage) (*KNXClient, *secure.DataSecure) {
+	t.Helper()
+	keyring := &secure.Keyring{
+		Devices: map[string]secure.Device{},
+		GroupKeys: map[models.FlatGroupAddress][]byte{
+			models.FlatGroupAddress(cemi.NewGroupAddr3(1, 2, 3)): bytes.Repeat([]byte{0x5a}, 16),
+		},
+	}
+	client := newTestClient(t, &fakeDialer{transports: []*fakeTransport{transport}})
+	var err error
+	client.dataSecure, err = secure.NewDataSecure(keyring, "1.1.250", "")
+	if err != nil {
+		t.Fatalf("NewDataSecure() error = %v", err)
+	}
+	if err := client.Connect(func(m *msg.KNXMessage) { messages <- m }); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+
+	// A device on the bus sharing the group key.
+	device, err := secure.NewDataSecure(keyring, "1.1.5", "")
+	if err != nil {
+		t.Fatalf("NewDataSecure() error = %v", err)
+	}
+	return client, device
+}
+
+func TestKNXClientDecryptsDataSecureTelegrams(t *testing.T) {
+	transport := newFakeTransport()
+	messages := make(chan *msg.KNXMessage, 1)
+	_, device := newDataSecureTestClient(t, transport, messages)
+
+	plain := knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: cemi.NewGroupAddr3(1, 2, 3), Data: []byte{1}}
+	encrypted, err := device.Encrypt(plain)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	// Unsecured telegrams and replays to a secure group address are discarded.
+	transport.inbound <- plain
+	transport.inbound <- encrypted
+	transport.inbound <- encrypted
+
+	m := waitForMessage(t, messages)
+	if m.String() != "On" || m.Source() != "1.1.5" {
+		t.Errorf("got %q from %s, want On from 1.1.5", m.String(), m.Source())
+	}
+	select {
+	case m := <-messages:
+		t.Errorf("unexpected message %q", m.String())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestKNXClientEncryptsDataSecureTelegrams(t *testing.T) {
+	transport := newFakeTransport()
+	client, _ := newDataSecureTestClient(t, transport, make(chan *msg.KNXMessage, 1))
+
+	event := client.createWriteEvent([]byte("true"), "1/2/3", false, false)
+	if event == nil {
+		t.Fatal("createWriteEvent() returned nil")
+	}
+	if err := client.send(*event); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	sent := transport.sentEvents()
+	if len(sent) != 1 {
+		t.Fatalf("transport sent %d events, want 1", len(sent))
+	}
+	if !secure.IsSecureEvent(sent[0]) {
+		t.Fatalf("expected a secure telegram, got %+v", sent[0])
+	}
+	if sent[0].Source != cemi.NewIndividualAddr3(1, 1, 250) {
+		t.Errorf("Source = %s, want 1.1.250", sent[0].Source)
+	}
+}
+
+func TestKNXClientVirtualGroupAddress(t *testing.T) {
+	transport := newFakeTransport()
+	client := newTestClient(t, &fakeDialer{transports: []*fakeTransport{transport}})
+	client.virtual = newVirtualGroupAddresses(client.items(), []models.VirtualGroupAddress{{Address: "1/2/3", Topic: "sensor/light"}})
+
+	messages := make(chan *msg.KNXMessage, 1)
+	if err := client.Connect(func(m *msg.KNXMessage) { messages <- m }); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+
+	// Reads are not answered before a value has been received.
+	read := knxgo.GroupEvent{Command: knxgo.GroupRead, Destination: cemi.NewGroupAddr3(1, 2, 3)}
+	transport.inbound <- read
+	waitForMessage(t, messages)
+	if sent := transport.sentEvents(); len(sent) != 0 {
+		t.Fatalf("unexpected events sent %+v", sent)
+	}
+
+	// Only changes are written.
+	client.UpdateVirtual("1/2/3", "true")
+	waitForSent(t, transport, 1)
+	client.UpdateVirtual("1/2/3", "1")
+	client.UpdateVirtual("1/2/3", "false")
+	waitForSent(t, transport, 2)
+
+	transport.inbound <- read
+	waitForMessage(t, messages)
+
+	sent := waitForSent(t, transport, 3)
+	if len(sent) != 3 {
+		t.Fatalf("transport sent %d events, want 3: %+v", len(sent), sent)
+	}
+	want := []struct {
+		command knxgo.GroupCommand
+		data    byte
+	}{
+		{knxgo.GroupWrite, 1},
+		{knxgo.GroupWrite, 0},
+		{knxgo.GroupResponse, 0},
+	}
+	for i, w := range want {
+		if sent[i].Command != w.command || !bytes.Equal(sent[i].Data, []byte{w.data}) {
+			t.Errorf("event %d = %+v, want %v with data %d", i, sent[i], w.command, w.data)
+		}
+	}
+}
+
+func waitForSent(t *testing.T, transport *fakeTransport, count int) []knxgo.GroupEvent {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		sent := transport.sentEvents()
+		if len(sent) >= count {
+			return sent
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transport sent %d events, want %d", len(sent), count)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestKNXClientReadsOnConnect(t *testing.T) {
+	first := newFakeTransport()
+	second := newFakeTransport()
+	client := newTestClient(t, &fakeDialer{transports: []*fakeTransport{first, second}})
+	client.poller = newPoller(models.ReadScheduleConfig{
+		ReadsPerSecond: 1000,
+		Rules: []models.ReadRule{
+			{Match: []string{"1/2/*"}, OnConnect: true},
+			{Match: []string{"Other/*/*"}, OnConnect: true},
+		},
+	}, client.items())
+
+	if err := client.Connect(func(m *msg.KNXMessage) {}); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+	sent := waitForSent(t, first, 1)
+	if sent[0].Command != knxgo.GroupRead || sent[0].Destination != cemi.NewGroupAddr3(1, 2, 3) {
+		t.Errorf("unexpected event %+v", sent[0])
+	}
+
+	// Reads are repeated after reconnecting.
+	first.drop()
+	waitForSent(t, second, 1)
+}
+
+func TestKNXClientPollsPeriodically(t *testing.T) {
+	transport := newFakeTransport()
+	client := newTestClient(t, &fakeDialer{transports: []*fakeTransport{transport}})
+	client.poller = newPoller(models.ReadScheduleConfig{
+		ReadsPerSecond: 1000,
+		Rules:          []models.ReadRule{{Match: []string{"Main/Middle/Light"}, Interval: 10 * time.Millisecond}},
+	}, client.items())
+
+	if err := client.Connect(func(m *msg.KNXMessage) {}); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+	for _, event := range waitForSent(t, transport, 3) {
+		if event.Command != knxgo.GroupRead {
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+}
+
+func TestPollerRateLimitAndDeduplication(t *testing.T) {
+	knxItems := models.EmptyKNX()
+	p := newPoller(models.ReadScheduleConfig{ReadsPerSecond: 20}, &knxItems)
+	if p.interval != 50*time.Millisecond {
+		t.Errorf("interval = %s, want 50ms", p.interval)
+	}
+
+	p.enqueue([]string{"1/2/3", "1/2/4"})
+	p.enqueue([]string{"1/2/4", "1/2/5"})
+	var addresses []string
+	for address, ok := p.next(); ok; address, ok = p.next() {
+		addresses = append(addresses, address)
+	}
+	if len(addresses) != 3 || addresses[0] != "1/2/3" || addresses[2] != "1/2/5" {
+		t.Errorf("queued addresses = %v", addresses)
+	}
+}
+
+func TestKNXClientReportsStatus(t *testing.T) {
+	first := newFakeTransport()
+	client := newTestClient(t, &fakeDialer{transports: []*fakeTransport{first}})
+	client.cfg.KNX.Endpoint = "192.168.1.10:3671"
+	statuses := make(chan models.KNXStatus, 10)
+	client.OnStatus(func(status models.KNXStatus) {
+		select {
+		case statuses <- status:
+		default:
+		}
+	})
+
+	if err := client.Connect(func(m *msg.KNXMessage) {}); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+	first.drop()
+
+	for _, want := range []string{models.KNXConnected, models.KNXReconnecting, models.KNXFailed} {
+		select {
+		case status := <-statuses:
+			if status.State != want || status.Endpoint != "192.168.1.10:3671" {
+				t.Errorf("status = %+v, want state %s", status, want)
+			}
+			if want == models.KNXFailed && status.Error != "no transport available" {
+				t.Errorf("Error = %q, want the dial error", status.Error)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for status %s", want)
+		}
+	}
+}
+
+func TestKNXClientSetKNX(t *testing.T) {
+	transport := newFakeTransport()
+	dialer := &fakeDialer{transports: []*fakeTransport{transport}}
+	client := newTestClient(t, dialer)
+	client.poller = newPoller(models.ReadScheduleConfig{
+		ReadsPerSecond: 1000,
+		Rules:          []models.ReadRule{{Match: []string{"Main/Middle/Heating"}, Interval: 10 * time.Millisecond}},
+	}, client.items())
+
+	messages := make(chan *msg.KNXMessage, 1)
+	if err := client.Connect(func(m *msg.KNXMessage) { messages <- m }); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+
+	knxItems := models.EmptyKNX()
+	knxItems.AddGroupAddress(models.GroupAddress{
+		Name:        "Heating",
+		FullName:    "Main/Middle/Heating",
+		Address:     "1/2/4",
+		FlatAddress: models.FlatGroupAddress(1<<11 | 2<<8 | 4),
+		Datapoint:   "1.001",
+	})
+	client.SetKNX(&knxItems)
+
+	// The read rule matches the new group address.
+	sent := waitForSent(t, transport, 1)
+	if sent[0].Command != knxgo.GroupRead || sent[0].Destination != cemi.NewGroupAddr3(1, 2, 4) {
+		t.Errorf("unexpected event %+v", sent[0])
+	}
+
+	transport.inbound <- knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: cemi.NewGroupAddr3(1, 2, 3), Data: []byte{1}}
+	if m := waitForMessage(t, messages); m.IsResolved() {
+		t.Errorf("removed group address %s is still resolved", m.Destination())
+	}
+	transport.inbound <- knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: cemi.NewGroupAddr3(1, 2, 4), Data: []byte{1}}
+	if m := waitForMessage(t, messages); !m.IsResolved() || m.Name() != "Heating" {
+		t.Errorf("new group address 1/2/4 is not resolved")
+	}
+	if dialer.dialCount() != 1 {
+		t.Errorf("expected the connection to be kept, dialed %d times", dialer.dialCount())
+	}
+}
+
+// fakeMQTTMessage is an MQTT message received on topic.
+type fakeMQTTMessage struct {
+	topic   string
+	payload []byte
+}
+
+func (m fakeMQTTMessage) Duplicate() bool   { return false }
+func (m fakeMQTTMessage) Qos() byte         { return 0 }
+func (m fakeMQTTMessage) Retained() bool    { return false }
+func (m fakeMQTTMessage) Topic() string     { return m.topic }
+func (m fakeMQTTMessage) MessageID() uint16 { return 0 }
+func (m fakeMQTTMessage) Payload() []byte   { return m.payload }
+func (m fakeMQTTMessage) Ack()              {}
+
+func TestKNXClientAppliesOverrides(t *testing.T) {
+	transport := newFakeTransport()
+	client := newTestClient(t, &fakeDialer{transports: []*fakeTransport{transport}})
+	knxItems := models.EmptyKNX()
+	knxItems.AddGroupAddress(models.GroupAddress{
+		FullName:    "Main/Middle/Light",
+		Address:     "1/2/3",
+		FlatAddress: models.FlatGroupAddress(1<<11 | 2<<8 | 3),
+		Datapoint:   "1.001",
+		ReadOnly:    true,
+	})
+	knxItems.AddGroupAddress(models.GroupAddress{
+		FullName:    "Main/Middle/Setpoint",
+		Address:     "1/2/4",
+		FlatAddress: models.FlatGroupAddress(1<<11 | 2<<8 | 4),
+		Datapoint:   "5.004",
+		Transform:   &models.ValueTransform{Scale: 0.5},
+	})
+	client.SetKNX(&knxItems)
+	if err := client.Connect(func(m *msg.KNXMessage) {}); err != nil {
+		t.Fatalf("Connect() error = %v", *err)
+	}
+
+	client.Send(*msg.NewMQTT(fakeMQTTMessage{topic: "knx/1/2/3/write", payload: []byte("true")}))
+	client.Send(*msg.NewMQTT(fakeMQTTMessage{topic: "knx/1/515/write", payload: []byte("true")}))
+	client.Send(*msg.NewMQTT(fakeMQTTMessage{topic: "knx/2563/write-bytes", payload: []byte{1}}))
+	client.Send(*msg.NewMQTT(fakeMQTTMessage{topic: "knx/Main/Middle/Light/response", payload: []byte("true")}))
+	client.Send(*msg.NewMQTT(fakeMQTTMessage{topic: "knx/1/2/3/read"}))
+	client.Send(*msg.NewMQTT(fakeMQTTMessage{topic: "knx/1/2/4/write", payload: []byte("20")}))
+
+	sent := waitForSent(t, transport, 2)
+	if sent[0].Command != knxgo.GroupRead {
+		t.Errorf("expected only the read to the read-only group address, got %+v", sent[0])
+	}
+	if sent[1].Destination != cemi.NewGroupAddr3(1, 2, 4) || !bytes.Equal(sent[1].Data, []byte{0, 40}) {
+		t.Errorf("expected the transformed value 40, got %+v", sent[1])
+	}
+}
+
+func TestKNXClientResolvesSourceDevice(t *testing.T) {
+	client := newTestClient(t, &fakeDialer{})
+	knxItems := models.EmptyKNX()
+	knxItems.AddGroupAddress(client.items().GroupAddresses[0])
+	knxItems.AddDevice(models.Device{Address: "1.1.5", Name: "Kitchen push button", Sends: []models.FlatGroupAddress{1<<11 | 2<<8 | 3}})
+	client.SetKNX(&knxItems)
+
+	source, _ := cemi.NewIndividualAddrString("1.1.5")
+	m := client.NewMessage(knxgo.GroupEvent{Command: knxgo.GroupWrite, Source: source, Destination: cemi.NewGroupAddr3(1, 2, 3), Data: []byte{1}})
+	if m.SourceName() != "Kitchen push button" {
+		t.Errorf("SourceName() = %q, want %q", m.SourceName(), "Kitchen push button")
+	}
+	payload, err := m.ToPayload(false, models.JsonType, &models.IncludedJsonFields{IncludeSource: true})
+	if err != nil {
+		t.Fatalf("ToPayload() error = %v", err)
+	}
+	if want := `{"command":"","source":"1.1.5","sourceName":"Kitchen push button"}`; payload != want {
+		t.Errorf("ToPayload() = %s, want %s", payload, want)
+	}
+
+	unknown, _ := cemi.NewIndividualAddrString("1.1.9")
+	if m := client.NewMessage(knxgo.GroupEvent{Source: unknown, Destination: cemi.NewGroupAddr3(1, 2, 3), Data: []byte{1}}); m.SourceName() != "" {
+		t.Errorf("SourceName() of unknown device = %q, want empty", m.SourceName())
+	}
+}
+
+func TestPackPayload(t *testing.T) {
+	dimmer := models.GroupAddress{Address: "1/2/5", Datapoint: "5.001"}
+	tests := []struct {
+		groupAddress models.GroupAddress
+		payload      string
+		want         []byte
+		wantErr      bool
+	}{
+		{models.GroupAddress{Datapoint: "1.001"}, `{"value":true}`, []byte{1}, false},
+		{models.GroupAddress{Datapoint: "1.001"}, `{"value":"on"}`, []byte{1}, false},
+		{models.GroupAddress{Datapoint: "1.001"}, `{"value":0}`, []byte{0}, false},
+		{models.GroupAddress{Datapoint: "1.001"}, "Off", []byte{0}, false},
+		{models.GroupAddress{Datapoint: "1.001"}, "maybe", nil, true},
+		{dimmer, `{"value":100,"unit":"%"}`, []byte{0, 255}, false},
+		{dimmer, `{"value":128,"unit":"raw"}`, []byte{0, 128}, false},
+		{dimmer, `{"value":300,"unit":"raw"}`, nil, true},
+		{dimmer, `{"value":50,"unit":"°C"}`, nil, true},
+		{models.GroupAddress{Datapoint: "9.001"}, `{"value":21.5}`, []byte{0, 0x0C, 0x33}, false},
+		{models.GroupAddress{Datapoint: "9.001"}, `{"value":21.5,"unit":"°c"}`, []byte{0, 0x0C, 0x33}, false},
+		{models.GroupAddress{Datapoint: "9.001", Unit: "K"}, `{"value":21.5,"unit":"°C"}`, nil, true},
+		{models.GroupAddress{Datapoint: "9.001"}, `{"value":null}`, nil, true},
+		{models.GroupAddress{Datapoint: "9.001"}, `{"value":21.5,"scale":2}`, nil, true},
+		{models.GroupAddress{Datapoint: "5.004", Transform: &models.ValueTransform{Scale: 0.5}}, `{"value":20}`, []byte{0, 40}, false},
+		{models.GroupAddress{Datapoint: "19.001"}, `{"value":"2024-05-01T14:30:00"}`, localdpt.FromTime(time.Date(2024, 5, 1, 14, 30, 0, 0, time.Local)).Pack(), false},
+		{models.GroupAddress{Datapoint: "232.600"}, `{"value":{"r":1,"g":2,"b":3}}`, []byte{0, 1, 2, 3}, false},
+		{models.GroupAddress{Datapoint: "232.600"}, `{"r":1,"g":2,"b":3}`, []byte{0, 1, 2, 3}, false},
+		{models.GroupAddress{Datapoint: "29.010", Unit: "kWh", Transform: &models.ValueTransform{Scale: 0.001}}, `{"value":1.5,"unit":"kWh"}`, []byte{0, 0, 0, 0, 0, 0, 0, 0x05, 0xDC}, false},
+		{models.GroupAddress{Datapoint: "21.001"}, "fault, inAlarm", []byte{0, 0x0A}, false},
+		{models.GroupAddress{Datapoint: "23.001"}, `{"value":"onOff"}`, []byte{3}, false},
+		{models.GroupAddress{Datapoint: "23.001"}, "sideways", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := packPayload(tt.groupAddress, []byte(tt.payload))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("packPayload(%s, %s) error = %v, wantErr %v", tt.groupAddress.Datapoint, tt.payload, err, tt.wantErr)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("packPayload(%s, %s) = %v, want %v", tt.groupAddress.Datapoint, tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionLost(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{net.ErrClosed, true},
+		{&net.OpError{Op: "write", Net: "udp", Err: errors.New("network is unreachable")}, true},
+		{fmt.Errorf("send: %w", net.ErrClosed), true},
+		{errors.New("response timeout reached"), true},
+		{errors.New("connection server has terminated"), true},
+		{errors.New("payload too long"), false},
+		{errors.New("gateway rejected the telegram"), false},
+	}
+	for _, tt := range tests {
+		if got := connectionLost(tt.err); got != tt.want {
+			t.Errorf("connectionLost(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pakerfeldt/knx-mqtt/internal/dpt"
@@ -28,7 +29,7 @@ type KNXLogger struct {
 	mu         sync.Mutex
 	fileSize   int64
 	lastRotate time.Time
-	knxItems   *models.KNX
+	knxItems   atomic.Pointer[models.KNX]
 }
 
 // KNXLogEntry represents a log entry for a KNX message
@@ -36,6 +37,7 @@ type KNXLogEntry struct {
 	Timestamp   time.Time   `json:"timestamp"`
 	Direction   string      `json:"direction"`
 	Source      string      `json:"source"`
+	SourceName  string      `json:"sourceName,omitempty"`
 	Destination string      `json:"destination"`
 	Command     string      `json:"command"`
 	Bytes       string      `json:"bytes,omitempty"`
@@ -69,13 +71,22 @@ func NewKNXLogger(config models.KNXLogConfig, knxItems *models.KNX) (*KNXLogger,
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	return &KNXLogger{
+	logger := &KNXLogger{
 		config:     config,
 		file:       file,
 		fileSize:   fileInfo.Size(),
 		lastRotate: time.Now(),
-		knxItems:   knxItems,
-	}, nil
+	}
+	logger.knxItems.Store(knxItems)
+	return logger, nil
+}
+
+// SetKNX replaces the group addresses used to resolve names and values.
+func (l *KNXLogger) SetKNX(knxItems *models.KNX) {
+	if l == nil {
+		return
+	}
+	l.knxItems.Store(knxItems)
 }
 
 // LogIncoming logs an incoming KNX message
@@ -88,6 +99,7 @@ func (l *KNXLogger) LogIncoming(message *msg.KNXMessage) error {
 		Timestamp:   time.Now(),
 		Direction:   "incoming",
 		Source:      message.Source(),
+		SourceName:  message.SourceName(),
 		Destination: message.Destination(),
 		Command:     message.Command(),
 		Bytes:       base64.StdEncoding.EncodeToString(message.Data()),
@@ -97,7 +109,7 @@ func (l *KNXLogger) LogIncoming(message *msg.KNXMessage) error {
 		entry.Name = message.Name()
 
 		// Add decoded value and unit
-		if l.knxItems != nil {
+		if l.knxItems.Load() != nil {
 			// Get the datapoint type from the message
 			dpType := message.Datapoint()
 
@@ -132,10 +144,13 @@ func (l *KNXLogger) LogOutgoing(event knxgo.GroupEvent) error {
 	}
 
 	// Try to resolve the group address and add decoded value and unit
-	if l.knxItems != nil {
+	if knxItems := l.knxItems.Load(); knxItems != nil {
+		if device, exists := knxItems.GetDevice(entry.Source); exists {
+			entry.SourceName = device.Name
+		}
 		flatAddr := models.FlatGroupAddress(event.Destination)
-		if index, exists := l.knxItems.GadToIndex[flatAddr]; exists {
-			groupAddress := l.knxItems.GroupAddresses[index]
+		if index, exists := knxItems.GadToIndex[flatAddr]; exists {
+			groupAddress := knxItems.GroupAddresses[index]
 			entry.Name = groupAddress.Name
 
 			// Get the datapoint type and decode the value
@@ -173,15 +188,19 @@ func (l *KNXLogger) writeLogEntry(entry KNXLogEntry) error {
 		data = append(data, '\n')
 	} else {
 		// Text format
-		data = fmt.Appendf(nil, "[%s] %s %s %s %s %s %s %v %s\n",
+		source := entry.Source
+		if entry.SourceName != "" {
+			source = fmt.Sprintf("%s (%s)", entry.Source, entry.SourceName)
+		}
+		data = fmt.Appendf(nil, "[%s] %s %s %s %s %s %s %s %s\n",
 			entry.Timestamp.Format(time.RFC3339),
 			entry.Direction,
-			entry.Source,
+			source,
 			entry.Destination,
 			entry.Command,
 			entry.Bytes,
 			entry.Name,
-			entry.Value,
+			utils.FormatValue(entry.Value),
 			entry.Unit,
 		)
 	}
@@ -0,0 +1,160 @@
+package knx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultReadsPerSecond limits scheduled reads when no rate is configured.
+const defaultReadsPerSecond = 5
+
+// pollRule is a read rule with the group addresses it matched.
+type pollRule struct {
+	rule      models.ReadRule
+	addresses []string
+}
+
+// poller sends the GroupValue_Read requests of the read schedule at a limited rate.
+// Addresses already waiting to be read are not queued again.
+type poller struct {
+	interval time.Duration
+	mu       sync.Mutex
+	rules    []pollRule
+	queue    []string
+	queued   map[string]bool
+	wake     chan struct{}
+}
+
+func newPoller(cfg models.ReadScheduleConfig, knxItems *models.KNX) *poller {
+	p := &poller{
+		queued: make(map[string]bool),
+		wake:   make(chan struct{}, 1),
+	}
+	readsPerSecond := cfg.ReadsPerSecond
+	if readsPerSecond <= 0 {
+		readsPerSecond = defaultReadsPerSecond
+	}
+	p.interval = time.Duration(float64(time.Second) / readsPerSecond)
+
+	for _, rule := range cfg.Rules {
+		p.rules = append(p.rules, pollRule{rule: rule})
+	}
+	p.setKNX(knxItems)
+	return p
+}
+
+// setKNX matches the rules against the group addresses of knxItems.
+func (p *poller) setKNX(knxItems *models.KNX) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.rules {
+		var addresses []string
+		for _, ga := range knxItems.GroupAddresses {
+			for _, pattern := range p.rules[i].rule.Match {
+				if ga.Matches(pattern) {
+					addresses = append(addresses, ga.Address)
+					break
+				}
+			}
+		}
+		if len(addresses) == 0 {
+			log.Warn().Strs("match", p.rules[i].rule.Match).Msg("Read rule does not match any group address")
+		}
+		p.rules[i].addresses = addresses
+	}
+}
+
+// addresses returns the group addresses currently matched by the rule at index.
+func (p *poller) addresses(index int) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rules[index].addresses
+}
+
+// enqueue adds addresses to the read queue.
+func (p *poller) enqueue(addresses []string) {
+	p.mu.Lock()
+	for _, address := range addresses {
+		if !p.queued[address] {
+			p.queued[address] = true
+			p.queue = append(p.queue, address)
+		}
+	}
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes the first address from the read queue.
+func (p *poller) next() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 {
+		return "", false
+	}
+	address := p.queue[0]
+	p.queue = p.queue[1:]
+	delete(p.queued, address)
+	return address, true
+}
+
+// connected queues the reads of all rules that read on connect.
+func (p *poller) connected() {
+	for i := range p.rules {
+		if p.rules[i].rule.OnConnect {
+			p.enqueue(p.addresses(i))
+		}
+	}
+}
+
+// run sends the queued reads through send and schedules the periodic reads until ctx is done.
+func (p *poller) run(ctx context.Context, send func(address string) error) {
+	for i := range p.rules {
+		if p.rules[i].rule.Interval > 0 {
+			go p.schedule(ctx, i)
+		}
+	}
+
+	for {
+		address, ok := p.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.wake:
+				continue
+			}
+		}
+
+		if err := send(address); err != nil {
+			log.Warn().Str("address", address).Str("error", fmt.Sprintf("%s", err)).Msg("Failed to send scheduled read")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+func (p *poller) schedule(ctx context.Context, index int) {
+	ticker := time.NewTicker(p.rules[index].rule.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.enqueue(p.addresses(index))
+		}
+	}
+}
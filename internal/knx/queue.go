@@ -0,0 +1,311 @@
+package knx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/metrics"
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// defaultTelegramsPerSecond keeps the load well below what a TP line can carry
+// when no budget is configured.
+const defaultTelegramsPerSecond = 20
+
+// defaultMaxQueueSize bounds the number of waiting telegrams when no size is configured, e.g. while
+// KNX is unreachable for a long time.
+const defaultMaxQueueSize = 1000
+
+// maxSendAttempts is how often a telegram is sent before it is dropped when the connection fails.
+const maxSendAttempts = 3
+
+// errNotConnected is returned by the send function of the queue when the telegram could not be
+// handed to KNX because the connection is down. Such telegrams are queued again.
+var errNotConnected = errors.New("not connected to KNX")
+
+// sendOrder lists the priorities in the order their telegrams are sent.
+var sendOrder = []models.Priority{models.PriorityHigh, models.PriorityNormal, models.PriorityLow}
+
+// QueueStats is a snapshot of the send queue.
+type QueueStats struct {
+	// Depth is the number of waiting telegrams per priority.
+	Depth map[models.Priority]int
+	// Sent is the total number of telegrams taken from the queue.
+	Sent uint64
+	// Coalesced is the total number of telegrams superseded while waiting.
+	Coalesced uint64
+	// Dropped is the total number of telegrams dropped because the queue was full or sending failed.
+	Dropped uint64
+}
+
+// queueKey identifies telegrams that supersede each other.
+type queueKey struct {
+	command     knxgo.GroupCommand
+	destination cemi.GroupAddr
+}
+
+type queuedEvent struct {
+	event    knxgo.GroupEvent
+	priority models.Priority
+	attempts int
+}
+
+// sendQueue sends telegrams at a limited rate, higher priorities first. A telegram with the same
+// command and destination as one still waiting replaces the waiting one's data instead of being queued.
+// While the queue is paused, e.g. during a reconnect to KNX, telegrams are kept until it is resumed.
+type sendQueue struct {
+	interval      time.Duration
+	retryInterval time.Duration
+	maxSize       int
+	rules         []models.PriorityRule
+	mu            sync.Mutex
+	priorities    map[models.FlatGroupAddress]models.Priority
+	queues        map[models.Priority][]*queuedEvent
+	pending       map[queueKey]*queuedEvent
+	paused        bool
+	sent          uint64
+	coalesced     uint64
+	dropped       uint64
+	wake          chan struct{}
+}
+
+func newSendQueue(cfg models.SendQueueConfig, knxItems *models.KNX) *sendQueue {
+	q := &sendQueue{
+		retryInterval: reconnectInterval,
+		maxSize:       cfg.MaxSize,
+		queues:        make(map[models.Priority][]*queuedEvent),
+		pending:       make(map[queueKey]*queuedEvent),
+		wake:          make(chan struct{}, 1),
+	}
+	if q.maxSize <= 0 {
+		q.maxSize = defaultMaxQueueSize
+	}
+	telegramsPerSecond := cfg.TelegramsPerSecond
+	if telegramsPerSecond <= 0 {
+		telegramsPerSecond = defaultTelegramsPerSecond
+	}
+	q.interval = time.Duration(float64(time.Second) / telegramsPerSecond)
+	q.rules = cfg.Priorities
+	q.setKNX(knxItems)
+	return q
+}
+
+// setKNX matches the priority rules against the group addresses of knxItems.
+func (q *sendQueue) setKNX(knxItems *models.KNX) {
+	priorities := make(map[models.FlatGroupAddress]models.Priority)
+	// The first matching rule decides the priority of a group address.
+	for _, ga := range knxItems.GroupAddresses {
+	rules:
+		for _, rule := range q.rules {
+			for _, pattern := range rule.Match {
+				if ga.Matches(pattern) {
+					priorities[ga.FlatAddress] = rule.Priority
+					break rules
+				}
+			}
+		}
+	}
+
+	q.mu.Lock()
+	q.priorities = priorities
+	q.mu.Unlock()
+}
+
+// priority returns the configured priority of destination.
+func (q *sendQueue) priority(destination cemi.GroupAddr) models.Priority {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.priorities[models.FlatGroupAddress(destination)]
+}
+
+// push queues event with the given priority.
+func (q *sendQueue) push(event knxgo.GroupEvent, priority models.Priority) {
+	key := queueKey{command: event.Command, destination: event.Destination}
+
+	q.mu.Lock()
+	if waiting, ok := q.pending[key]; ok {
+		waiting.event = event
+		q.coalesced++
+		q.mu.Unlock()
+		metrics.QueueCoalesced()
+		log.Trace().Str("address", event.Destination.String()).Msg("Coalesced queued KNX telegram")
+		return
+	}
+	if q.size() >= q.maxSize && !q.evict(priority, true) {
+		q.dropped++
+		q.mu.Unlock()
+		metrics.QueueDropped()
+		log.Warn().Str("address", event.Destination.String()).Int("maxSize", q.maxSize).Msg("KNX send queue is full, dropped telegram")
+		return
+	}
+	queued := &queuedEvent{event: event, priority: priority}
+	q.pending[key] = queued
+	q.queues[priority] = append(q.queues[priority], queued)
+	metrics.SetQueueDepth(priority.String(), len(q.queues[priority]))
+	q.mu.Unlock()
+	q.notify()
+}
+
+// notify wakes up run.
+func (q *sendQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// size returns the number of waiting telegrams. It must be called with q.mu held.
+func (q *sendQueue) size() int {
+	size := 0
+	for _, queue := range q.queues {
+		size += len(queue)
+	}
+	return size
+}
+
+// evict drops the oldest telegram of the lowest waiting priority to make room for a telegram
+// with the given priority. Telegrams of the same priority are only dropped if samePriority is set.
+// It returns false if nothing was dropped. It must be called with q.mu held.
+func (q *sendQueue) evict(priority models.Priority, samePriority bool) bool {
+	for i := len(sendOrder) - 1; i >= 0; i-- {
+		lowest := sendOrder[i]
+		if lowest == priority && !samePriority {
+			return false
+		}
+		queue := q.queues[lowest]
+		if len(queue) == 0 {
+			if lowest == priority {
+				return false
+			}
+			continue
+		}
+		evicted := queue[0]
+		q.queues[lowest] = queue[1:]
+		metrics.SetQueueDepth(lowest.String(), len(q.queues[lowest]))
+		delete(q.pending, queueKey{command: evicted.event.Command, destination: evicted.event.Destination})
+		q.dropped++
+		metrics.QueueDropped()
+		log.Warn().Str("address", evicted.event.Destination.String()).Int("maxSize", q.maxSize).Msg("KNX send queue is full, dropped oldest telegram")
+		return true
+	}
+	return false
+}
+
+// requeue puts a telegram that could not be sent back at the front of its priority, unless a newer
+// telegram superseding it is already waiting. As the oldest telegram of its priority, it only makes
+// room by dropping telegrams of lower priority when the queue is full. It returns false if the
+// telegram was dropped instead because it failed too often or the queue is full.
+func (q *sendQueue) requeue(queued *queuedEvent) bool {
+	key := queueKey{command: queued.event.Command, destination: queued.event.Destination}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// A telegram taken from the queue was not sent after all
+	q.sent--
+	queued.attempts++
+	if queued.attempts >= maxSendAttempts {
+		q.dropped++
+		metrics.QueueDropped()
+		return false
+	}
+	if _, ok := q.pending[key]; ok {
+		q.coalesced++
+		metrics.QueueCoalesced()
+		return true
+	}
+	if q.size() >= q.maxSize && !q.evict(queued.priority, false) {
+		q.dropped++
+		metrics.QueueDropped()
+		log.Warn().Str("address", queued.event.Destination.String()).Int("maxSize", q.maxSize).Msg("KNX send queue is full, dropped telegram")
+		return false
+	}
+	q.pending[key] = queued
+	q.queues[queued.priority] = append([]*queuedEvent{queued}, q.queues[queued.priority]...)
+	metrics.SetQueueDepth(queued.priority.String(), len(q.queues[queued.priority]))
+	return true
+}
+
+// pause keeps the waiting telegrams in the queue until resume is called.
+func (q *sendQueue) pause() {
+	q.mu.Lock()
+	q.paused = true
+	q.mu.Unlock()
+}
+
+// resume continues sending the waiting telegrams.
+func (q *sendQueue) resume() {
+	q.mu.Lock()
+	q.paused = false
+	q.mu.Unlock()
+	q.notify()
+}
+
+// pop removes the next telegram to send from the queue. It returns nothing while the queue is paused.
+func (q *sendQueue) pop() (*queuedEvent, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.paused {
+		return nil, false
+	}
+	for _, priority := range sendOrder {
+		queue := q.queues[priority]
+		if len(queue) == 0 {
+			continue
+		}
+		queued := queue[0]
+		q.queues[priority] = queue[1:]
+		metrics.SetQueueDepth(priority.String(), len(q.queues[priority]))
+		delete(q.pending, queueKey{command: queued.event.Command, destination: queued.event.Destination})
+		q.sent++
+		return queued, true
+	}
+	return nil, false
+}
+
+// stats returns a snapshot of the queue.
+func (q *sendQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	depth := make(map[models.Priority]int, len(sendOrder))
+	for _, priority := range sendOrder {
+		depth[priority] = len(q.queues[priority])
+	}
+	return QueueStats{Depth: depth, Sent: q.sent, Coalesced: q.coalesced, Dropped: q.dropped}
+}
+
+// run sends the queued telegrams through send until ctx is done. Telegrams failing with
+// errNotConnected are sent again after retryInterval.
+func (q *sendQueue) run(ctx context.Context, send func(event knxgo.GroupEvent) error) {
+	for {
+		queued, ok := q.pop()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+				continue
+			}
+		}
+
+		delay := q.interval
+		event := queued.event
+		if err := send(event); errors.Is(err, errNotConnected) && q.requeue(queued) {
+			log.Warn().Str("error", fmt.Sprintf("%s", err)).Str("address", event.Destination.String()).Msgf("Error writing to KNX, retrying in %s", q.retryInterval)
+			delay = q.retryInterval
+		} else if err != nil {
+			log.Error().Str("error", fmt.Sprintf("%s", err)).Str("address", event.Destination.String()).Msgf("Error writing to KNX")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package knx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+func TestSendQueuePrioritiesAndCoalescing(t *testing.T) {
+	knxItems := models.EmptyKNX()
+	knxItems.AddGroupAddress(models.GroupAddress{FullName: "Alarms/Fire/Alarm", Address: "7/0/1", FlatAddress: models.FlatGroupAddress(cemi.NewGroupAddr3(7, 0, 1))})
+	knxItems.AddGroupAddress(models.GroupAddress{FullName: "Lights/Kitchen/Light", Address: "1/2/3", FlatAddress: models.FlatGroupAddress(cemi.NewGroupAddr3(1, 2, 3))})
+	q := newSendQueue(models.SendQueueConfig{
+		TelegramsPerSecond: 50,
+		Priorities:         []models.PriorityRule{{Match: []string{"Alarms/*/*"}, Priority: models.PriorityHigh}},
+	}, &knxItems)
+	if q.interval != 20*time.Millisecond {
+		t.Errorf("interval = %s, want 20ms", q.interval)
+	}
+
+	light := cemi.NewGroupAddr3(1, 2, 3)
+	alarm := cemi.NewGroupAddr3(7, 0, 1)
+	push := func(command knxgo.GroupCommand, destination cemi.GroupAddr, priority models.Priority, data byte) {
+		q.push(knxgo.GroupEvent{Command: command, Destination: destination, Data: []byte{data}}, priority)
+	}
+	push(knxgo.GroupRead, light, models.PriorityLow, 0)
+	push(knxgo.GroupWrite, light, q.priority(light), 1)
+	push(knxgo.GroupWrite, light, q.priority(light), 0)
+	push(knxgo.GroupWrite, alarm, q.priority(alarm), 1)
+
+	stats := q.stats()
+	if stats.Depth[models.PriorityHigh] != 1 || stats.Depth[models.PriorityNormal] != 1 || stats.Depth[models.PriorityLow] != 1 {
+		t.Errorf("Depth = %v", stats.Depth)
+	}
+	if stats.Coalesced != 1 {
+		t.Errorf("Coalesced = %d, want 1", stats.Coalesced)
+	}
+
+	want := []knxgo.GroupEvent{
+		{Command: knxgo.GroupWrite, Destination: alarm, Data: []byte{1}},
+		{Command: knxgo.GroupWrite, Destination: light, Data: []byte{0}},
+		{Command: knxgo.GroupRead, Destination: light, Data: []byte{0}},
+	}
+	for i, w := range want {
+		queued, ok := q.pop()
+		if !ok {
+			t.Fatalf("pop() %d returned nothing", i)
+		}
+		event := queued.event
+		if event.Command != w.Command || event.Destination != w.Destination || event.Data[0] != w.Data[0] {
+			t.Errorf("pop() %d = %+v, want %+v", i, event, w)
+		}
+	}
+	if _, ok := q.pop(); ok {
+		t.Error("expected empty queue")
+	}
+	if q.stats().Sent != 3 {
+		t.Errorf("Sent = %d, want 3", q.stats().Sent)
+	}
+}
+
+func TestSendQueueMaxSize(t *testing.T) {
+	knxItems := models.EmptyKNX()
+	q := newSendQueue(models.SendQueueConfig{MaxSize: 2}, &knxItems)
+	push := func(destination cemi.GroupAddr, priority models.Priority) {
+		q.push(knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: destination, Data: []byte{1}}, priority)
+	}
+	push(cemi.NewGroupAddr3(1, 0, 1), models.PriorityLow)
+	push(cemi.NewGroupAddr3(1, 0, 2), models.PriorityHigh)
+	// Replaces the low priority telegram
+	push(cemi.NewGroupAddr3(1, 0, 3), models.PriorityNormal)
+	// Only telegrams of higher priority are waiting
+	push(cemi.NewGroupAddr3(1, 0, 4), models.PriorityLow)
+	// Replaces the oldest telegram of the same priority
+	push(cemi.NewGroupAddr3(1, 0, 5), models.PriorityNormal)
+
+	if dropped := q.stats().Dropped; dropped != 3 {
+		t.Errorf("Dropped = %d, want 3", dropped)
+	}
+	for _, want := range []cemi.GroupAddr{cemi.NewGroupAddr3(1, 0, 2), cemi.NewGroupAddr3(1, 0, 5)} {
+		queued, ok := q.pop()
+		if !ok || queued.event.Destination != want {
+			t.Fatalf("pop() = %v, %v, want %s", queued, ok, want)
+		}
+	}
+	if _, ok := q.pop(); ok {
+		t.Error("expected empty queue")
+	}
+}
+
+func TestSendQueueRequeueRespectsMaxSize(t *testing.T) {
+	knxItems := models.EmptyKNX()
+	q := newSendQueue(models.SendQueueConfig{MaxSize: 2}, &knxItems)
+	push := func(destination cemi.GroupAddr, priority models.Priority) {
+		q.push(knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: destination, Data: []byte{1}}, priority)
+	}
+	push(cemi.NewGroupAddr3(1, 0, 1), models.PriorityNormal)
+	failed, _ := q.pop()
+	push(cemi.NewGroupAddr3(1, 0, 2), models.PriorityNormal)
+	push(cemi.NewGroupAddr3(1, 0, 3), models.PriorityLow)
+
+	// Makes room by dropping the low priority telegram
+	if !q.requeue(failed) {
+		t.Fatal("requeue() dropped the telegram, want the low priority one dropped")
+	}
+	// Only telegrams of the same or higher priority are waiting
+	low := &queuedEvent{event: knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: cemi.NewGroupAddr3(1, 0, 4)}, priority: models.PriorityLow}
+	if q.requeue(low) {
+		t.Error("requeue() kept a telegram exceeding the maximum size")
+	}
+
+	stats := q.stats()
+	if stats.Depth[models.PriorityNormal] != 2 || stats.Depth[models.PriorityLow] != 0 || stats.Dropped != 2 {
+		t.Errorf("stats = %+v, want 2 normal telegrams waiting and 2 dropped", stats)
+	}
+	for _, want := range []cemi.GroupAddr{cemi.NewGroupAddr3(1, 0, 1), cemi.NewGroupAddr3(1, 0, 2)} {
+		queued, ok := q.pop()
+		if !ok || queued.event.Destination != want {
+			t.Fatalf("pop() = %v, %v, want %s", queued, ok, want)
+		}
+	}
+}
+
+func TestSendQueueKeepsTelegramsWhileNotConnected(t *testing.T) {
+	knxItems := models.EmptyKNX()
+	q := newSendQueue(models.SendQueueConfig{TelegramsPerSecond: 1000}, &knxItems)
+	q.retryInterval = time.Millisecond
+
+	var mu sync.Mutex
+	failures := 0
+	var sent []knxgo.GroupEvent
+	send := func(event knxgo.GroupEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// The connection is not up yet when the queue resumes
+		if failures < maxSendAttempts-1 {
+			failures++
+			return errNotConnected
+		}
+		sent = append(sent, event)
+		return nil
+	}
+	sentCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.pause()
+	go q.run(ctx, send)
+
+	event := knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: cemi.NewGroupAddr3(1, 2, 3), Data: []byte{1}}
+	q.push(event, models.PriorityNormal)
+	time.Sleep(20 * time.Millisecond)
+	if n := sentCount(); n != 0 || q.stats().Depth[models.PriorityNormal] != 1 {
+		t.Fatalf("sent %d telegrams while paused, depth %v", n, q.stats().Depth)
+	}
+
+	q.resume()
+	deadline := time.Now().Add(time.Second)
+	for sentCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := sentCount(); n != 1 {
+		t.Fatalf("sent %d telegrams, want 1", n)
+	}
+	if stats := q.stats(); stats.Sent != 1 || stats.Dropped != 0 {
+		t.Errorf("stats = %+v, want 1 sent and none dropped", stats)
+	}
+}
@@ -0,0 +1,188 @@
+package knx
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/pakerfeldt/knx-mqtt/internal/secure"
+	"github.com/rs/zerolog/log"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+	"github.com/vapourismo/knx-go/knx/knxnet"
+)
+
+// Transport is a connection to the KNX bus capable of group communication.
+// It is satisfied by *groupConn as well as the KNX IP Secure transports
+// *secure.GroupTunnel and *secure.GroupRouter.
+type Transport interface {
+	Send(event knxgo.GroupEvent) error
+	Inbound() <-chan knxgo.GroupEvent
+	Close()
+}
+
+// Dialer establishes a new Transport to the KNX bus.
+type Dialer func() (Transport, error)
+
+// NewDialer returns a Dialer for the transport configured in cfg,
+// i.e. a tunnel (unicast) or a router (multicast) connection.
+// If KNX IP Secure is enabled, the credentials are taken from keyring.
+func NewDialer(cfg models.KNXConfig, keyring *secure.Keyring) (Dialer, error) {
+	if cfg.Secure.IPSecure {
+		return newSecureDialer(cfg, keyring)
+	}
+	if cfg.TunnelMode {
+		return func() (Transport, error) {
+			tunnel, err := dialTunnel(cfg.Endpoint)
+			if err != nil {
+				return nil, err
+			}
+			return newGroupConn(tunnel, true), nil
+		}, nil
+	}
+	return func() (Transport, error) {
+		router, err := dialRouter(cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return newGroupConn(router, false), nil
+	}, nil
+}
+
+// dialTunnel and dialRouter open the knx-go connections of NewDialer. They are replaced in tests.
+var (
+	dialTunnel = func(endpoint string) (messageConn, error) {
+		tunnel, err := knxgo.NewTunnel(endpoint, knxnet.TunnelLayerData, knxgo.DefaultTunnelConfig)
+		if err != nil {
+			return nil, err
+		}
+		return tunnel, nil
+	}
+	dialRouter = func(endpoint string) (messageConn, error) {
+		router, err := knxgo.NewRouter(endpoint, knxgo.DefaultRouterConfig)
+		if err != nil {
+			return nil, err
+		}
+		return router, nil
+	}
+)
+
+// messageConn is a knx-go connection exchanging cEMI frames, i.e. *knxgo.Tunnel or *knxgo.Router.
+type messageConn interface {
+	Send(message cemi.Message) error
+	Inbound() <-chan cemi.Message
+	Close()
+}
+
+// groupConn provides group communication on top of a knx-go tunnel or router.
+// Unlike knxgo.GroupTunnel and knxgo.GroupRouter it passes on KNX Data Secure telegrams.
+type groupConn struct {
+	conn    messageConn
+	tunnel  bool
+	inbound chan knxgo.GroupEvent
+	// done is closed by Close to stop serve once nobody reads inbound anymore.
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newGroupConn(conn messageConn, tunnel bool) *groupConn {
+	g := &groupConn{
+		conn:    conn,
+		tunnel:  tunnel,
+		inbound: make(chan knxgo.GroupEvent),
+		done:    make(chan struct{}),
+	}
+	go g.serve()
+	return g
+}
+
+func (g *groupConn) serve() {
+	defer close(g.inbound)
+	for {
+		select {
+		case <-g.done:
+			return
+		case message, ok := <-g.conn.Inbound():
+			if !ok {
+				return
+			}
+			event, ok := secure.GroupEventFromMessage(message)
+			if !ok {
+				continue
+			}
+			select {
+			case g.inbound <- event:
+			case <-g.done:
+				return
+			}
+		}
+	}
+}
+
+// Send transmits a group event, as L_Data.req through a tunnel or as L_Data.ind when routing.
+func (g *groupConn) Send(event knxgo.GroupEvent) error {
+	ldata := secure.BuildGroupOutbound(event)
+	if g.tunnel {
+		return g.conn.Send(&cemi.LDataReq{LData: ldata})
+	}
+	return g.conn.Send(&cemi.LDataInd{LData: ldata})
+}
+
+func (g *groupConn) Inbound() <-chan knxgo.GroupEvent {
+	return g.inbound
+}
+
+func (g *groupConn) Close() {
+	g.closeOnce.Do(func() {
+		close(g.done)
+		g.conn.Close()
+	})
+}
+
+func newSecureDialer(cfg models.KNXConfig, keyring *secure.Keyring) (Dialer, error) {
+	if keyring == nil {
+		return nil, fmt.Errorf("KNX IP Secure requires a keyring")
+	}
+
+	if cfg.TunnelMode {
+		iface, err := keyring.Tunnel(cfg.Secure.TunnelAddress)
+		if err != nil {
+			return nil, err
+		}
+		authentication := iface.Authentication
+		if authentication == "" {
+			authentication = keyring.Devices[iface.Host].Authentication
+		}
+		credentials := secure.TunnelCredentials{
+			UserID:          iface.UserID,
+			UserPasswordKey: secure.UserPasswordKey(iface.Password),
+		}
+		if authentication != "" {
+			credentials.DeviceAuthenticationCode = secure.DeviceAuthenticationCode(authentication)
+		}
+		log.Info().Str("tunnel", iface.IndividualAddress).Uint8("user", iface.UserID).Msg("Using KNX IP Secure tunnel")
+		return func() (Transport, error) {
+			return secure.NewGroupTunnel(cfg.Endpoint, credentials)
+		}, nil
+	}
+
+	if keyring.Backbone == nil {
+		return nil, fmt.Errorf("keyring does not contain a secure backbone")
+	}
+	backbone := *keyring.Backbone
+	return func() (Transport, error) {
+		return secure.NewGroupRouter(cfg.Endpoint, backbone.Key, backbone.Latency)
+	}, nil
+}
+
+// TransportName returns a human readable name of the transport configured in cfg.
+func TransportName(cfg models.KNXConfig) string {
+	kind := "router"
+	if cfg.TunnelMode {
+		kind = "tunnel"
+	}
+	if cfg.Secure.IPSecure {
+		kind = "secure " + kind
+	}
+	return fmt.Sprintf("%s %s", kind, cfg.Endpoint)
+}
@@ -0,0 +1,134 @@
+package knx
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// fakeMessageConn is an in-memory messageConn.
+type fakeMessageConn struct {
+	mu        sync.Mutex
+	inbound   chan cemi.Message
+	sent      []cemi.Message
+	closeOnce sync.Once
+}
+
+func (f *fakeMessageConn) Send(message cemi.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, message)
+	return nil
+}
+
+func (f *fakeMessageConn) Inbound() <-chan cemi.Message {
+	return f.inbound
+}
+
+func (f *fakeMessageConn) Close() {
+	f.closeOnce.Do(func() { close(f.inbound) })
+}
+
+func (f *fakeMessageConn) sentMessages() []cemi.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]cemi.Message(nil), f.sent...)
+}
+
+// stubDialers makes NewDialer connect tunnels and routers to conn.
+func stubDialers(t *testing.T, conn *fakeMessageConn) {
+	t.Helper()
+	previousTunnel, previousRouter := dialTunnel, dialRouter
+	t.Cleanup(func() { dialTunnel, dialRouter = previousTunnel, previousRouter })
+	dialTunnel = func(string) (messageConn, error) { return conn, nil }
+	dialRouter = func(string) (messageConn, error) { return conn, nil }
+}
+
+func groupInd(command cemi.APCI, data []byte) *cemi.LDataInd {
+	return &cemi.LDataInd{LData: cemi.LData{
+		Control2:    cemi.Control2GroupAddr,
+		Source:      cemi.NewIndividualAddr3(1, 1, 5),
+		Destination: uint16(cemi.NewGroupAddr3(1, 2, 3)),
+		Data:        &cemi.AppData{Command: command, Data: data},
+	}}
+}
+
+func TestGroupConnInbound(t *testing.T) {
+	conn := &fakeMessageConn{inbound: make(chan cemi.Message, 4)}
+	group := newGroupConn(conn, true)
+
+	conn.inbound <- groupInd(cemi.MemoryRead, []byte{0})
+	conn.inbound <- groupInd(cemi.GroupValueWrite, []byte{1})
+	conn.inbound <- groupInd(cemi.Escape, []byte{0x31, 0x10, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	conn.Close()
+
+	var events []knxgo.GroupEvent
+	timeout := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case event, ok := <-group.Inbound():
+			if !ok {
+				done = true
+				break
+			}
+			events = append(events, event)
+		case <-timeout:
+			t.Fatal("timed out waiting for inbound channel to close")
+		}
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	if events[0].Command != knxgo.GroupWrite || events[0].Source != cemi.NewIndividualAddr3(1, 1, 5) {
+		t.Errorf("unexpected group write %+v", events[0])
+	}
+	if events[1].Command != knxgo.GroupCommand(cemi.Escape) {
+		t.Errorf("expected Data Secure telegram to be passed on, got %+v", events[1])
+	}
+}
+
+func TestGroupConnCloseStopsServe(t *testing.T) {
+	conn := &fakeMessageConn{inbound: make(chan cemi.Message, 1)}
+	group := newGroupConn(conn, true)
+
+	conn.inbound <- groupInd(cemi.GroupValueWrite, []byte{1})
+	for deadline := time.Now().Add(time.Second); len(conn.inbound) > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	// Nobody reads the event, serve must not block on it once the connection is closed.
+	group.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case event, ok := <-group.Inbound():
+		if ok {
+			t.Errorf("expected serve to stop on close, got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for inbound channel to close")
+	}
+}
+
+func TestGroupConnSend(t *testing.T) {
+	event := knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: cemi.NewGroupAddr3(1, 2, 3), Data: []byte{1}}
+
+	tunnel := &fakeMessageConn{inbound: make(chan cemi.Message)}
+	if err := newGroupConn(tunnel, true).Send(event); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if _, ok := tunnel.sent[0].(*cemi.LDataReq); !ok {
+		t.Errorf("tunnel sent %T, want *cemi.LDataReq", tunnel.sent[0])
+	}
+
+	router := &fakeMessageConn{inbound: make(chan cemi.Message)}
+	if err := newGroupConn(router, false).Send(event); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if _, ok := router.sent[0].(*cemi.LDataInd); !ok {
+		t.Errorf("router sent %T, want *cemi.LDataInd", router.sent[0])
+	}
+}
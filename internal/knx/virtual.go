@@ -0,0 +1,83 @@
+package knx
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+	knxgo "github.com/vapourismo/knx-go/knx"
+)
+
+// virtualGroupAddresses holds the current values of the group addresses owned by the bridge.
+type virtualGroupAddresses struct {
+	mu sync.Mutex
+	// values maps an owned group address to its last packed value, nil until one has been received.
+	values map[models.FlatGroupAddress][]byte
+}
+
+func newVirtualGroupAddresses(knxItems *models.KNX, virtual []models.VirtualGroupAddress) *virtualGroupAddresses {
+	v := &virtualGroupAddresses{values: make(map[models.FlatGroupAddress][]byte)}
+	for _, address := range virtual {
+		groupAddress, exists := knxItems.GetGroupAddress(address.Address)
+		if !exists {
+			log.Error().Str("address", address.Address).Msg("Missing reference to virtual group address")
+			continue
+		}
+		v.values[groupAddress.FlatAddress] = nil
+	}
+	return v
+}
+
+// update stores value for address and reports whether it changed.
+func (v *virtualGroupAddresses) update(address models.FlatGroupAddress, value []byte) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	previous, owned := v.values[address]
+	if !owned {
+		return false
+	}
+	v.values[address] = value
+	return previous == nil || !bytes.Equal(previous, value)
+}
+
+// get returns the current value of address, if it is owned and has a value.
+func (v *virtualGroupAddresses) get(address models.FlatGroupAddress) ([]byte, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	value := v.values[address]
+	return value, value != nil
+}
+
+// UpdateVirtual sets the value of a virtual group address from its string representation
+// and writes it to KNX if it changed.
+func (c *KNXClient) UpdateVirtual(address string, value string) {
+	event := c.createWriteEvent([]byte(value), address, false, false)
+	if event == nil {
+		log.Error().Str("address", address).Msg("Failed to create KNX write event for virtual group address")
+		return
+	}
+	if !c.virtual.update(models.FlatGroupAddress(event.Destination), event.Data) {
+		return
+	}
+	log.Debug().Str("protocol", "knx").Str("address", address).Str("value", value).Msg("Outgoing virtual")
+	c.enqueue(*event)
+}
+
+// respondVirtual answers a GroupValue_Read for a virtual group address with its current value.
+func (c *KNXClient) respondVirtual(event knxgo.GroupEvent) {
+	if event.Command != knxgo.GroupRead {
+		return
+	}
+	value, ok := c.virtual.get(models.FlatGroupAddress(event.Destination))
+	if !ok {
+		return
+	}
+	response := c.createWriteEvent(value, event.Destination.String(), true, true)
+	if response == nil {
+		log.Error().Str("address", event.Destination.String()).Msg("Failed to create KNX response event for virtual group address")
+		return
+	}
+	log.Debug().Str("protocol", "knx").Str("address", event.Destination.String()).Hex("value", value).Msg("Responding to read")
+	c.enqueue(*response)
+}
@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+const namespace = "knx_mqtt"
+
+// busLoadWindow is the period over which the bus load is estimated.
+const busLoadWindow = time.Minute
+
+// busCapacity is the approximate number of telegrams per second a TP1 line can carry.
+const busCapacity = 50
+
+var registry = prometheus.NewRegistry()
+
+var (
+	telegrams = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "knx_telegrams_total",
+		Help:      "Number of group telegrams received from and sent to KNX.",
+	}, []string{"direction", "command", "address"})
+	decodeFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "knx_decode_failures_total",
+		Help:      "Number of received telegrams that could not be decoded using the datapoint type of the group address.",
+	}, []string{"address", "datapoint"})
+	packFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "knx_pack_failures_total",
+		Help:      "Number of values from MQTT that could not be packed using the datapoint type of the group address.",
+	}, []string{"address", "datapoint"})
+	publishErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mqtt_publish_errors_total",
+		Help:      "Number of MQTT messages that could not be published.",
+	})
+	reconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconnects_total",
+		Help:      "Number of times a lost connection was re-established.",
+	}, []string{"connection"})
+	connected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connected",
+		Help:      "Whether the connection is established (1) or not (0).",
+	}, []string{"connection"})
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "knx_send_queue_depth",
+		Help:      "Number of telegrams waiting to be sent to KNX.",
+	}, []string{"priority"})
+	queueCoalesced = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "knx_send_queue_coalesced_total",
+		Help:      "Number of queued telegrams replaced by a newer telegram to the same group address.",
+	})
+	queueDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "knx_send_queue_dropped_total",
+		Help:      "Number of telegrams dropped because the send queue was full or sending failed repeatedly.",
+	})
+	busTelegrams = &telegramRate{window: busLoadWindow}
+)
+
+func init() {
+	registry.MustRegister(
+		telegrams,
+		decodeFailures,
+		packFailures,
+		publishErrors,
+		reconnects,
+		connected,
+		queueDepth,
+		queueCoalesced,
+		queueDropped,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "knx_bus_telegrams_per_second",
+			Help:      "Group telegrams per second seen by the bridge, averaged over the last minute.",
+		}, busTelegrams.rate),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "knx_bus_load_ratio",
+			Help:      "Estimated load of a TP1 line, assuming it carries 50 telegrams per second.",
+		}, func() float64 { return busTelegrams.rate() / busCapacity }),
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+// TelegramReceived counts a telegram received from KNX.
+func TelegramReceived(command string, address string) {
+	telegrams.WithLabelValues("in", command, address).Inc()
+	busTelegrams.add()
+}
+
+// TelegramSent counts a telegram sent to KNX.
+func TelegramSent(command string, address string) {
+	telegrams.WithLabelValues("out", command, address).Inc()
+	busTelegrams.add()
+}
+
+// DecodeFailed counts a received telegram that could not be decoded.
+func DecodeFailed(address string, datapoint string) {
+	decodeFailures.WithLabelValues(address, datapoint).Inc()
+}
+
+// PackFailed counts a value that could not be packed for sending.
+func PackFailed(address string, datapoint string) {
+	packFailures.WithLabelValues(address, datapoint).Inc()
+}
+
+// PublishFailed counts an MQTT message that could not be published.
+func PublishFailed() {
+	publishErrors.Inc()
+}
+
+// Reconnected counts a re-established connection, either "knx" or "mqtt".
+func Reconnected(connection string) {
+	reconnects.WithLabelValues(connection).Inc()
+}
+
+// SetConnected sets the state of a connection, either "knx" or "mqtt".
+func SetConnected(connection string, isConnected bool) {
+	value := 0.0
+	if isConnected {
+		value = 1
+	}
+	connected.WithLabelValues(connection).Set(value)
+}
+
+// SetQueueDepth sets the number of telegrams waiting to be sent with the given priority.
+func SetQueueDepth(priority string, depth int) {
+	queueDepth.WithLabelValues(priority).Set(float64(depth))
+}
+
+// QueueCoalesced counts a queued telegram replaced by a newer one.
+func QueueCoalesced() {
+	queueCoalesced.Inc()
+}
+
+// QueueDropped counts a telegram dropped from the send queue.
+func QueueDropped() {
+	queueDropped.Inc()
+}
+
+// Handler returns the HTTP handler serving the metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Serve serves the metrics on /metrics at address until ctx is done.
+func Serve(ctx context.Context, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Info().Str("address", address).Msg("Serving metrics")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error().Err(err).Msg("Failed to serve metrics")
+	}
+}
+
+// telegramRate keeps the times of the telegrams within a sliding window.
+type telegramRate struct {
+	mu     sync.Mutex
+	window time.Duration
+	times  []time.Time
+}
+
+func (r *telegramRate) add() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.prune(now)
+	r.times = append(r.times, now)
+}
+
+func (r *telegramRate) rate() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(time.Now())
+	return float64(len(r.times)) / r.window.Seconds()
+}
+
+// prune drops the times outside the window. It must be called with r.mu held.
+func (r *telegramRate) prune(now time.Time) {
+	cutoff := now.Add(-r.window)
+	i := 0
+	for i < len(r.times) && r.times[i].Before(cutoff) {
+		i++
+	}
+	r.times = r.times[i:]
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	TelegramReceived("GroupValue_Write", "1/2/3")
+	TelegramSent("GroupValue_Read", "1/2/3")
+	DecodeFailed("1/2/4", "9.001")
+	SetConnected("knx", true)
+	SetQueueDepth("normal", 2)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+
+	for _, want := range []string{
+		`knx_mqtt_knx_telegrams_total{address="1/2/3",command="GroupValue_Write",direction="in"} 1`,
+		`knx_mqtt_knx_telegrams_total{address="1/2/3",command="GroupValue_Read",direction="out"} 1`,
+		`knx_mqtt_knx_decode_failures_total{address="1/2/4",datapoint="9.001"} 1`,
+		`knx_mqtt_connected{connection="knx"} 1`,
+		`knx_mqtt_knx_send_queue_depth{priority="normal"} 2`,
+		`knx_mqtt_knx_bus_telegrams_per_second`,
+		`knx_mqtt_knx_bus_load_ratio`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+}
+
+func TestTelegramRate(t *testing.T) {
+	r := &telegramRate{window: 10 * time.Second}
+	now := time.Now()
+	r.times = []time.Time{now.Add(-time.Minute), now.Add(-20 * time.Second)}
+	for i := 0; i < 5; i++ {
+		r.add()
+	}
+	if got := r.rate(); got != 0.5 {
+		t.Errorf("rate() = %v, want 0.5", got)
+	}
+}
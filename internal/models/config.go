@@ -1,14 +1,26 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
 
 // Config represents the top-level structure of the YAML configuration.
 type Config struct {
-	LogLevel                    string              `yaml:"loglevel"`
-	OutgoingMqttMessage         OutgoingMqttMessage `yaml:"outgoingMqttMessage"`
-	IgnoreUnknownGroupAddresses bool                `yaml:"ignoreUnknownGroupAddresses"`
-	KNX                         KNXConfig           `yaml:"knx"`
-	MQTT                        MQTTConfig          `yaml:"mqtt"`
+	LogLevel                    string                 `yaml:"loglevel"`
+	OutgoingMqttMessage         OutgoingMqttMessage    `yaml:"outgoingMqttMessage"`
+	IgnoreUnknownGroupAddresses bool                   `yaml:"ignoreUnknownGroupAddresses"`
+	KNX                         KNXConfig              `yaml:"knx"`
+	MQTT                        MQTTConfig             `yaml:"mqtt"`
+	HomeAssistant               HomeAssistantConfig    `yaml:"homeAssistant"`
+	StateCache                  StateCacheConfig       `yaml:"stateCache"`
+	VirtualGroupAddresses       []VirtualGroupAddress  `yaml:"virtualGroupAddresses"`
+	ReadSchedule                ReadScheduleConfig     `yaml:"readSchedule"`
+	Metrics                     MetricsConfig          `yaml:"metrics"`
+	Overrides                   []GroupAddressOverride `yaml:"overrides"`
 }
 
 const ValueType = "value"
@@ -20,6 +32,7 @@ type OutgoingMqttMessage struct {
 	Type                  string             `yaml:"type"`
 	EmitUsingAddress      bool               `yaml:"emitUsingAddress"`
 	EmitUsingName         bool               `yaml:"emitUsingName"`
+	EmitUsingLocation     bool               `yaml:"emitUsingLocation"`
 	EmitValueAsString     bool               `yaml:"emitValueAsString"`
 	ReadCommandsOwnPrefix bool               `yaml:"readCommandsOwnPrefix"`
 	IncludedJsonFields    IncludedJsonFields `yaml:"includedJsonFields"`
@@ -47,13 +60,147 @@ type KNXLogConfig struct {
 
 // KNXConfig represents the KNX configuration section.
 type KNXConfig struct {
-	ETSExport                   string                 `yaml:"etsExport"`
+	ETSExport                   ETSExports             `yaml:"etsExport"`
+	ProjectPassword             string                 `yaml:"projectPassword"`
+	WatchETSExport              bool                   `yaml:"watchEtsExport"`
+	DefaultDatapoints           map[string]string      `yaml:"defaultDatapoints"`
+	Validation                  ValidationPolicy       `yaml:"validation"`
 	Endpoint                    string                 `yaml:"endpoint"`
 	TunnelMode                  bool                   `yaml:"tunnelMode"`
 	IgnoreUnknownGroupAddresses bool                   `yaml:"ignoreUnknownGroupAddresses"`
 	EnableLogs                  bool                   `yaml:"enableLogs"`
 	GaTranslation               FlatAddressTranslation `yaml:"translateFlatGroupAddresses"`
 	KNXLog                      KNXLogConfig           `yaml:"knxLog"`
+	Secure                      KNXSecureConfig        `yaml:"secure"`
+	SendQueue                   SendQueueConfig        `yaml:"sendQueue"`
+}
+
+// ETSExport is an ETS export file to import and, for project files with several installations,
+// the name of the installation. The first installation is imported if Installation is empty.
+type ETSExport struct {
+	File         string `yaml:"file"`
+	Installation string `yaml:"installation"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for ETSExport, accepting the file
+// name alone as well as a mapping with file and installation.
+func (e *ETSExport) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*e = ETSExport{}
+		return value.Decode(&e.File)
+	}
+	type plain ETSExport
+	var export plain
+	if err := value.Decode(&export); err != nil {
+		return err
+	}
+	if export.File == "" {
+		return fmt.Errorf("ETS export in line %d has no file", value.Line)
+	}
+	*e = ETSExport(export)
+	return nil
+}
+
+// ETSExports are the ETS export files merged into one set of group addresses.
+type ETSExports []ETSExport
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for ETSExports, accepting a single
+// export as well as a list.
+func (e *ETSExports) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.SequenceNode {
+		var export ETSExport
+		if err := value.Decode(&export); err != nil {
+			return err
+		}
+		*e = nil
+		if export.File != "" {
+			*e = ETSExports{export}
+		}
+		return nil
+	}
+	var exports []ETSExport
+	if err := value.Decode(&exports); err != nil {
+		return err
+	}
+	*e = exports
+	return nil
+}
+
+// Files returns the file names of the exports.
+func (e ETSExports) Files() []string {
+	files := make([]string, 0, len(e))
+	for _, export := range e {
+		files = append(files, export.File)
+	}
+	return files
+}
+
+// KNXSecureConfig represents the KNX IP Secure and Data Secure configuration section.
+type KNXSecureConfig struct {
+	Keyring           string        `yaml:"keyring"`           // Path to the ETS keyring export (.knxkeys)
+	KeyringPassword   string        `yaml:"keyringPassword"`   // Password the keyring was exported with
+	IPSecure          bool          `yaml:"ipSecure"`          // Connect using KNX IP Secure tunnelling or routing
+	TunnelAddress     string        `yaml:"tunnelAddress"`     // Individual address of the secure tunnel to use
+	IndividualAddress string        `yaml:"individualAddress"` // Source address of Data Secure telegrams, defaults to the tunnel address
+	StateFile         string        `yaml:"stateFile"`         // File keeping the Data Secure sequence numbers across restarts
+	PersistInterval   time.Duration `yaml:"persistInterval"`   // How often changes are written to the state file
+}
+
+// Enabled returns true if a keyring is configured.
+func (s KNXSecureConfig) Enabled() bool {
+	return s.Keyring != ""
+}
+
+// SendQueueConfig represents the configuration of the queue for telegrams sent to KNX.
+type SendQueueConfig struct {
+	TelegramsPerSecond float64        `yaml:"telegramsPerSecond"` // Maximum number of telegrams sent per second
+	MaxSize            int            `yaml:"maxSize"`            // Maximum number of waiting telegrams, the oldest of the lowest priority is dropped first
+	Priorities         []PriorityRule `yaml:"priorities"`
+}
+
+// PriorityRule assigns a priority to the group addresses matching any of the patterns.
+type PriorityRule struct {
+	Match    []string `yaml:"match"` // Glob patterns matching the address or full name, e.g. 1/2/* or Alarms/*/*
+	Priority Priority `yaml:"priority"`
+}
+
+// Priority decides the order in which queued telegrams are sent.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+	PriorityLow
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for Priority
+func (p *Priority) UnmarshalYAML(value *yaml.Node) error {
+	var stringValue string
+	if err := value.Decode(&stringValue); err != nil {
+		return fmt.Errorf("priority must be a string: %w", err)
+	}
+	switch strings.ToLower(stringValue) {
+	case "high":
+		*p = PriorityHigh
+	case "normal":
+		*p = PriorityNormal
+	case "low":
+		*p = PriorityLow
+	default:
+		return fmt.Errorf("invalid priority: %s", stringValue)
+	}
+	return nil
 }
 
 // MQTTConfig represents the MQTT configuration section.
@@ -69,3 +216,43 @@ type MQTTConfig struct {
 	Qos         byte    `yaml:"qos"`
 	Retain      bool    `yaml:"retain"`
 }
+
+// HomeAssistantConfig represents the Home Assistant MQTT discovery configuration section.
+type HomeAssistantConfig struct {
+	Discovery       bool   `yaml:"discovery"`       // Publish discovery messages for the imported group addresses
+	DiscoveryPrefix string `yaml:"discoveryPrefix"` // Discovery prefix configured in Home Assistant, defaults to homeassistant
+	NodeID          string `yaml:"nodeId"`          // Node ID used in discovery topics and unique IDs, defaults to knx_mqtt
+}
+
+// StateCacheConfig represents the state cache configuration section.
+type StateCacheConfig struct {
+	File            string        `yaml:"file"`            // Persist the cached values to this file, keeps them in memory only if empty
+	PersistInterval time.Duration `yaml:"persistInterval"` // How often changes are written to the file
+}
+
+// VirtualGroupAddress is a group address owned by the bridge, with its value fed from an MQTT topic.
+type VirtualGroupAddress struct {
+	Address   string `yaml:"address"`   // Group address, e.g. 1/2/3
+	Name      string `yaml:"name"`      // Name used when the address is not in the ETS export, defaults to the address
+	Datapoint string `yaml:"datapoint"` // Datapoint type, required when the address is not in the ETS export
+	Topic     string `yaml:"topic"`     // MQTT topic providing the value
+	Field     string `yaml:"field"`     // Dot separated path to the value if the payload is a JSON object
+}
+
+// ReadScheduleConfig represents the configuration of reads sent on connect and periodically.
+type ReadScheduleConfig struct {
+	ReadsPerSecond float64    `yaml:"readsPerSecond"` // Maximum number of reads sent per second
+	Rules          []ReadRule `yaml:"rules"`
+}
+
+// ReadRule selects group addresses of the ETS export to read.
+type ReadRule struct {
+	Match     []string      `yaml:"match"`     // Glob patterns matching the address or full name, e.g. 1/2/* or Lights/*/*
+	OnConnect bool          `yaml:"onConnect"` // Read when the connection to KNX is established
+	Interval  time.Duration `yaml:"interval"`  // Read periodically, disabled if zero
+}
+
+// MetricsConfig represents the Prometheus metrics configuration section.
+type MetricsConfig struct {
+	Listen string `yaml:"listen"` // Address to serve /metrics on, e.g. :9100, disabled if empty
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestETSExportsUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		yamlStr  string
+		expected ETSExports
+		wantErr  bool
+	}{
+		{"Single file", `etsExport: knx.xml`, ETSExports{{File: "knx.xml"}}, false},
+		{"Empty", `etsExport: ""`, nil, false},
+		{"List", "etsExport:\n  - a.knxproj\n  - file: b.knxproj\n    installation: Garage", ETSExports{{File: "a.knxproj"}, {File: "b.knxproj", Installation: "Garage"}}, false},
+		{"Single mapping", "etsExport:\n  file: b.knxproj\n  installation: Garage", ETSExports{{File: "b.knxproj", Installation: "Garage"}}, false},
+		{"Missing file", "etsExport:\n  - installation: Garage", nil, true},
+	}
+	for _, tt := range tests {
+		var config KNXConfig
+		err := yaml.Unmarshal([]byte(tt.yamlStr), &config)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Unmarshal() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(config.ETSExport, tt.expected) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, config.ETSExport, tt.expected)
+		}
+	}
+}
+
+func TestValidationPolicyUnmarshalYAML(t *testing.T) {
+	for yamlStr, expected := range map[string]ValidationPolicy{"warn": ValidationWarn, "Fail": ValidationFail, "disambiguate": ValidationDisambiguate} {
+		var policy ValidationPolicy
+		if err := yaml.Unmarshal([]byte(yamlStr), &policy); err != nil || policy != expected {
+			t.Errorf("Unmarshal(%q) = %v, %v, want %v", yamlStr, policy, err, expected)
+		}
+	}
+	var policy ValidationPolicy
+	if err := yaml.Unmarshal([]byte("ignore"), &policy); err == nil {
+		t.Errorf("expected an error for an invalid policy")
+	}
+}
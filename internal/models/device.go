@@ -0,0 +1,47 @@
+package models
+
+// Device is a device of the ETS project topology.
+type Device struct {
+	Address string // Individual address, e.g. 1.1.5
+	Name    string // Name of the device in ETS, or the product name if it has none
+	Product string // Product name of the manufacturer
+	// Sends are the group addresses the communication objects of the device send to and
+	// Receives the group addresses they listen on.
+	Sends    []FlatGroupAddress
+	Receives []FlatGroupAddress
+}
+
+// AddDevice adds device and records it as sender and listener of its group addresses.
+// Group addresses must be added before the devices linked to them.
+func (k *KNX) AddDevice(device Device) {
+	k.Devices = append(k.Devices, device)
+	k.DeviceToIndex[device.Address] = len(k.Devices) - 1
+	for _, address := range device.Sends {
+		if index, exists := k.GadToIndex[address]; exists {
+			k.GroupAddresses[index].Senders = appendUnique(k.GroupAddresses[index].Senders, device.Address)
+		}
+	}
+	for _, address := range device.Receives {
+		if index, exists := k.GadToIndex[address]; exists {
+			k.GroupAddresses[index].Listeners = appendUnique(k.GroupAddresses[index].Listeners, device.Address)
+		}
+	}
+}
+
+// GetDevice returns the device with the individual address, e.g. 1.1.5.
+func (k *KNX) GetDevice(address string) (*Device, bool) {
+	index, exists := k.DeviceToIndex[address]
+	if !exists {
+		return nil, false
+	}
+	return &k.Devices[index], true
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"fmt"
+	"path"
 	"strconv"
 	"strings"
 
@@ -18,37 +19,101 @@ type GroupAddress struct {
 	Address     string
 	FlatAddress FlatGroupAddress
 	Datapoint   string
+
+	// Location of the group address in the building structure of the ETS project, from the
+	// building down to the room, and the name of the function using it. LocationName is made
+	// of these and the name, e.g. House/Ground floor/Kitchen/Ceiling light/Switch.
+	Location     []string
+	Function     string
+	LocationName string
+
+	// Individual addresses of the devices sending to and listening on the group address.
+	Senders   []string
+	Listeners []string
+
+	// Settings from the overrides in the configuration.
+	Retain    *bool
+	Qos       *byte
+	ReadOnly  bool
+	Ignore    bool
+	Unit      string
+	Transform *ValueTransform
+}
+
+// Matches reports whether the address or the full name matches the glob pattern. A * matches
+// within one level of the address or name, e.g. 1/2/* or Lights/*/*.
+func (ga GroupAddress) Matches(pattern string) bool {
+	if ok, _ := path.Match(pattern, ga.Address); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, ga.FullName)
+	return ok
 }
 
 type KNX struct {
-	NameToIndex    map[string]int
+	NameToIndex     map[string]int
+	LocationToIndex map[string]int
+	// AmbiguousNames are the full and location names shared by several group addresses,
+	// which cannot be used to look up a group address.
+	AmbiguousNames map[string]bool
 	GadToIndex     map[FlatGroupAddress]int
 	GroupAddresses []GroupAddress
+	DeviceToIndex  map[string]int
+	Devices        []Device
+	// Validation lists the problems found while importing the ETS export.
+	Validation ValidationReport
 }
 
 func EmptyKNX() KNX {
 	return KNX{
-		NameToIndex:    make(map[string]int),
-		GadToIndex:     make(map[FlatGroupAddress]int),
-		GroupAddresses: []GroupAddress{},
+		NameToIndex:     make(map[string]int),
+		LocationToIndex: make(map[string]int),
+		AmbiguousNames:  make(map[string]bool),
+		GadToIndex:      make(map[FlatGroupAddress]int),
+		GroupAddresses:  []GroupAddress{},
+		DeviceToIndex:   make(map[string]int),
+		Devices:         []Device{},
 	}
 }
 
 func (k *KNX) AddGroupAddress(groupAddress GroupAddress) {
 	k.GroupAddresses = append(k.GroupAddresses, groupAddress)
 	index := len(k.GroupAddresses) - 1
-	k.NameToIndex[groupAddress.FullName] = index
+	k.addName(k.NameToIndex, groupAddress.FullName, index)
+	if groupAddress.LocationName != "" {
+		k.addName(k.LocationToIndex, groupAddress.LocationName, index)
+	}
 	k.GadToIndex[groupAddress.FlatAddress] = index
 }
 
+// addName indexes the full or location name of the group address at index. A name already
+// used by another group address is marked as ambiguous instead.
+func (k *KNX) addName(names map[string]int, name string, index int) {
+	for _, existing := range []map[string]int{k.NameToIndex, k.LocationToIndex} {
+		if other, exists := existing[name]; exists && other != index {
+			k.AmbiguousNames[name] = true
+		}
+	}
+	if !k.AmbiguousNames[name] {
+		names[name] = index
+	}
+}
+
+// IsAmbiguous reports whether name is shared by several group addresses.
+func (k *KNX) IsAmbiguous(name string) bool {
+	return k.AmbiguousNames[name]
+}
+
 func (k *KNX) GetGroupAddress(address string) (*GroupAddress, bool) {
 	var index int
 	var exists bool
 	if utils.IsRegularOrFlatGroupAddress(address) {
 		flatAddr, _ := ParseGroupAddress(address)
 		index, exists = k.GadToIndex[flatAddr]
-	} else {
-		index, exists = k.NameToIndex[address]
+	} else if k.AmbiguousNames[address] {
+		return nil, false
+	} else if index, exists = k.NameToIndex[address]; !exists {
+		index, exists = k.LocationToIndex[address]
 	}
 	if !exists {
 		return nil, false
@@ -56,6 +121,130 @@ func (k *KNX) GetGroupAddress(address string) (*GroupAddress, bool) {
 	return &k.GroupAddresses[index], true
 }
 
+// AddVirtualGroupAddresses adds the virtual group addresses that are missing from the ETS export.
+func (k *KNX) AddVirtualGroupAddresses(virtual []VirtualGroupAddress) error {
+	for _, v := range virtual {
+		if v.Topic == "" {
+			return fmt.Errorf("virtual group address %s has no topic", v.Address)
+		}
+		if _, exists := k.GetGroupAddress(v.Address); exists {
+			continue
+		}
+		if !utils.IsRegularGroupAddress(v.Address) {
+			return fmt.Errorf("virtual group address %q is not a three level group address", v.Address)
+		}
+		if v.Datapoint == "" {
+			return fmt.Errorf("virtual group address %s is not in the ETS export and has no datapoint", v.Address)
+		}
+		flatAddress, err := ParseGroupAddress(v.Address)
+		if err != nil {
+			return err
+		}
+		name := v.Name
+		if name == "" {
+			name = v.Address
+		}
+		k.AddGroupAddress(GroupAddress{
+			FullName:    name,
+			Name:        name,
+			Address:     v.Address,
+			FlatAddress: flatAddress,
+			Datapoint:   v.Datapoint,
+		})
+	}
+	return nil
+}
+
+// KNXDiff lists the group addresses that differ between two imports of the ETS export.
+type KNXDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// IsEmpty reports whether no group address differs.
+func (d KNXDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares k with next. Group addresses are identified by their flat address and are
+// changed if their address, names, datapoint or overridden settings differ.
+func (k *KNX) Diff(next *KNX) KNXDiff {
+	diff := KNXDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	for _, ga := range next.GroupAddresses {
+		index, exists := k.GadToIndex[ga.FlatAddress]
+		if !exists {
+			diff.Added = append(diff.Added, ga.Address)
+		} else if !k.GroupAddresses[index].sameDefinition(ga) {
+			diff.Changed = append(diff.Changed, ga.Address)
+		}
+	}
+	for _, ga := range k.GroupAddresses {
+		if _, exists := next.GadToIndex[ga.FlatAddress]; !exists {
+			diff.Removed = append(diff.Removed, ga.Address)
+		}
+	}
+	return diff
+}
+
+// sameDefinition reports whether ga and other have the same address, names, datapoint and
+// settings from the overrides.
+func (ga GroupAddress) sameDefinition(other GroupAddress) bool {
+	return ga.Address == other.Address && ga.FullName == other.FullName && ga.Name == other.Name &&
+		ga.LocationName == other.LocationName && ga.Datapoint == other.Datapoint &&
+		equalPointers(ga.Retain, other.Retain) && equalPointers(ga.Qos, other.Qos) &&
+		ga.ReadOnly == other.ReadOnly && ga.Ignore == other.Ignore && ga.Unit == other.Unit &&
+		equalPointers(ga.Transform, other.Transform)
+}
+
+// equalPointers reports whether a and b are both nil or point to equal values.
+func equalPointers[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Merge adds the group addresses and devices of other. A group address in both must have the
+// same full name and datapoint, otherwise all conflicting group addresses are returned as an
+// error and nothing is added. Devices already present are kept.
+func (k *KNX) Merge(other *KNX) error {
+	var conflicts []string
+	for _, ga := range other.GroupAddresses {
+		index, exists := k.GadToIndex[ga.FlatAddress]
+		if !exists {
+			continue
+		}
+		if existing := k.GroupAddresses[index]; existing.FullName != ga.FullName || existing.Datapoint != ga.Datapoint {
+			conflicts = append(conflicts, fmt.Sprintf("%s is %q (%s) and %q (%s)",
+				ga.Address, existing.FullName, existing.Datapoint, ga.FullName, ga.Datapoint))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("conflicting definitions of group addresses: %s", strings.Join(conflicts, "; "))
+	}
+
+	for _, ga := range other.GroupAddresses {
+		if index, exists := k.GadToIndex[ga.FlatAddress]; exists {
+			existing := &k.GroupAddresses[index]
+			for _, sender := range ga.Senders {
+				existing.Senders = appendUnique(existing.Senders, sender)
+			}
+			for _, listener := range ga.Listeners {
+				existing.Listeners = appendUnique(existing.Listeners, listener)
+			}
+			continue
+		}
+		k.AddGroupAddress(ga)
+	}
+	for _, device := range other.Devices {
+		if _, exists := k.DeviceToIndex[device.Address]; !exists {
+			k.AddDevice(device)
+		}
+	}
+	return nil
+}
+
 func (k *KNX) Is(address GroupAddress) error {
 	if address.Name == "" {
 		return errors.New("address name cannot be empty")
@@ -162,6 +351,8 @@ const (
 	FAT_None FlatAddressTranslation = iota
 	FAT_2_parts
 	FAT_3_parts
+	// FAT_Auto translates according to the group address style of the ETS project
+	FAT_Auto
 )
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface for FlatAddressTranslation
@@ -196,6 +387,8 @@ func (fat *FlatAddressTranslation) UnmarshalYAML(value *yaml.Node) error {
 		*fat = FAT_2_parts
 	case "3-part", "three-part":
 		*fat = FAT_3_parts
+	case "auto", "project":
+		*fat = FAT_Auto
 	default:
 		return fmt.Errorf("invalid FlatAddressTranslation string value: %s", stringValue)
 	}
@@ -203,6 +396,20 @@ func (fat *FlatAddressTranslation) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// AddressStyleTranslation returns the translation matching an ETS group address style
+// (ThreeLevel, TwoLevel or Free).
+func AddressStyleTranslation(style string) (FlatAddressTranslation, bool) {
+	switch style {
+	case "ThreeLevel":
+		return FAT_3_parts, true
+	case "TwoLevel":
+		return FAT_2_parts, true
+	case "Free":
+		return FAT_None, true
+	}
+	return FAT_None, false
+}
+
 func TranslateFlatAddress(fga FlatGroupAddress, translation FlatAddressTranslation) string {
 	// Convert the flat address to uint16 for bit manipulation
 	flatAddr := uint16(fga)
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -152,3 +153,186 @@ func TestParseGroupAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestGetGroupAddress(t *testing.T) {
+	knx := EmptyKNX()
+	knx.AddGroupAddress(GroupAddress{Name: "Light", FullName: "Main/Middle/Light", Address: "1/2/3", FlatAddress: 0x0a03, Datapoint: "1.001"})
+	knx.AddGroupAddress(GroupAddress{Name: "Blind", FullName: "Main/Blind", Address: "1/600", FlatAddress: 2648, Datapoint: "1.008"})
+	knx.AddGroupAddress(GroupAddress{Name: "Heating", FullName: "Heating", Address: "4097", FlatAddress: 4097, Datapoint: "5.001"})
+
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"1/2/3", "Main/Middle/Light"},
+		{"2563", "Main/Middle/Light"},
+		{"1/600", "Main/Blind"},
+		{"1/2/88", "Main/Blind"},
+		{"4097", "Heating"},
+		{"2/1", "Heating"},
+		{"Main/Blind", "Main/Blind"},
+	}
+	for _, tt := range tests {
+		ga, ok := knx.GetGroupAddress(tt.address)
+		if !ok || ga.FullName != tt.want {
+			t.Errorf("GetGroupAddress(%q) = %+v, %v, want %s", tt.address, ga, ok, tt.want)
+		}
+	}
+	if _, ok := knx.GetGroupAddress("1/2/4"); ok {
+		t.Errorf("GetGroupAddress(1/2/4) found a group address")
+	}
+}
+
+func TestAmbiguousNames(t *testing.T) {
+	knx := EmptyKNX()
+	knx.AddGroupAddress(GroupAddress{FullName: "Lights/Hall", LocationName: "House/Hall/Light", Address: "1/0/1", FlatAddress: 2049})
+	knx.AddGroupAddress(GroupAddress{FullName: "Lights/Hall", LocationName: "House/Hall/Spot", Address: "1/0/2", FlatAddress: 2050})
+	knx.AddGroupAddress(GroupAddress{FullName: "Lights/Porch", LocationName: "House/Hall/Light", Address: "1/0/3", FlatAddress: 2051})
+
+	for _, name := range []string{"Lights/Hall", "House/Hall/Light"} {
+		if !knx.IsAmbiguous(name) {
+			t.Errorf("expected %s to be ambiguous", name)
+		}
+		if ga, ok := knx.GetGroupAddress(name); ok {
+			t.Errorf("GetGroupAddress(%s) = %+v, want no group address", name, ga)
+		}
+	}
+	if ga, ok := knx.GetGroupAddress("House/Hall/Spot"); !ok || ga.Address != "1/0/2" {
+		t.Errorf("GetGroupAddress(House/Hall/Spot) = %+v, %v", ga, ok)
+	}
+
+	knx.Disambiguate()
+	for name, address := range map[string]string{
+		"Lights/Hall_1_0_1":      "1/0/1",
+		"Lights/Hall_1_0_2":      "1/0/2",
+		"House/Hall/Light_1_0_3": "1/0/3",
+		"Lights/Porch":           "1/0/3",
+	} {
+		if ga, ok := knx.GetGroupAddress(name); !ok || ga.Address != address {
+			t.Errorf("GetGroupAddress(%s) = %+v, %v, want %s", name, ga, ok, address)
+		}
+	}
+}
+
+func TestAddVirtualGroupAddresses(t *testing.T) {
+	knx := EmptyKNX()
+	knx.AddGroupAddress(GroupAddress{Name: "Light", FullName: "Main/Middle/Light", Address: "1/2/3", FlatAddress: 0x0a03, Datapoint: "1.001"})
+
+	err := knx.AddVirtualGroupAddresses([]VirtualGroupAddress{
+		{Address: "1/2/3", Topic: "zigbee/switch"},
+		{Address: "5/0/1", Name: "Outdoor temperature", Datapoint: "9.001", Topic: "weather"},
+	})
+	if err != nil {
+		t.Fatalf("AddVirtualGroupAddresses() error = %v", err)
+	}
+	if len(knx.GroupAddresses) != 2 {
+		t.Fatalf("got %d group addresses, want 2", len(knx.GroupAddresses))
+	}
+	ga, ok := knx.GetGroupAddress("5/0/1")
+	if !ok || ga.Name != "Outdoor temperature" || ga.Datapoint != "9.001" {
+		t.Errorf("GetGroupAddress(5/0/1) = %+v, %v", ga, ok)
+	}
+
+	for _, invalid := range []VirtualGroupAddress{
+		{Address: "5/0/2", Topic: "weather"},
+		{Address: "5/0/3", Datapoint: "9.001"},
+		{Address: "Outdoor", Datapoint: "9.001", Topic: "weather"},
+	} {
+		if err := knx.AddVirtualGroupAddresses([]VirtualGroupAddress{invalid}); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestGroupAddressMatches(t *testing.T) {
+	ga := GroupAddress{Name: "Light", FullName: "Lights/Kitchen/Light", Address: "1/2/3"}
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"1/2/3", true},
+		{"1/2/*", true},
+		{"1/*/*", true},
+		{"1/*", false},
+		{"2/*/*", false},
+		{"Lights/Kitchen/*", true},
+		{"Lights/*/Light", true},
+		{"Lights/*", false},
+	}
+	for _, tt := range tests {
+		if got := ga.Matches(tt.pattern); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestKNXDiff(t *testing.T) {
+	previous := EmptyKNX()
+	previous.AddGroupAddress(GroupAddress{FullName: "Lights/Hall", Address: "1/0/1", FlatAddress: 2049, Datapoint: "1.001"})
+	previous.AddGroupAddress(GroupAddress{FullName: "Lights/Kitchen", Address: "1/0/2", FlatAddress: 2050, Datapoint: "1.001"})
+	previous.AddGroupAddress(GroupAddress{FullName: "Lights/Porch", Address: "1/0/3", FlatAddress: 2051, Datapoint: "1.001"})
+
+	next := EmptyKNX()
+	next.AddGroupAddress(GroupAddress{FullName: "Lights/Hall", Address: "1/0/1", FlatAddress: 2049, Datapoint: "1.001"})
+	next.AddGroupAddress(GroupAddress{FullName: "Lights/Kitchen", Address: "1/0/2", FlatAddress: 2050, Datapoint: "5.001"})
+	next.AddGroupAddress(GroupAddress{FullName: "Lights/Garage", Address: "1/0/4", FlatAddress: 2052, Datapoint: "1.001"})
+
+	diff := previous.Diff(&next)
+	if !reflect.DeepEqual(diff, KNXDiff{Added: []string{"1/0/4"}, Removed: []string{"1/0/3"}, Changed: []string{"1/0/2"}}) {
+		t.Errorf("Diff() = %+v", diff)
+	}
+
+	retain, qos := true, byte(1)
+	overridden := EmptyKNX()
+	overridden.AddGroupAddress(GroupAddress{FullName: "Lights/Hall", Address: "1/0/1", FlatAddress: 2049, Datapoint: "1.001", Retain: &retain})
+	overridden.AddGroupAddress(GroupAddress{FullName: "Lights/Kitchen", Address: "1/0/2", FlatAddress: 2050, Datapoint: "5.001", Qos: &qos})
+	overridden.AddGroupAddress(GroupAddress{FullName: "Lights/Garage", Address: "1/0/4", FlatAddress: 2052, Datapoint: "1.001",
+		Transform: &ValueTransform{Scale: 2}})
+	diff = next.Diff(&overridden)
+	if !reflect.DeepEqual(diff, KNXDiff{Added: []string{}, Removed: []string{}, Changed: []string{"1/0/1", "1/0/2", "1/0/4"}}) {
+		t.Errorf("Diff() = %+v", diff)
+	}
+	sameRetain, sameTransform := true, ValueTransform{Scale: 2}
+	again := EmptyKNX()
+	again.AddGroupAddress(GroupAddress{FullName: "Lights/Hall", Address: "1/0/1", FlatAddress: 2049, Datapoint: "1.001", Retain: &sameRetain})
+	again.AddGroupAddress(GroupAddress{FullName: "Lights/Kitchen", Address: "1/0/2", FlatAddress: 2050, Datapoint: "5.001", Qos: &qos})
+	again.AddGroupAddress(GroupAddress{FullName: "Lights/Garage", Address: "1/0/4", FlatAddress: 2052, Datapoint: "1.001", Transform: &sameTransform})
+	if diff := overridden.Diff(&again); !diff.IsEmpty() {
+		t.Errorf("Diff() = %+v", diff)
+	}
+	if !previous.Diff(&previous).IsEmpty() {
+		t.Errorf("expected no difference to itself")
+	}
+}
+
+func TestKNXMerge(t *testing.T) {
+	first := EmptyKNX()
+	first.AddGroupAddress(GroupAddress{FullName: "Lights/Hall", Address: "1/0/1", FlatAddress: 2049, Datapoint: "1.001"})
+	first.AddDevice(Device{Address: "1.1.1", Name: "Hall push button", Sends: []FlatGroupAddress{2049}})
+
+	second := EmptyKNX()
+	second.AddGroupAddress(GroupAddress{FullName: "Lights/Hall", Address: "1/0/1", FlatAddress: 2049, Datapoint: "1.001"})
+	second.AddGroupAddress(GroupAddress{FullName: "Lights/Garage", Address: "1/0/4", FlatAddress: 2052, Datapoint: "1.001"})
+	second.AddDevice(Device{Address: "1.2.1", Name: "Garage actuator", Receives: []FlatGroupAddress{2049, 2052}})
+
+	if err := first.Merge(&second); err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(first.GroupAddresses) != 2 || len(first.Devices) != 2 {
+		t.Errorf("got %d group addresses and %d devices, want 2 and 2", len(first.GroupAddresses), len(first.Devices))
+	}
+	hall, _ := first.GetGroupAddress("1/0/1")
+	if !reflect.DeepEqual(hall.Senders, []string{"1.1.1"}) || !reflect.DeepEqual(hall.Listeners, []string{"1.2.1"}) {
+		t.Errorf("got senders %v and listeners %v", hall.Senders, hall.Listeners)
+	}
+
+	conflicting := EmptyKNX()
+	conflicting.AddGroupAddress(GroupAddress{FullName: "Lights/Porch", Address: "1/0/5", FlatAddress: 2053, Datapoint: "1.001"})
+	conflicting.AddGroupAddress(GroupAddress{FullName: "Lights/Garage", Address: "1/0/4", FlatAddress: 2052, Datapoint: "5.001"})
+	if err := first.Merge(&conflicting); err == nil {
+		t.Errorf("expected a conflict for 1/0/4")
+	}
+	if _, exists := first.GetGroupAddress("1/0/5"); exists {
+		t.Errorf("expected nothing to be merged after a conflict")
+	}
+}
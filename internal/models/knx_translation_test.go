@@ -73,6 +73,12 @@ func TestFlatAddressTranslationUnmarshalYAML(t *testing.T) {
 			expected: FAT_3_parts,
 			wantErr:  false,
 		},
+		{
+			name:     "String 'auto'",
+			yamlStr:  "auto",
+			expected: FAT_Auto,
+			wantErr:  false,
+		},
 		{
 			name:     "Case insensitive 'NONE'",
 			yamlStr:  "NONE",
@@ -7,4 +7,19 @@ type OutgoingMqttJson struct {
 	Unit    *string `json:"unit,omitempty"`
 	Command string  `json:"command"`
 	Source  string  `json:"source"`
+	// SourceName is the name of the device with the source address in the ETS project.
+	SourceName string `json:"sourceName,omitempty"`
+	// Timestamp is when a value answered from the state cache was seen on the bus.
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+const KNXConnected = "connected"
+const KNXReconnecting = "reconnecting"
+const KNXFailed = "failed"
+
+// KNXStatus is the state of the connection to KNX published on <topicPrefix>bridge/knx.
+type KNXStatus struct {
+	State    string `json:"state"`
+	Endpoint string `json:"endpoint"`
+	Error    string `json:"error,omitempty"`
 }
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// GroupAddressOverride changes the group addresses matching any of the Match patterns after
+// importing the ETS export. When several overrides match, later ones take precedence.
+type GroupAddressOverride struct {
+	Match     []string        `yaml:"match"`     // Addresses or full names, see GroupAddress.Matches
+	Datapoint string          `yaml:"datapoint"` // Datapoint type, e.g. 9.001
+	Alias     string          `yaml:"alias"`     // Full name used in topics instead of the name from ETS
+	Retain    *bool           `yaml:"retain"`    // Retain flag of published messages
+	Qos       *byte           `yaml:"qos"`       // QoS of published messages
+	Writable  *bool           `yaml:"writable"`  // Whether writes from MQTT are sent to KNX
+	Ignore    bool            `yaml:"ignore"`    // Neither publish nor write the group address
+	Unit      string          `yaml:"unit"`      // Unit published instead of the datapoint's unit
+	Transform *ValueTransform `yaml:"transform"` // Conversion of numeric values
+}
+
+// ValueTransform converts numeric values: the value published to MQTT is the KNX value
+// multiplied by Scale plus Offset. Values written from MQTT are converted back.
+type ValueTransform struct {
+	Scale  float64 `yaml:"scale"`
+	Offset float64 `yaml:"offset"`
+}
+
+// ApplyOverrides applies the overrides matching ga in order.
+func ApplyOverrides(ga *GroupAddress, overrides []GroupAddressOverride) {
+	for _, override := range overrides {
+		for _, pattern := range override.Match {
+			if ga.Matches(pattern) {
+				override.apply(ga)
+				break
+			}
+		}
+	}
+}
+
+func (o GroupAddressOverride) apply(ga *GroupAddress) {
+	if o.Datapoint != "" {
+		ga.Datapoint = o.Datapoint
+	}
+	if o.Alias != "" {
+		ga.FullName = o.Alias
+	}
+	if o.Retain != nil {
+		ga.Retain = o.Retain
+	}
+	if o.Qos != nil {
+		ga.Qos = o.Qos
+	}
+	if o.Writable != nil {
+		ga.ReadOnly = !*o.Writable
+	}
+	if o.Ignore {
+		ga.Ignore = true
+	}
+	if o.Unit != "" {
+		ga.Unit = o.Unit
+	}
+	if o.Transform != nil {
+		ga.Transform = o.Transform
+	}
+}
+
+func (t *ValueTransform) scale() float64 {
+	if t.Scale == 0 {
+		return 1
+	}
+	return t.Scale
+}
+
+// Apply converts a value decoded from KNX. Values that are not numeric are returned unchanged.
+func (t *ValueTransform) Apply(value interface{}) interface{} {
+	if t == nil {
+		return value
+	}
+	var number float64
+	switch v := value.(type) {
+	case float32:
+		// Go through the shortest representation to avoid e.g. 21.1 turning into 21.100000381.
+		number, _ = strconv.ParseFloat(strconv.FormatFloat(float64(v), 'f', -1, 32), 64)
+	case float64:
+		number = v
+	case int8:
+		number = float64(v)
+	case int16:
+		number = float64(v)
+	case int32:
+		number = float64(v)
+	case int64:
+		number = float64(v)
+	case uint8:
+		number = float64(v)
+	case uint16:
+		number = float64(v)
+	case uint32:
+		number = float64(v)
+	default:
+		return value
+	}
+	return number*t.scale() + t.Offset
+}
+
+// Reverse converts a numeric value written from MQTT back to the value sent to KNX.
+func (t *ValueTransform) Reverse(value string) (string, error) {
+	if t == nil {
+		return value, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", fmt.Errorf("cannot transform non-numeric value %q", value)
+	}
+	return strconv.FormatFloat((number-t.Offset)/t.scale(), 'f', -1, 64), nil
+}
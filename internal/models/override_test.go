@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	retain := false
+	writable := false
+	overrides := []GroupAddressOverride{
+		{Match: []string{"Heating/*/*"}, Datapoint: "9.001", Retain: &retain, Unit: "C"},
+		{Match: []string{"1/2/3"}, Alias: "Heating/Living/Temperature", Writable: &writable, Unit: "°C"},
+		{Match: []string{"Lights/*/*"}, Ignore: true},
+	}
+
+	ga := GroupAddress{FullName: "Heating/Living/Temp", Address: "1/2/3"}
+	ApplyOverrides(&ga, overrides)
+	if ga.Datapoint != "9.001" || ga.FullName != "Heating/Living/Temperature" || ga.Unit != "°C" {
+		t.Errorf("unexpected group address %+v", ga)
+	}
+	if ga.Retain == nil || *ga.Retain || !ga.ReadOnly || ga.Ignore {
+		t.Errorf("unexpected flags %+v", ga)
+	}
+
+	other := GroupAddress{FullName: "Other/Group/Address", Address: "4/0/0", Datapoint: "1.001"}
+	ApplyOverrides(&other, overrides)
+	if other.Datapoint != "1.001" || other.Retain != nil || other.ReadOnly {
+		t.Errorf("override applied to non-matching group address: %+v", other)
+	}
+}
+
+func TestValueTransform(t *testing.T) {
+	transform := &ValueTransform{Scale: 0.1, Offset: -5}
+	if got := transform.Apply(float32(21.5)); got != 21.5*0.1-5 {
+		t.Errorf("Apply(21.5) = %v", got)
+	}
+	if got := transform.Apply(uint8(200)); got != 200*0.1-5 {
+		t.Errorf("Apply(200) = %v", got)
+	}
+	if got := transform.Apply("Comfort"); got != "Comfort" {
+		t.Errorf("Apply() changed a non-numeric value to %v", got)
+	}
+	if got, err := transform.Reverse("15"); err != nil || got != "200" {
+		t.Errorf("Reverse(15) = %q, %v", got, err)
+	}
+	if _, err := transform.Reverse("on"); err == nil {
+		t.Errorf("expected an error for a non-numeric value")
+	}
+
+	var none *ValueTransform
+	if got := none.Apply(float32(1.5)); got != float32(1.5) {
+		t.Errorf("nil transform changed the value to %v", got)
+	}
+}
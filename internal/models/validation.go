@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationPolicy decides what happens when importing the ETS export finds problems.
+type ValidationPolicy int
+
+const (
+	// ValidationWarn logs the problems and imports the remaining group addresses.
+	ValidationWarn ValidationPolicy = iota
+	// ValidationFail refuses to import the ETS export.
+	ValidationFail
+	// ValidationDisambiguate appends the address to the full names shared by several group
+	// addresses and logs the other problems.
+	ValidationDisambiguate
+)
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for ValidationPolicy
+func (p *ValidationPolicy) UnmarshalYAML(value *yaml.Node) error {
+	var stringValue string
+	if err := value.Decode(&stringValue); err != nil {
+		return fmt.Errorf("validation policy must be a string: %w", err)
+	}
+	switch strings.ToLower(stringValue) {
+	case "warn", "":
+		*p = ValidationWarn
+	case "fail":
+		*p = ValidationFail
+	case "disambiguate":
+		*p = ValidationDisambiguate
+	default:
+		return fmt.Errorf("invalid validation policy: %s", stringValue)
+	}
+	return nil
+}
+
+// ValidationReport lists the problems found while importing the ETS export.
+type ValidationReport struct {
+	DuplicateNames        []DuplicateName        `json:"duplicateNames,omitempty"`
+	DuplicateAddresses    []DuplicateAddress     `json:"duplicateAddresses,omitempty"`
+	UnsupportedDatapoints []UnsupportedDatapoint `json:"unsupportedDatapoints,omitempty"`
+}
+
+// DuplicateName is a full name shared by several group addresses.
+type DuplicateName struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses"`
+}
+
+// DuplicateAddress is a group address defined more than once. Only the first definition is imported.
+type DuplicateAddress struct {
+	Address string   `json:"address"`
+	Names   []string `json:"names"`
+}
+
+// UnsupportedDatapoint is a group address with a datapoint type the bridge cannot convert.
+type UnsupportedDatapoint struct {
+	Address   string `json:"address"`
+	Name      string `json:"name"`
+	Datapoint string `json:"datapoint"`
+}
+
+// IsEmpty reports whether no problems were found.
+func (r ValidationReport) IsEmpty() bool {
+	return len(r.DuplicateNames) == 0 && len(r.DuplicateAddresses) == 0 && len(r.UnsupportedDatapoints) == 0
+}
+
+// String summarizes the number of problems of each kind.
+func (r ValidationReport) String() string {
+	return fmt.Sprintf("%d duplicate names, %d duplicate addresses, %d unsupported datapoint types",
+		len(r.DuplicateNames), len(r.DuplicateAddresses), len(r.UnsupportedDatapoints))
+}
+
+// DuplicateNames returns the full and location names shared by several group addresses, sorted by name.
+func (k *KNX) DuplicateNames() []DuplicateName {
+	addresses := make(map[string][]string)
+	for _, ga := range k.GroupAddresses {
+		addresses[ga.FullName] = append(addresses[ga.FullName], ga.Address)
+		if ga.LocationName != "" && ga.LocationName != ga.FullName {
+			addresses[ga.LocationName] = append(addresses[ga.LocationName], ga.Address)
+		}
+	}
+	var duplicates []DuplicateName
+	for name, gas := range addresses {
+		if len(gas) > 1 {
+			duplicates = append(duplicates, DuplicateName{Name: name, Addresses: gas})
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].Name < duplicates[j].Name })
+	return duplicates
+}
+
+// Disambiguate appends the address to the full and location names shared with another group
+// address, e.g. Lights/Hall_1_0_1, so that each can be written to by name.
+func (k *KNX) Disambiguate() {
+	duplicates := make(map[string]bool)
+	for _, duplicate := range k.DuplicateNames() {
+		duplicates[duplicate.Name] = true
+	}
+	if len(duplicates) == 0 {
+		return
+	}
+	groupAddresses := k.GroupAddresses
+	k.GroupAddresses = make([]GroupAddress, 0, len(groupAddresses))
+	k.NameToIndex = make(map[string]int, len(groupAddresses))
+	k.LocationToIndex = make(map[string]int, len(groupAddresses))
+	k.AmbiguousNames = make(map[string]bool)
+	for _, ga := range groupAddresses {
+		suffix := "_" + strings.ReplaceAll(ga.Address, "/", "_")
+		if duplicates[ga.FullName] {
+			ga.FullName += suffix
+		}
+		if duplicates[ga.LocationName] {
+			ga.LocationName += suffix
+		}
+		k.AddGroupAddress(ga)
+	}
+}
@@ -24,6 +24,79 @@ type XmlProject struct {
 type XmlInstallation struct {
 	Name           string `xml:",attr"`
 	GroupAddresses XmlGroupAddresses
+	Locations      []XmlSpace `xml:"Locations>Space"`        // ETS 5 and later
+	Buildings      []XmlSpace `xml:"Buildings>BuildingPart"` // ETS 4
+	Trades         []XmlTrade `xml:"Trades>Trade"`
+	Topology       XmlTopology
+}
+
+// XmlTopology represents the Topology element with the areas, lines and devices
+type XmlTopology struct {
+	Areas []XmlArea `xml:"Area"`
+}
+
+// XmlArea represents an area of the topology
+type XmlArea struct {
+	Name    string    `xml:"Name,attr"`
+	Address string    `xml:"Address,attr"`
+	Lines   []XmlLine `xml:"Line"`
+}
+
+// XmlLine represents a line of an area. Devices are placed in segments since ETS 6
+type XmlLine struct {
+	Name     string              `xml:"Name,attr"`
+	Address  string              `xml:"Address,attr"`
+	Devices  []XmlDeviceInstance `xml:"DeviceInstance"`
+	Segments []struct {
+		Devices []XmlDeviceInstance `xml:"DeviceInstance"`
+	} `xml:"Segment"`
+}
+
+// XmlDeviceInstance represents a device of a line
+type XmlDeviceInstance struct {
+	Name         string                    `xml:"Name,attr"`
+	Address      string                    `xml:"Address,attr"` // Device part of the individual address
+	ProductRefID string                    `xml:"ProductRefId,attr"`
+	ComObjects   []XmlComObjectInstanceRef `xml:"ComObjectInstanceRefs>ComObjectInstanceRef"`
+}
+
+// XmlComObjectInstanceRef represents a communication object of a device and its group addresses
+type XmlComObjectInstanceRef struct {
+	Links   string         `xml:"Links,attr"` // Ids of the group addresses since ETS 5.7, the first one is sent to
+	Send    []XmlConnector `xml:"Connectors>Send"`
+	Receive []XmlConnector `xml:"Connectors>Receive"`
+}
+
+// XmlConnector references the group address a communication object sends to or receives from
+type XmlConnector struct {
+	GroupAddressRefID string `xml:"GroupAddressRefId,attr"`
+}
+
+// XmlSpace represents a building, floor, room or other part of a building
+type XmlSpace struct {
+	Type          string        `xml:"Type,attr"` // Building, Floor, Room, Corridor, ...
+	Name          string        `xml:"Name,attr"`
+	Spaces        []XmlSpace    `xml:"Space"`
+	BuildingParts []XmlSpace    `xml:"BuildingPart"` // Nested spaces in ETS 4
+	Functions     []XmlFunction `xml:"Function"`
+}
+
+// XmlTrade represents a trade, grouping functions independent of the building structure
+type XmlTrade struct {
+	Name      string        `xml:"Name,attr"`
+	Trades    []XmlTrade    `xml:"Trade"`
+	Functions []XmlFunction `xml:"Function"`
+}
+
+// XmlFunction represents a function, e.g. a dimmable light, and the group addresses it uses
+type XmlFunction struct {
+	Name           string               `xml:"Name,attr"`
+	GroupAddresses []XmlGroupAddressRef `xml:"GroupAddressRef"`
+}
+
+// XmlGroupAddressRef references a group address by its Id
+type XmlGroupAddressRef struct {
+	RefID string `xml:"RefId,attr"`
 }
 
 // XmlGroupAddresses represents the GroupAddresses element
@@ -33,7 +106,8 @@ type XmlGroupAddresses struct {
 
 // XmlGroupAddressExport represents the GroupRanges element for direct export format
 type XmlGroupAddressExport struct {
-	GroupRanges []XmlGroupRange `xml:"GroupRange"`
+	GroupRanges []XmlGroupRange   `xml:"GroupRange"`
+	Addresses   []XmlGroupAddress `xml:"GroupAddress"` // Group addresses outside of any range
 }
 
 type XmlGroupRange struct {
@@ -43,9 +117,38 @@ type XmlGroupRange struct {
 }
 
 type XmlGroupAddress struct {
+	ID            string `xml:"Id,attr,omitempty"` // Id referenced by functions in ETS exports
 	Name          string `xml:"Name,attr"`
 	Address       string `xml:"Address,attr"`
 	DPTs          string `xml:"DPTs,attr,omitempty"`          // DPTs is the attribute's name in group address exports
 	DatapointType string `xml:"DatapointType,attr,omitempty"` // DatapointType is the attribute's name in ETS exports
 	Description   string `xml:"Description,attr,omitempty"`
 }
+
+// XmlProjectFile represents the project.xml file of an ETS project export
+type XmlProjectFile struct {
+	XMLName xml.Name `xml:"KNX"`
+	Project struct {
+		Information XmlProjectInformation `xml:"ProjectInformation"`
+	} `xml:"Project"`
+}
+
+// XmlProjectInformation represents the ProjectInformation element
+type XmlProjectInformation struct {
+	Name              string `xml:"Name,attr"`
+	GroupAddressStyle string `xml:"GroupAddressStyle,attr"` // ThreeLevel, TwoLevel or Free
+}
+
+// XmlHardwareFile represents the Hardware.xml file of a manufacturer in an ETS project export
+type XmlHardwareFile struct {
+	XMLName  xml.Name `xml:"KNX"`
+	Hardware []struct {
+		Products []XmlProduct `xml:"Products>Product"`
+	} `xml:"ManufacturerData>Manufacturer>Hardware>Hardware"`
+}
+
+// XmlProduct represents a product of a manufacturer
+type XmlProduct struct {
+	ID   string `xml:"Id,attr"`
+	Text string `xml:"Text,attr"`
+}
@@ -1,8 +1,14 @@
 package mqtt
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/pakerfeldt/knx-mqtt/internal/homeassistant"
+	"github.com/pakerfeldt/knx-mqtt/internal/metrics"
 	"github.com/pakerfeldt/knx-mqtt/internal/models"
 	"github.com/pakerfeldt/knx-mqtt/internal/msg"
 	"github.com/rs/zerolog/log"
@@ -10,18 +16,50 @@ import (
 	mqttgo "github.com/eclipse/paho.mqtt.golang"
 )
 
+// statusTopic is the availability topic of the bridge, relative to the topic prefix.
+const statusTopic = "bridge/status"
+
+// knxStatusTopic is the topic with the state of the connection to KNX, relative to the topic prefix.
+const knxStatusTopic = "bridge/knx"
+
+// cachedSuffix is appended to the state topics for values answered from the state cache.
+const cachedSuffix = "/cached"
+
+// reloadTopic receives a summary whenever the ETS export is reloaded, relative to the topic prefix.
+const reloadTopic = "bridge/reload"
+
+// validationTopic is the topic with the problems found while importing the ETS export, relative to
+// the topic prefix.
+const validationTopic = "bridge/validation"
+
 type MQTTClient struct {
-	cfg      *models.Config
-	client   mqttgo.Client
-	callback *func(*msg.MQTTMessage)
+	cfg       *models.Config
+	client    mqttgo.Client
+	callback  *func(*msg.MQTTMessage)
+	discovery []homeassistant.Message
+	// hasConnected is set after the first connection to tell reconnects apart.
+	hasConnected bool
+	mu           sync.Mutex
+	knxStatus    []byte
+	validation   []byte
+	// commandFilters are the subscriptions to the command topics of the group addresses.
+	commandFilters map[string]byte
+	// stateTopics are the topics values are published to, which are never commands.
+	stateTopics map[string]bool
 }
 
-func NewClient(config models.Config) *MQTTClient {
+func NewClient(config models.Config, knxItems *models.KNX) *MQTTClient {
 	c := &MQTTClient{
 		cfg:      &config,
 		client:   nil,
 		callback: nil,
 	}
+	if config.HomeAssistant.Discovery {
+		c.discovery = homeassistant.Discovery(config, knxItems)
+	}
+	c.commandFilters = commandFilters(config, knxItems)
+	c.stateTopics = stateTopics(config, knxItems)
+	c.validation = validationPayload(knxItems)
 	mqttOptions := mqttgo.NewClientOptions()
 	if config.MQTT.Username != nil {
 		mqttOptions.SetUsername(*config.MQTT.Username)
@@ -44,9 +82,11 @@ func NewClient(config models.Config) *MQTTClient {
 		}
 	}
 	mqttOptions.AddBroker(config.MQTT.URL)
+	mqttOptions.SetWill(config.MQTT.TopicPrefix+statusTopic, "offline", config.MQTT.Qos, true)
 
 	mqttOptions.OnConnectionLost = func(client mqttgo.Client, err error) {
 		log.Error().Str("error", fmt.Sprintf("%+v", err)).Msg("Connection to MQTT broker lost")
+		metrics.SetConnected("mqtt", false)
 	}
 	mqttOptions.SetOnConnectHandler(c.onConnect)
 	c.client = mqttgo.NewClient(mqttOptions)
@@ -54,17 +94,240 @@ func NewClient(config models.Config) *MQTTClient {
 }
 
 func (c *MQTTClient) onConnect(client mqttgo.Client) {
-	token := c.client.Subscribe(c.cfg.MQTT.TopicPrefix+"+/+/+/+", 0, func(client mqttgo.Client, m mqttgo.Message) {
-		if c.callback != nil {
-			(*c.callback)(msg.NewMQTT(m))
+	metrics.SetConnected("mqtt", true)
+	if c.hasConnected {
+		metrics.Reconnected("mqtt")
+	}
+	c.hasConnected = true
+
+	c.publish(c.cfg.MQTT.TopicPrefix+statusTopic, c.cfg.MQTT.Qos, true, "online")
+	c.mu.Lock()
+	if c.knxStatus != nil {
+		c.publish(c.cfg.MQTT.TopicPrefix+knxStatusTopic, c.cfg.MQTT.Qos, true, c.knxStatus)
+	}
+	if c.validation != nil {
+		c.publish(c.cfg.MQTT.TopicPrefix+validationTopic, c.cfg.MQTT.Qos, true, c.validation)
+	}
+	c.mu.Unlock()
+
+	c.mu.Lock()
+	filters := c.commandFilters
+	c.mu.Unlock()
+	c.subscribeCommands(filters)
+
+	for _, virtual := range c.cfg.VirtualGroupAddresses {
+		token := c.client.Subscribe(virtual.Topic, 0, c.onMessage)
+		token.Wait()
+		if token.Error() != nil {
+			log.Warn().Str("topic", virtual.Topic).Msg("Failed to subscribe to virtual group address topic")
+		}
+	}
+
+	if c.cfg.HomeAssistant.Discovery {
+		c.publishDiscovery()
+		// Home Assistant announces itself after a restart, at which point discovery is repeated.
+		statusTopic := homeassistant.StatusTopic(c.cfg.HomeAssistant)
+		token := c.client.Subscribe(statusTopic, 0, func(client mqttgo.Client, m mqttgo.Message) {
+			if string(m.Payload()) == "online" {
+				c.publishDiscovery()
+			}
+		})
+		token.Wait()
+		if token.Error() != nil {
+			log.Warn().Str("topic", statusTopic).Msg("Failed to subscribe to Home Assistant status")
+		}
+	}
+}
+
+func (c *MQTTClient) onMessage(client mqttgo.Client, m mqttgo.Message) {
+	if c.callback != nil {
+		(*c.callback)(msg.NewMQTT(m))
+	}
+}
+
+// onCommand handles messages to the command topics. A command filter of a group address also matches
+// the state topics of group addresses whose name continues its name, e.g. Lights/Kitchen/+ matches
+// Lights/Kitchen/Ceiling, so the values published by the bridge itself are skipped.
+func (c *MQTTClient) onCommand(client mqttgo.Client, m mqttgo.Message) {
+	c.mu.Lock()
+	own := c.stateTopics[m.Topic()]
+	c.mu.Unlock()
+	if own {
+		return
+	}
+	c.onMessage(client, m)
+}
+
+// commandFilters returns the subscriptions to the command topics of the group addresses by address
+// and full name, and with emitUsingLocation by location name, e.g. knx/1/2/3/+ and
+// knx/Lights/Kitchen/Ceiling/+. Names containing MQTT wildcards cannot be subscribed to. Unless
+// unknown group addresses are ignored, the commands to any three level address are accepted as well.
+func commandFilters(cfg models.Config, knxItems *models.KNX) map[string]byte {
+	filters := make(map[string]byte)
+	wildcard := !cfg.KNX.IgnoreUnknownGroupAddresses
+	if wildcard {
+		filters[cfg.MQTT.TopicPrefix+"+/+/+/+"] = 0
+	}
+	for _, ga := range knxItems.GroupAddresses {
+		if ga.Ignore {
+			continue
+		}
+		for _, topic := range topics(cfg, ga) {
+			if strings.ContainsAny(topic, "+#") {
+				log.Warn().Str("topic", topic).Msg("Cannot subscribe to commands of topic with MQTT wildcards")
+				continue
+			}
+			// Overlapping subscriptions may deliver a command twice
+			if wildcard && strings.Count(topic, "/") == 2 {
+				continue
+			}
+			filters[cfg.MQTT.TopicPrefix+topic+"/+"] = 0
+		}
+	}
+	return filters
+}
+
+// stateTopics returns the topics the values of the group addresses are published to.
+func stateTopics(cfg models.Config, knxItems *models.KNX) map[string]bool {
+	states := make(map[string]bool)
+	for _, ga := range knxItems.GroupAddresses {
+		for _, topic := range topics(cfg, ga) {
+			states[cfg.MQTT.TopicPrefix+topic] = true
+			states[cfg.MQTT.TopicPrefix+topic+"/GroupValue_Read"] = true
+			states[cfg.MQTT.TopicPrefix+topic+cachedSuffix] = true
 		}
-	})
+	}
+	return states
+}
+
+// topics returns the topics of a group address relative to the topic prefix.
+func topics(cfg models.Config, ga models.GroupAddress) []string {
+	topics := []string{ga.Address, ga.FullName}
+	if cfg.OutgoingMqttMessage.EmitUsingLocation && ga.LocationName != "" {
+		topics = append(topics, ga.LocationName)
+	}
+	return topics
+}
+
+// subscribeCommands subscribes to the command topics of the group addresses.
+func (c *MQTTClient) subscribeCommands(filters map[string]byte) {
+	if len(filters) == 0 {
+		return
+	}
+	token := c.client.SubscribeMultiple(filters, c.onCommand)
 	token.Wait()
 	if token.Error() != nil {
-		log.Warn().Msg("Failed to connect to MQTT broker")
+		log.Warn().Err(token.Error()).Msg("Failed to subscribe to command topics")
 	} else {
-		log.Info().Msg("Subscribed to MQTT")
+		log.Info().Int("topics", len(filters)).Msg("Subscribed to MQTT")
+	}
+}
+
+// setCommandFilters replaces the subscriptions to the command topics of the group addresses.
+func (c *MQTTClient) setCommandFilters(filters map[string]byte, states map[string]bool) {
+	c.mu.Lock()
+	previous := c.commandFilters
+	c.commandFilters = filters
+	c.stateTopics = states
+	c.mu.Unlock()
+	if !c.client.IsConnected() {
+		return
+	}
+
+	var removed []string
+	for filter := range previous {
+		if _, exists := filters[filter]; !exists {
+			removed = append(removed, filter)
+		}
+	}
+	if len(removed) > 0 {
+		token := c.client.Unsubscribe(removed...)
+		token.Wait()
+		if token.Error() != nil {
+			log.Warn().Err(token.Error()).Msg("Failed to unsubscribe from command topics")
+		}
+	}
+	c.subscribeCommands(filters)
+}
+
+// publishDiscovery publishes the retained Home Assistant discovery messages.
+func (c *MQTTClient) publishDiscovery() {
+	c.mu.Lock()
+	discovery := c.discovery
+	c.mu.Unlock()
+	for _, message := range discovery {
+		c.publish(message.Topic, c.cfg.MQTT.Qos, true, message.Payload)
+	}
+	log.Debug().Int("entities", len(discovery)).Msg("Published Home Assistant discovery")
+}
+
+// SetConfig replaces the settings that are reloaded together with the ETS export, i.e. the
+// settings of the ETS export and the overrides. Other settings require a restart.
+func (c *MQTTClient) SetConfig(config models.Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg.KNX = config.KNX
+	c.cfg.Overrides = config.Overrides
+}
+
+// SetKNX replaces the group addresses. With Home Assistant discovery enabled, the entities of
+// removed group addresses are deleted and the discovery messages are published again.
+func (c *MQTTClient) SetKNX(knxItems *models.KNX) {
+	c.setCommandFilters(commandFilters(*c.cfg, knxItems), stateTopics(*c.cfg, knxItems))
+	c.setValidation(validationPayload(knxItems))
+	if !c.cfg.HomeAssistant.Discovery {
+		return
+	}
+	discovery := homeassistant.Discovery(*c.cfg, knxItems)
+	c.mu.Lock()
+	previous := c.discovery
+	c.discovery = discovery
+	c.mu.Unlock()
+	if !c.client.IsConnected() {
+		return
+	}
+
+	topics := make(map[string]bool, len(discovery))
+	for _, message := range discovery {
+		topics[message.Topic] = true
+	}
+	for _, message := range previous {
+		if !topics[message.Topic] {
+			// An empty retained message removes the entity from Home Assistant.
+			c.publish(message.Topic, c.cfg.MQTT.Qos, true, "")
+		}
+	}
+	c.publishDiscovery()
+}
+
+// validationPayload returns the validation report of the ETS export as published.
+func validationPayload(knxItems *models.KNX) []byte {
+	payload, err := json.Marshal(knxItems.Validation)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create validation message")
+		return nil
+	}
+	return payload
+}
+
+// setValidation replaces the validation report and publishes it if the bridge is connected.
+func (c *MQTTClient) setValidation(payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validation = payload
+	if payload != nil && c.client.IsConnected() {
+		c.publish(c.cfg.MQTT.TopicPrefix+validationTopic, c.cfg.MQTT.Qos, true, payload)
+	}
+}
+
+// PublishReload publishes the group addresses that changed when the ETS export was reloaded.
+func (c *MQTTClient) PublishReload(diff models.KNXDiff) {
+	payload, err := json.Marshal(diff)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create reload message")
+		return
 	}
+	c.publish(c.cfg.MQTT.TopicPrefix+reloadTopic, c.cfg.MQTT.Qos, false, payload)
 }
 
 func (c *MQTTClient) Connect(callback func(*msg.MQTTMessage)) *error {
@@ -77,16 +340,50 @@ func (c *MQTTClient) Connect(callback func(*msg.MQTTMessage)) *error {
 }
 
 func (c *MQTTClient) Close() {
+	if c.client.IsConnected() {
+		token := c.client.Publish(c.cfg.MQTT.TopicPrefix+statusTopic, c.cfg.MQTT.Qos, true, "offline")
+		token.WaitTimeout(time.Second)
+	}
 	c.client.Disconnect(1)
 }
 
+// PublishKNXStatus publishes the state of the connection to KNX. The last state is
+// published again whenever the connection to the broker is established.
+func (c *MQTTClient) PublishKNXStatus(status models.KNXStatus) {
+	payload, err := json.Marshal(status)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create KNX status message")
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.knxStatus = payload
+	if c.client.IsConnected() {
+		c.publish(c.cfg.MQTT.TopicPrefix+knxStatusTopic, c.cfg.MQTT.Qos, true, payload)
+	}
+}
+
 func (c *MQTTClient) Subscribe(callback func(*msg.MQTTMessage)) {
 	c.callback = &callback
 }
 
 func (c *MQTTClient) Send(message msg.KNXMessage) {
+	c.send(message, false)
+}
+
+// SendCached publishes a value answered from the state cache. It is published to the state topics
+// followed by /cached and never retained, so it cannot be mistaken for a value from the bus.
+func (c *MQTTClient) SendCached(message msg.KNXMessage) {
+	c.send(message, true)
+}
+
+func (c *MQTTClient) send(message msg.KNXMessage, cached bool) {
+	suffix, retain := "", c.cfg.MQTT.Retain
+	if cached {
+		suffix, retain = cachedSuffix, false
+	}
 	if !message.IsResolved() && c.cfg.OutgoingMqttMessage.Type == "bytes" && c.cfg.OutgoingMqttMessage.EmitUsingAddress {
-		c.client.Publish(c.cfg.MQTT.TopicPrefix+message.Destination(), c.cfg.MQTT.Qos, c.cfg.MQTT.Retain, message.Data())
+		c.publish(c.cfg.MQTT.TopicPrefix+message.Destination()+suffix, c.cfg.MQTT.Qos, retain, message.Data())
 		return
 	} else if !message.IsResolved() {
 		log.Info().Str("address", message.Destination()).Msg("Cannot read unknown address, update your KNX XML export")
@@ -107,15 +404,38 @@ func (c *MQTTClient) Send(message msg.KNXMessage) {
 
 	// Check if this is a GroupValue_Read command and if we should use a separate topic suffix
 	isReadCommand := message.Command() == "GroupValue_Read"
-	readSuffix := ""
 	if isReadCommand && c.cfg.OutgoingMqttMessage.ReadCommandsOwnPrefix {
-		readSuffix = "/GroupValue_Read"
+		suffix = "/GroupValue_Read"
+	}
+
+	qos := c.cfg.MQTT.Qos
+	groupAddress, _ := message.GroupAddress()
+	if groupAddress.Qos != nil {
+		qos = *groupAddress.Qos
+	}
+	if groupAddress.Retain != nil && !cached {
+		retain = *groupAddress.Retain
 	}
 
 	if c.cfg.OutgoingMqttMessage.EmitUsingAddress {
-		c.client.Publish(c.cfg.MQTT.TopicPrefix+message.Address()+readSuffix, c.cfg.MQTT.Qos, c.cfg.MQTT.Retain, payload)
+		c.publish(c.cfg.MQTT.TopicPrefix+message.Address()+suffix, qos, retain, payload)
 	}
 	if c.cfg.OutgoingMqttMessage.EmitUsingName {
-		c.client.Publish(c.cfg.MQTT.TopicPrefix+message.FullName()+readSuffix, c.cfg.MQTT.Qos, c.cfg.MQTT.Retain, payload)
+		c.publish(c.cfg.MQTT.TopicPrefix+message.FullName()+suffix, qos, retain, payload)
+	}
+	if c.cfg.OutgoingMqttMessage.EmitUsingLocation && groupAddress.LocationName != "" {
+		c.publish(c.cfg.MQTT.TopicPrefix+groupAddress.LocationName+suffix, qos, retain, payload)
 	}
 }
+
+// publish publishes payload to topic and counts failed publishes.
+func (c *MQTTClient) publish(topic string, qos byte, retained bool, payload interface{}) {
+	token := c.client.Publish(topic, qos, retained, payload)
+	go func() {
+		<-token.Done()
+		if token.Error() != nil {
+			log.Error().Err(token.Error()).Str("topic", topic).Msg("Failed to publish MQTT message")
+			metrics.PublishFailed()
+		}
+	}()
+}
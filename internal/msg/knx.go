@@ -4,6 +4,8 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/pakerfeldt/knx-mqtt/internal/models"
 	"github.com/pakerfeldt/knx-mqtt/internal/utils"
@@ -15,6 +17,8 @@ import (
 type KNXMessage struct {
 	ge                knxgo.GroupEvent
 	resolvedDatapoint *ResolvedDatapoint
+	sourceName        string
+	timestamp         time.Time
 }
 
 type ResolvedDatapoint struct {
@@ -34,6 +38,28 @@ func (m KNXMessage) Source() string {
 	return m.ge.Source.String()
 }
 
+// SourceName returns the name of the device that sent the message, or an empty string if the
+// device is not in the ETS project.
+func (m KNXMessage) SourceName() string {
+	return m.sourceName
+}
+
+// SetSourceName sets the name of the device that sent the message.
+func (m *KNXMessage) SetSourceName(name string) {
+	m.sourceName = name
+}
+
+// Timestamp returns when a message answered from the state cache was seen on the bus, or the
+// zero time for messages from the bus.
+func (m KNXMessage) Timestamp() time.Time {
+	return m.timestamp
+}
+
+// SetTimestamp marks the message as answered from the state cache, seen on the bus at timestamp.
+func (m *KNXMessage) SetTimestamp(timestamp time.Time) {
+	m.timestamp = timestamp
+}
+
 func (m KNXMessage) Destination() string {
 	return m.ge.Destination.String()
 }
@@ -90,6 +116,49 @@ func (m KNXMessage) Datapoint() string {
 	}
 }
 
+// GroupAddress returns the group address the message was resolved to.
+func (m KNXMessage) GroupAddress() (models.GroupAddress, bool) {
+	if m.resolvedDatapoint == nil {
+		return models.GroupAddress{}, false
+	}
+	return m.resolvedDatapoint.groupAddress, true
+}
+
+// Value returns the decoded value with preserved type, or nil if the message is unresolved.
+// The transform of the group address is applied.
+func (m KNXMessage) Value() interface{} {
+	if m.resolvedDatapoint == nil {
+		return nil
+	}
+	value := utils.ExtractDatapointValue(m.resolvedDatapoint.datapoint, m.resolvedDatapoint.groupAddress.Datapoint)
+	return m.resolvedDatapoint.groupAddress.Transform.Apply(value)
+}
+
+// unit returns the unit of the value, unless it is overridden for the group address.
+func (m KNXMessage) unit() string {
+	if unit := m.resolvedDatapoint.groupAddress.Unit; unit != "" {
+		return unit
+	}
+	return m.resolvedDatapoint.datapoint.Unit()
+}
+
+// valueString returns the value as string without unit.
+func (m KNXMessage) valueString() string {
+	if m.resolvedDatapoint.groupAddress.Transform == nil {
+		return utils.StringWithoutSuffix(m.resolvedDatapoint.datapoint)
+	}
+	return utils.FormatValue(m.Value())
+}
+
+// valueWithUnit returns the value as string followed by its unit.
+func (m KNXMessage) valueWithUnit() string {
+	groupAddress := m.resolvedDatapoint.groupAddress
+	if groupAddress.Transform == nil && groupAddress.Unit == "" {
+		return m.resolvedDatapoint.datapoint.String()
+	}
+	return strings.TrimSpace(m.valueString() + " " + m.unit())
+}
+
 func (m KNXMessage) ToPayload(emitValueAsString bool, messageType string, jsonFields *models.IncludedJsonFields) (interface{}, error) {
 	var payload interface{}
 	if messageType == models.JsonType {
@@ -103,13 +172,13 @@ func (m KNXMessage) ToPayload(emitValueAsString bool, messageType string, jsonFi
 		}
 		if jsonFields.IncludeValue {
 			if emitValueAsString {
-				outgoingJson.Value = utils.StringWithoutSuffix(m.resolvedDatapoint.datapoint)
+				outgoingJson.Value = m.valueString()
 			} else {
-				outgoingJson.Value = utils.ExtractDatapointValue(m.resolvedDatapoint.datapoint, m.resolvedDatapoint.groupAddress.Datapoint)
+				outgoingJson.Value = m.Value()
 			}
 		}
 		if jsonFields.IncludeUnit {
-			unit := m.resolvedDatapoint.datapoint.Unit()
+			unit := m.unit()
 			outgoingJson.Unit = &unit
 		}
 		if jsonFields.IncludeCommand {
@@ -117,6 +186,10 @@ func (m KNXMessage) ToPayload(emitValueAsString bool, messageType string, jsonFi
 		}
 		if jsonFields.IncludeSource {
 			outgoingJson.Source = m.Source()
+			outgoingJson.SourceName = m.SourceName()
+		}
+		if !m.timestamp.IsZero() {
+			outgoingJson.Timestamp = m.timestamp.Format(time.RFC3339)
 		}
 		jsonBytes, err := json.Marshal(outgoingJson)
 		if err != nil {
@@ -126,12 +199,12 @@ func (m KNXMessage) ToPayload(emitValueAsString bool, messageType string, jsonFi
 		payload = string(jsonBytes)
 	} else if messageType == models.ValueType {
 		if emitValueAsString {
-			payload = utils.StringWithoutSuffix(m.resolvedDatapoint.datapoint)
+			payload = m.valueString()
 		} else {
-			payload = fmt.Sprintf("%v", utils.ExtractDatapointValue(m.resolvedDatapoint.datapoint, m.resolvedDatapoint.groupAddress.Datapoint))
+			payload = utils.FormatValue(m.Value())
 		}
 	} else if messageType == models.ValueWithUnitType {
-		payload = m.resolvedDatapoint.datapoint.String()
+		payload = m.valueWithUnit()
 	} else if messageType == models.BytesType {
 		payload = m.resolvedDatapoint.datapoint.Pack()
 	}
@@ -171,6 +244,7 @@ func (m KNXMessage) ToPayload3(groupAddress models.GroupAddress, emitValueAsStri
 		}
 		if jsonFields.IncludeSource {
 			outgoingJson.Source = m.Source()
+			outgoingJson.SourceName = m.SourceName()
 		}
 		jsonBytes, err := json.Marshal(outgoingJson)
 		if err != nil {
@@ -182,7 +256,7 @@ func (m KNXMessage) ToPayload3(groupAddress models.GroupAddress, emitValueAsStri
 		if emitValueAsString {
 			payload = utils.StringWithoutSuffix(datapoint)
 		} else {
-			payload = fmt.Sprintf("%v", utils.ExtractDatapointValue(datapoint, groupAddress.Datapoint))
+			payload = utils.FormatValue(utils.ExtractDatapointValue(datapoint, groupAddress.Datapoint))
 		}
 	} else if messageType == models.ValueWithUnitType {
 		payload = datapoint.String()
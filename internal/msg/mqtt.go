@@ -1,6 +1,8 @@
 package msg
 
 import (
+	"strings"
+
 	mqttgo "github.com/eclipse/paho.mqtt.golang"
 )
 
@@ -19,3 +21,10 @@ func (m MQTTMessage) Topic() string {
 func (m MQTTMessage) Bytes() []byte {
 	return m.message.Payload()
 }
+
+// AddressAndCommand splits a topic of the form <topicPrefix><address>/<command>.
+func (m MQTTMessage) AddressAndCommand(topicPrefix string) (string, string) {
+	topic := m.message.Topic()
+	separator := strings.LastIndex(topic, "/")
+	return strings.TrimPrefix(topic[:separator], topicPrefix), topic[separator+1:]
+}
@@ -0,0 +1,217 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// regexpCSVAddress matches group addresses as well as the addresses of ranges, e.g. 1/-/-.
+var regexpCSVAddress = regexp.MustCompile(`^\d+(/(\d+|-)){0,2}$`)
+
+// regexpDottedDpt matches datapoint types written as e.g. 9.001.
+var regexpDottedDpt = regexp.MustCompile(`^(\d+)\.(\d+)$`)
+
+// regexpNumber matches datapoint types given as main type only, e.g. 9.
+var regexpNumber = regexp.MustCompile(`^\d+$`)
+
+// csvDelimiters are the delimiters ETS offers for CSV exports.
+var csvDelimiters = []rune{';', ',', '\t'}
+
+// csvColumns holds the index of each known column of a CSV export, -1 if the column is missing.
+type csvColumns struct {
+	name    int
+	main    int
+	middle  int
+	sub     int
+	address int
+	dpt     int
+}
+
+func noCSVColumns() csvColumns {
+	return csvColumns{name: -1, main: -1, middle: -1, sub: -1, address: -1, dpt: -1}
+}
+
+// isXML reports whether content looks like an XML document rather than a CSV export.
+func isXML(content []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(decodeText(content)), "<")
+}
+
+// decodeText returns content as UTF-8. ETS writes CSV exports as UTF-8, UTF-16 or in the Windows code page.
+func decodeText(content []byte) string {
+	if bytes.HasPrefix(content, []byte{0xff, 0xfe}) || bytes.HasPrefix(content, []byte{0xfe, 0xff}) {
+		decoded, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(content)
+		if err == nil {
+			return string(decoded)
+		}
+	}
+	if utf8.Valid(content) {
+		return strings.TrimPrefix(string(content), "\ufeff")
+	}
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(content)
+	if err != nil {
+		return string(content)
+	}
+	return string(decoded)
+}
+
+// parseCSV converts an ETS CSV group address export into the structure of an XML group address export.
+// The 1/1 (name, address, ...) and 3/1 (main, middle, sub, address, ...) formats of ETS are supported,
+// with or without header, as well as a plain address, name and datapoint type listing.
+func parseCSV(content []byte) (models.XmlGroupAddressExport, error) {
+	text := decodeText(content)
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = csvDelimiter(text)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return models.XmlGroupAddressExport{}, fmt.Errorf("failed to read CSV export: %w", err)
+	}
+	if len(records) == 0 {
+		return models.XmlGroupAddressExport{}, fmt.Errorf("empty CSV export")
+	}
+
+	columns, isHeader := csvHeaderColumns(records[0])
+	if isHeader {
+		records = records[1:]
+	} else if columns, err = csvGuessColumns(records[0]); err != nil {
+		return models.XmlGroupAddressExport{}, err
+	}
+
+	var export models.XmlGroupAddressExport
+	mains := make(map[string]int)
+	middles := make(map[string]int)
+	for _, record := range records {
+		address := csvField(record, columns.address)
+		if !regexpCSVAddress.MatchString(address) {
+			continue
+		}
+		name := csvName(record, columns)
+		parts := strings.Split(address, "/")
+
+		switch {
+		case len(parts) > 1 && parts[1] == "-":
+			mains[parts[0]] = len(export.GroupRanges)
+			export.GroupRanges = append(export.GroupRanges, models.XmlGroupRange{Name: name})
+		case len(parts) == 3 && parts[2] == "-":
+			main, ok := mains[parts[0]]
+			if !ok {
+				continue
+			}
+			middles[parts[0]+"/"+parts[1]] = len(export.GroupRanges[main].GroupRanges)
+			export.GroupRanges[main].GroupRanges = append(export.GroupRanges[main].GroupRanges, models.XmlGroupRange{Name: name})
+		default:
+			groupAddress := models.XmlGroupAddress{
+				Name:    name,
+				Address: address,
+				DPTs:    csvDatapoint(csvField(record, columns.dpt)),
+			}
+			main, hasMain := mains[parts[0]]
+			var middle int
+			hasMiddle := false
+			if len(parts) == 3 {
+				middle, hasMiddle = middles[parts[0]+"/"+parts[1]]
+			}
+			switch {
+			case hasMain && hasMiddle:
+				groupRange := &export.GroupRanges[main].GroupRanges[middle]
+				groupRange.Addresses = append(groupRange.Addresses, groupAddress)
+			case hasMain:
+				export.GroupRanges[main].Addresses = append(export.GroupRanges[main].Addresses, groupAddress)
+			default:
+				export.Addresses = append(export.Addresses, groupAddress)
+			}
+		}
+	}
+	return export, nil
+}
+
+// csvDelimiter returns the delimiter occurring most often in the first line of text.
+func csvDelimiter(text string) rune {
+	line, _, _ := strings.Cut(text, "\n")
+	delimiter, count := csvDelimiters[0], -1
+	for _, candidate := range csvDelimiters {
+		if n := strings.Count(line, string(candidate)); n > count {
+			delimiter, count = candidate, n
+		}
+	}
+	return delimiter
+}
+
+// csvHeaderColumns returns the columns named by record, if record is a header line.
+func csvHeaderColumns(record []string) (csvColumns, bool) {
+	columns := noCSVColumns()
+	for i, field := range record {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "group name", "name":
+			columns.name = i
+		case "main", "main group":
+			columns.main = i
+		case "middle", "middle group":
+			columns.middle = i
+		case "sub", "sub group":
+			columns.sub = i
+		case "address", "group address":
+			columns.address = i
+		case "datapointtype", "datapoint type", "dpt", "dpts":
+			columns.dpt = i
+		}
+	}
+	return columns, columns.address >= 0
+}
+
+// csvGuessColumns returns the columns of an export without header line, based on its first record.
+func csvGuessColumns(record []string) (csvColumns, error) {
+	columns := noCSVColumns()
+	switch {
+	case regexpCSVAddress.MatchString(csvField(record, 0)):
+		// Address, Name, DPT
+		columns.address, columns.name, columns.dpt = 0, 1, 2
+	case regexpCSVAddress.MatchString(csvField(record, 1)):
+		// ETS 1/1: Group name, Address, Central, Unfiltered, Description, DatapointType, Security
+		columns.name, columns.address, columns.dpt = 0, 1, 5
+	case regexpCSVAddress.MatchString(csvField(record, 3)):
+		// ETS 3/1: Main, Middle, Sub, Address, Central, Unfiltered, Description, DatapointType, Security
+		columns.main, columns.middle, columns.sub, columns.address, columns.dpt = 0, 1, 2, 3, 7
+	default:
+		return columns, fmt.Errorf("unknown CSV export format")
+	}
+	return columns, nil
+}
+
+// csvName returns the name of a record, which in the 3/1 format is in the column of its level.
+func csvName(record []string, columns csvColumns) string {
+	for _, column := range []int{columns.name, columns.sub, columns.middle, columns.main} {
+		if name := csvField(record, column); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// csvDatapoint returns the datapoint type in the notation of the XML exports, e.g. DPST-9-1 for 9.001
+// and DPT-9 for 9.
+func csvDatapoint(dpt string) string {
+	if match := regexpDottedDpt.FindStringSubmatch(dpt); match != nil {
+		return fmt.Sprintf("DPST-%s-%s", match[1], match[2])
+	}
+	if regexpNumber.MatchString(dpt) {
+		return "DPT-" + dpt
+	}
+	return dpt
+}
+
+func csvField(record []string, column int) string {
+	if column < 0 || column >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[column])
+}
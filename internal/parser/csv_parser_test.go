@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestParseCSVFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name: "1/1 with header",
+			content: `"Group name";"Address";"Central";"Unfiltered";"Description";"DatapointType";"Security"
+"Lights";"1/-/-";"";"";"";"";"Auto"
+"Kitchen";"1/1/-";"";"";"";"";"Auto"
+"Ceiling";"1/1/1";"";"";"";"DPST-1-1";"Auto"
+"Dimmer";"1/1/2";"";"";"";"DPST-5-1";"Auto"
+`,
+			want: map[string]string{"1/1/1": "Lights/Kitchen/Ceiling", "1/1/2": "Lights/Kitchen/Dimmer"},
+		},
+		{
+			name: "3/1 without header",
+			content: `"Lights","","","1/-/-","","","","","Auto"
+"","Kitchen","","1/1/-","","","","","Auto"
+"","","Ceiling","1/1/1","","","","DPST-1-1","Auto"
+`,
+			want: map[string]string{"1/1/1": "Lights/Kitchen/Ceiling"},
+		},
+		{
+			name:    "address, name and DPT",
+			content: "1/1/1\tCeiling\t1.001\n2/0/3\tTemperature\t9.001\n",
+			want:    map[string]string{"1/1/1": "Ceiling", "2/0/3": "Temperature"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export, err := parseCSV([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("parseCSV() error = %v", err)
+			}
+			knxItems, skipped := parseExport(export, nil, ImportOptions{GaTranslation: models.FAT_None})
+			if len(skipped) != 0 {
+				t.Errorf("unexpected skipped addresses: %+v", skipped)
+			}
+			if len(knxItems.GroupAddresses) != len(tt.want) {
+				t.Errorf("got %d group addresses, want %d: %+v", len(knxItems.GroupAddresses), len(tt.want), knxItems.GroupAddresses)
+			}
+			for address, fullName := range tt.want {
+				ga, ok := knxItems.GetGroupAddress(address)
+				if !ok {
+					t.Errorf("missing group address %s", address)
+				} else if ga.FullName != fullName {
+					t.Errorf("FullName of %s = %q, want %q", address, ga.FullName, fullName)
+				}
+			}
+		})
+	}
+}
+
+func TestReadGroupsFromFileDecodesCSV(t *testing.T) {
+	const content = "\"Group name\",\"Address\",\"DatapointType\"\n\"Kök\",\"1/0/1\",\"DPST-1-1\"\n"
+	utf16, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows1252, err := charmap.Windows1252.NewEncoder().Bytes([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, encoded := range map[string][]byte{
+		"utf-8":        append([]byte{0xef, 0xbb, 0xbf}, content...),
+		"utf-16":       utf16,
+		"windows-1252": windows1252,
+	} {
+		path := filepath.Join(t.TempDir(), "export.csv")
+		if err := os.WriteFile(path, encoded, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		knxItems, err := ReadGroupsFromFile(path, ImportOptions{GaTranslation: models.FAT_3_parts})
+		if err != nil {
+			t.Fatalf("%s: ReadGroupsFromFile() error = %v", name, err)
+		}
+		if len(knxItems.GroupAddresses) != 1 || knxItems.GroupAddresses[0].Name != "Kök" {
+			t.Errorf("%s: got %+v, want Kök at 1/0/1", name, knxItems.GroupAddresses)
+		}
+	}
+}
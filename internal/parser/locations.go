@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+)
+
+// groupLocation is where a group address is used in the building structure of an ETS project.
+type groupLocation struct {
+	// spaces are the names of the enclosing spaces, from the building down to the room.
+	spaces   []string
+	function string
+}
+
+// locationName returns the name of a group address at the location, e.g.
+// House/Ground floor/Kitchen/Ceiling light/Switch, or an empty string if it is not in a building.
+func (l groupLocation) locationName(name string) string {
+	if len(l.spaces) == 0 {
+		return ""
+	}
+	parts := append([]string{}, l.spaces...)
+	if l.function != "" {
+		parts = append(parts, l.function)
+	}
+	return strings.Join(append(parts, name), "/")
+}
+
+// installationLocations returns the locations of the group addresses of installation by their Id,
+// found through the functions of the buildings and trades. A group address used by several
+// functions gets the location of the first one, preferring functions placed in a building.
+func installationLocations(installation models.XmlInstallation) map[string]groupLocation {
+	locations := make(map[string]groupLocation)
+	addFunctions := func(spaces []string, functions []models.XmlFunction) {
+		for _, function := range functions {
+			for _, ref := range function.GroupAddresses {
+				if _, exists := locations[ref.RefID]; !exists {
+					locations[ref.RefID] = groupLocation{spaces: spaces, function: replaceSlashInName(function.Name)}
+				}
+			}
+		}
+	}
+
+	var walk func(path []string, space models.XmlSpace)
+	walk = func(path []string, space models.XmlSpace) {
+		path = append(append([]string{}, path...), replaceSlashInName(space.Name))
+		addFunctions(path, space.Functions)
+		for _, child := range space.Spaces {
+			walk(path, child)
+		}
+		for _, child := range space.BuildingParts {
+			walk(path, child)
+		}
+	}
+	for _, space := range installation.Locations {
+		walk(nil, space)
+	}
+	for _, space := range installation.Buildings {
+		walk(nil, space)
+	}
+
+	var walkTrade func(trade models.XmlTrade)
+	walkTrade = func(trade models.XmlTrade) {
+		addFunctions(nil, trade.Functions)
+		for _, child := range trade.Trades {
+			walkTrade(child)
+		}
+	}
+	for _, trade := range installation.Trades {
+		walkTrade(trade)
+	}
+	return locations
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+)
+
+const locationsProject = `<KNX><Project><Installations><Installation Name="Home">
+  <Locations>
+    <Space Type="Building" Name="House">
+      <Space Type="Floor" Name="Ground floor">
+        <Space Type="Room" Name="Kitchen">
+          <Function Name="Ceiling light">
+            <GroupAddressRef RefId="P-0001-0_GA-1" />
+            <GroupAddressRef RefId="P-0001-0_GA-2" />
+          </Function>
+        </Space>
+      </Space>
+    </Space>
+  </Locations>
+  <Trades>
+    <Trade Name="Heating">
+      <Function Name="Kitchen/Dining">
+        <GroupAddressRef RefId="P-0001-0_GA-2" />
+        <GroupAddressRef RefId="P-0001-0_GA-3" />
+      </Function>
+    </Trade>
+  </Trades>
+  <GroupAddresses><GroupRanges>
+    <GroupRange Name="Lights">
+      <GroupAddress Id="P-0001-0_GA-1" Name="Switch" Address="2305" DatapointType="DPST-1-1" />
+      <GroupAddress Id="P-0001-0_GA-2" Name="Brightness" Address="2306" DatapointType="DPST-5-1" />
+    </GroupRange>
+    <GroupRange Name="Heating">
+      <GroupAddress Id="P-0001-0_GA-3" Name="Setpoint" Address="4353" DatapointType="DPST-9-1" />
+      <GroupAddress Id="P-0001-0_GA-4" Name="Mode" Address="4354" DatapointType="DPST-20-102" />
+    </GroupRange>
+  </GroupRanges></GroupAddresses>
+</Installation></Installations></Project></KNX>`
+
+func TestParseExportAssignsLocations(t *testing.T) {
+	var knxfile models.XmlKNX
+	if err := xml.Unmarshal([]byte(locationsProject), &knxfile); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	installation := knxfile.Project.Installations[0]
+	export := installation.GroupAddresses.GroupRanges
+	translateDatapointTypeToDPTs(&export)
+
+	knxItems, _ := parseExport(export, installationLocations(installation), ImportOptions{GaTranslation: models.FAT_3_parts})
+
+	tests := []struct {
+		address      string
+		function     string
+		locationName string
+	}{
+		{"1/1/1", "Ceiling light", "House/Ground floor/Kitchen/Ceiling light/Switch"},
+		{"1/1/2", "Ceiling light", "House/Ground floor/Kitchen/Ceiling light/Brightness"},
+		{"2/1/1", "Kitchen_Dining", ""},
+		{"2/1/2", "", ""},
+	}
+	for _, tt := range tests {
+		ga, ok := knxItems.GetGroupAddress(tt.address)
+		if !ok {
+			t.Errorf("missing group address %s", tt.address)
+			continue
+		}
+		if ga.Function != tt.function || ga.LocationName != tt.locationName {
+			t.Errorf("%s: got function %q and location name %q, want %q and %q", tt.address, ga.Function, ga.LocationName, tt.function, tt.locationName)
+		}
+	}
+
+	ga, ok := knxItems.GetGroupAddress("House/Ground floor/Kitchen/Ceiling light/Switch")
+	if !ok || ga.Address != "1/1/1" {
+		t.Errorf("expected the location name to resolve to 1/1/1, got %+v", ga)
+	}
+}
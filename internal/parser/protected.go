@@ -0,0 +1,193 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/secure"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// winZipAESMethod is the compression method of entries encrypted with WinZip AES.
+	winZipAESMethod = 99
+	// winZipAESExtraID is the ID of the extra field describing WinZip AES encryption.
+	winZipAESExtraID = 0x9901
+	winZipIterations = 1000
+	winZipVerifySize = 2
+	winZipMACSize    = 10
+)
+
+var errWrongPassword = errors.New("wrong project password")
+
+// isProtectedProject reports whether f is the encrypted project archive of a password protected ETS project.
+func isProtectedProject(f *zip.File) bool {
+	name := filepath.Base(f.Name)
+	return strings.HasPrefix(name, "P-") && strings.HasSuffix(name, ".zip")
+}
+
+// readProtectedProject reads 0.xml and project.xml from the encrypted project archive f.
+func readProtectedProject(f *zip.File, password string) (*exportFile, error) {
+	if password == "" {
+		return nil, errors.New("the ETS project is password protected, set projectPassword")
+	}
+
+	data, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open protected project archive: %w", err)
+	}
+
+	export := &exportFile{id: ETSExport}
+	passwords := secure.ProjectArchivePasswords(password)
+	for _, inner := range archive.File {
+		switch filepath.Base(inner.Name) {
+		case "0.xml":
+			export.groups, passwords, err = readEncryptedZipFile(inner, passwords)
+		case "project.xml":
+			export.project, passwords, err = readEncryptedZipFile(inner, passwords)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", inner.Name, err)
+		}
+	}
+
+	if export.groups == nil {
+		return nil, fmt.Errorf("no 0.xml file found in the protected project archive")
+	}
+	return export, nil
+}
+
+// readEncryptedZipFile decrypts f with the first of passwords that matches. It returns the content
+// and the matching password, so that it is tried first for the following files.
+func readEncryptedZipFile(f *zip.File, passwords [][]byte) ([]byte, [][]byte, error) {
+	if f.Flags&0x1 == 0 {
+		content, err := readZipFile(f)
+		return content, passwords, err
+	}
+	if f.Method != winZipAESMethod {
+		return nil, nil, fmt.Errorf("unsupported encryption method %d", f.Method)
+	}
+	keyLen, method, err := parseWinZipAESExtra(f.Extra)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, password := range passwords {
+		compressed, err := decryptWinZipAES(data, password, keyLen)
+		if errors.Is(err, errWrongPassword) {
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		content, err := decompress(compressed, method)
+		return content, passwords[i : i+1], err
+	}
+	return nil, nil, errWrongPassword
+}
+
+// parseWinZipAESExtra returns the key length and the actual compression method from the extra fields.
+func parseWinZipAESExtra(extra []byte) (int, uint16, error) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		if id == winZipAESExtraID && size >= 7 {
+			field := extra[4 : 4+size]
+			method := binary.LittleEndian.Uint16(field[5:])
+			switch field[4] {
+			case 1:
+				return 16, method, nil
+			case 2:
+				return 24, method, nil
+			case 3:
+				return 32, method, nil
+			}
+			return 0, 0, fmt.Errorf("invalid AES strength %d", field[4])
+		}
+		extra = extra[4+size:]
+	}
+	return 0, 0, errors.New("missing WinZip AES extra field")
+}
+
+// decryptWinZipAES decrypts an entry encrypted with WinZip AES (AE-1 or AE-2). The data consists
+// of salt | password verifier | ciphertext | authentication code.
+func decryptWinZipAES(data []byte, password []byte, keyLen int) ([]byte, error) {
+	saltLen := keyLen / 2
+	if len(data) < saltLen+winZipVerifySize+winZipMACSize {
+		return nil, errors.New("encrypted entry too short")
+	}
+	salt := data[:saltLen]
+	verifier := data[saltLen : saltLen+winZipVerifySize]
+	ciphertext := data[saltLen+winZipVerifySize : len(data)-winZipMACSize]
+	mac := data[len(data)-winZipMACSize:]
+
+	derived := pbkdf2.Key(password, salt, winZipIterations, 2*keyLen+winZipVerifySize, sha1.New)
+	if subtle.ConstantTimeCompare(derived[2*keyLen:], verifier) != 1 {
+		return nil, errWrongPassword
+	}
+	authentication := hmac.New(sha1.New, derived[keyLen:2*keyLen])
+	authentication.Write(ciphertext)
+	if !hmac.Equal(authentication.Sum(nil)[:winZipMACSize], mac) {
+		return nil, errors.New("authentication of encrypted entry failed")
+	}
+
+	block, err := aes.NewCipher(derived[:keyLen])
+	if err != nil {
+		return nil, err
+	}
+	// WinZip uses AES-CTR with a little endian counter starting at 1.
+	plaintext := make([]byte, len(ciphertext))
+	var counter, keystream [aes.BlockSize]byte
+	for offset := 0; offset < len(ciphertext); offset += aes.BlockSize {
+		for i := range counter {
+			counter[i]++
+			if counter[i] != 0 {
+				break
+			}
+		}
+		block.Encrypt(keystream[:], counter[:])
+		end := min(offset+aes.BlockSize, len(ciphertext))
+		for i := offset; i < end; i++ {
+			plaintext[i] = ciphertext[i] ^ keystream[i-offset]
+		}
+	}
+	return plaintext, nil
+}
+
+func decompress(data []byte, method uint16) ([]byte, error) {
+	switch method {
+	case zip.Store:
+		return data, nil
+	case zip.Deflate:
+		reader := flate.NewReader(bytes.NewReader(data))
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	return nil, fmt.Errorf("unsupported compression method %d", method)
+}
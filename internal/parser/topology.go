@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// shortID returns the part of an ETS Id following the project prefix, e.g. GA-1 for P-0001-0_GA-1,
+// since communication objects refer to group addresses using either form.
+func shortID(id string) string {
+	if separator := strings.LastIndex(id, "_"); separator >= 0 {
+		return id[separator+1:]
+	}
+	return id
+}
+
+// groupAddressIDs returns the group addresses of export by their short Id.
+func groupAddressIDs(export models.XmlGroupAddressExport) map[string]models.FlatGroupAddress {
+	ids := make(map[string]models.FlatGroupAddress)
+	addAddresses := func(addresses []models.XmlGroupAddress) {
+		for _, address := range addresses {
+			if address.ID == "" {
+				continue
+			}
+			if flatAddr, err := models.ParseGroupAddress(address.Address); err == nil {
+				ids[shortID(address.ID)] = flatAddr
+			}
+		}
+	}
+	var walk func(groupRange models.XmlGroupRange)
+	walk = func(groupRange models.XmlGroupRange) {
+		addAddresses(groupRange.Addresses)
+		for _, child := range groupRange.GroupRanges {
+			walk(child)
+		}
+	}
+	addAddresses(export.Addresses)
+	for _, groupRange := range export.GroupRanges {
+		walk(groupRange)
+	}
+	return ids
+}
+
+// productNames returns the names of the products in the Hardware.xml files by their Id.
+func productNames(hardware [][]byte) map[string]string {
+	products := make(map[string]string)
+	for _, content := range hardware {
+		var hardwareFile models.XmlHardwareFile
+		if err := xml.Unmarshal(content, &hardwareFile); err != nil {
+			log.Warn().Err(err).Msg("Failed to parse Hardware.xml")
+			continue
+		}
+		for _, h := range hardwareFile.Hardware {
+			for _, product := range h.Products {
+				products[product.ID] = product.Text
+			}
+		}
+	}
+	return products
+}
+
+// installationDevices returns the devices in the topology of installation that have an individual
+// address, with the group addresses their communication objects are linked to. The first group
+// address of a communication object is the one it sends to, all of them are listened on.
+func installationDevices(installation models.XmlInstallation, products map[string]string, ids map[string]models.FlatGroupAddress) []models.Device {
+	var devices []models.Device
+	addDevice := func(area models.XmlArea, line models.XmlLine, instance models.XmlDeviceInstance) {
+		if instance.Address == "" {
+			return
+		}
+		device := models.Device{
+			Address: fmt.Sprintf("%s.%s.%s", area.Address, line.Address, instance.Address),
+			Name:    instance.Name,
+			Product: products[instance.ProductRefID],
+		}
+		if device.Name == "" {
+			device.Name = device.Product
+		}
+		link := func(refID string, sends bool) {
+			flatAddr, ok := ids[shortID(refID)]
+			if !ok {
+				return
+			}
+			if sends {
+				device.Sends = append(device.Sends, flatAddr)
+			}
+			device.Receives = append(device.Receives, flatAddr)
+		}
+		for _, comObject := range instance.ComObjects {
+			for i, refID := range strings.Fields(comObject.Links) {
+				link(refID, i == 0)
+			}
+			for _, connector := range comObject.Send {
+				link(connector.GroupAddressRefID, true)
+			}
+			for _, connector := range comObject.Receive {
+				link(connector.GroupAddressRefID, false)
+			}
+		}
+		devices = append(devices, device)
+	}
+
+	for _, area := range installation.Topology.Areas {
+		for _, line := range area.Lines {
+			for _, instance := range line.Devices {
+				addDevice(area, line, instance)
+			}
+			for _, segment := range line.Segments {
+				for _, instance := range segment.Devices {
+					addDevice(area, line, instance)
+				}
+			}
+		}
+	}
+	return devices
+}
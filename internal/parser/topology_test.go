@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+)
+
+const topologyProject = `<KNX><Project><Installations><Installation Name="Home">
+  <Topology>
+    <Area Address="1" Name="Home">
+      <Line Address="1" Name="Ground floor">
+        <DeviceInstance Id="P-0001-0_DI-1" Name="Kitchen push button" Address="5" ProductRefId="M-0083_H-1_P-1">
+          <ComObjectInstanceRefs>
+            <ComObjectInstanceRef RefId="O-0" Links="GA-1 P-0001-0_GA-2" />
+          </ComObjectInstanceRefs>
+        </DeviceInstance>
+        <Segment>
+          <DeviceInstance Id="P-0001-0_DI-2" Address="6" ProductRefId="M-0083_H-2_P-2">
+            <ComObjectInstanceRefs>
+              <ComObjectInstanceRef RefId="O-1">
+                <Connectors>
+                  <Send GroupAddressRefId="P-0001-0_GA-2" />
+                  <Receive GroupAddressRefId="P-0001-0_GA-1" />
+                </Connectors>
+              </ComObjectInstanceRef>
+            </ComObjectInstanceRefs>
+          </DeviceInstance>
+        </Segment>
+        <DeviceInstance Id="P-0001-0_DI-3" Name="Not downloaded" ProductRefId="M-0083_H-1_P-1" />
+      </Line>
+    </Area>
+  </Topology>
+  <GroupAddresses><GroupRanges>
+    <GroupRange Name="Lights">
+      <GroupAddress Id="P-0001-0_GA-1" Name="Switch" Address="2305" DatapointType="DPST-1-1" />
+      <GroupAddress Id="P-0001-0_GA-2" Name="Status" Address="2306" DatapointType="DPST-1-1" />
+    </GroupRange>
+  </GroupRanges></GroupAddresses>
+</Installation></Installations></Project></KNX>`
+
+const topologyHardware = `<KNX><ManufacturerData><Manufacturer RefId="M-0083"><Hardware>
+  <Hardware Id="M-0083_H-1"><Products><Product Id="M-0083_H-1_P-1" Text="Push button 4-fold" /></Products></Hardware>
+  <Hardware Id="M-0083_H-2"><Products><Product Id="M-0083_H-2_P-2" Text="Switch actuator 8-fold" /></Products></Hardware>
+</Hardware></Manufacturer></ManufacturerData></KNX>`
+
+func TestReadGroupsFromFileImportsTopology(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "project.knxproj")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(file)
+	for name, content := range map[string]string{
+		"P-0001/0.xml":        topologyProject,
+		"M-0083/Hardware.xml": topologyHardware,
+	} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	knxItems, err := ReadGroupsFromFile(path, ImportOptions{GaTranslation: models.FAT_3_parts})
+	if err != nil {
+		t.Fatalf("ReadGroupsFromFile() error = %v", err)
+	}
+
+	if len(knxItems.Devices) != 2 {
+		t.Fatalf("got %d devices, want 2: %+v", len(knxItems.Devices), knxItems.Devices)
+	}
+	for address, name := range map[string]string{"1.1.5": "Kitchen push button", "1.1.6": "Switch actuator 8-fold"} {
+		device, ok := knxItems.GetDevice(address)
+		if !ok || device.Name != name {
+			t.Errorf("device %s: got %+v, want name %q", address, device, name)
+		}
+	}
+
+	tests := []struct {
+		address   string
+		senders   []string
+		listeners []string
+	}{
+		{"1/1/1", []string{"1.1.5"}, []string{"1.1.5", "1.1.6"}},
+		{"1/1/2", []string{"1.1.6"}, []string{"1.1.5", "1.1.6"}},
+	}
+	for _, tt := range tests {
+		ga, ok := knxItems.GetGroupAddress(tt.address)
+		if !ok {
+			t.Errorf("missing group address %s", tt.address)
+			continue
+		}
+		if !reflect.DeepEqual(ga.Senders, tt.senders) || !reflect.DeepEqual(ga.Listeners, tt.listeners) {
+			t.Errorf("%s: got senders %v and listeners %v, want %v and %v", tt.address, ga.Senders, ga.Listeners, tt.senders, tt.listeners)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// validationReport collects the group addresses sharing a full name and the group addresses that
+// were skipped as duplicates or for their datapoint type.
+func validationReport(knxItems *models.KNX, skipped []SkippedAddress) models.ValidationReport {
+	report := models.ValidationReport{DuplicateNames: knxItems.DuplicateNames()}
+	duplicates := make(map[models.FlatGroupAddress]int)
+	for _, s := range skipped {
+		switch s.Reason {
+		case reasonDuplicateAddress:
+			flatAddr, _ := models.ParseGroupAddress(s.Address)
+			if i, exists := duplicates[flatAddr]; exists {
+				report.DuplicateAddresses[i].Names = append(report.DuplicateAddresses[i].Names, s.Name)
+				continue
+			}
+			duplicate := models.DuplicateAddress{Address: s.Address, Names: []string{s.Name}}
+			if first, exists := knxItems.GetGroupAddress(s.Address); exists {
+				duplicate = models.DuplicateAddress{Address: first.Address, Names: []string{first.Name, s.Name}}
+			}
+			duplicates[flatAddr] = len(report.DuplicateAddresses)
+			report.DuplicateAddresses = append(report.DuplicateAddresses, duplicate)
+		case reasonUnsupportedDPT:
+			report.UnsupportedDatapoints = append(report.UnsupportedDatapoints,
+				models.UnsupportedDatapoint{Address: s.Address, Name: s.Name, Datapoint: s.Datapoint})
+		}
+	}
+	return report
+}
+
+// validate logs the validation report of knxItems, keeps it in knxItems to be published and handles
+// the problems according to policy.
+func validate(knxItems *models.KNX, skipped []SkippedAddress, policy models.ValidationPolicy) error {
+	report := validationReport(knxItems, skipped)
+	if report.IsEmpty() {
+		return nil
+	}
+
+	for _, duplicate := range report.DuplicateNames {
+		log.Warn().Str("name", duplicate.Name).Strs("addresses", duplicate.Addresses).Msg("Group addresses share the same name")
+	}
+	for _, duplicate := range report.DuplicateAddresses {
+		log.Warn().Str("address", duplicate.Address).Strs("names", duplicate.Names).Msg("Group address defined more than once, using the first definition")
+	}
+	for _, unsupported := range report.UnsupportedDatapoints {
+		log.Warn().Str("address", unsupported.Address).Str("name", unsupported.Name).Str("datapoint", unsupported.Datapoint).Msg("Skipped group address with unsupported DPT")
+	}
+
+	switch policy {
+	case models.ValidationFail:
+		return fmt.Errorf("validation of the ETS export failed: %s", report)
+	case models.ValidationDisambiguate:
+		if len(report.DuplicateNames) > 0 {
+			knxItems.Disambiguate()
+			log.Info().Int("names", len(report.DuplicateNames)).Msg("Appended the address to names shared by several group addresses")
+		}
+	}
+	knxItems.Validation = report
+	return nil
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+)
+
+const collidingExport = `<GroupAddress-Export>
+  <GroupRange Name="Lights">
+    <GroupRange Name="Hall">
+      <GroupAddress Name="Ceiling" Address="1/1/1" DPTs="DPST-1-1" />
+      <GroupAddress Name="Ceiling" Address="1/1/2" DPTs="DPST-1-1" />
+      <GroupAddress Name="Wall" Address="1/1/3" DPTs="DPST-1-1" />
+      <GroupAddress Name="Wall again" Address="1/1/3" DPTs="DPST-1-1" />
+      <GroupAddress Name="Scene" Address="1/1/4" DPTs="DPST-1-1" />
+      <GroupAddress Name="Scene" Address="1/1/5" DPTs="DPST-1-1" />
+      <GroupAddress Name="Unknown" Address="1/1/6" DPTs="DPST-99-1" />
+    </GroupRange>
+  </GroupRange>
+</GroupAddress-Export>`
+
+func TestValidate(t *testing.T) {
+	parse := func() (*models.KNX, []SkippedAddress) {
+		var export models.XmlGroupAddressExport
+		if err := xml.Unmarshal([]byte(collidingExport), &export); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		return parseExport(export, nil, ImportOptions{GaTranslation: models.FAT_3_parts})
+	}
+
+	knxItems, skipped := parse()
+	report := validationReport(knxItems, skipped)
+	want := models.ValidationReport{
+		DuplicateNames: []models.DuplicateName{
+			{Name: "Lights/Hall/Ceiling", Addresses: []string{"1/1/1", "1/1/2"}},
+			{Name: "Lights/Hall/Scene", Addresses: []string{"1/1/4", "1/1/5"}},
+		},
+		DuplicateAddresses:    []models.DuplicateAddress{{Address: "1/1/3", Names: []string{"Wall", "Wall again"}}},
+		UnsupportedDatapoints: []models.UnsupportedDatapoint{{Address: "1/1/6", Name: "Unknown", Datapoint: "99.001"}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("validationReport() = %+v, want %+v", report, want)
+	}
+
+	if err := validate(knxItems, skipped, models.ValidationWarn); err != nil {
+		t.Errorf("validate() with policy warn error = %v", err)
+	}
+	if !reflect.DeepEqual(knxItems.Validation, want) {
+		t.Errorf("validate() kept %+v, want %+v", knxItems.Validation, want)
+	}
+	if err := validate(knxItems, skipped, models.ValidationFail); err == nil {
+		t.Errorf("expected validate() with policy fail to fail")
+	}
+
+	knxItems, skipped = parse()
+	if err := validate(knxItems, skipped, models.ValidationDisambiguate); err != nil {
+		t.Fatalf("validate() with policy disambiguate error = %v", err)
+	}
+	for name, address := range map[string]string{
+		"Lights/Hall/Ceiling_1_1_1": "1/1/1",
+		"Lights/Hall/Ceiling_1_1_2": "1/1/2",
+		"Lights/Hall/Wall":          "1/1/3",
+	} {
+		if ga, ok := knxItems.GetGroupAddress(name); !ok || ga.Address != address {
+			t.Errorf("expected %s to resolve to %s, got %+v", name, address, ga)
+		}
+	}
+	if _, ok := knxItems.GetGroupAddress("Lights/Hall/Ceiling"); ok {
+		t.Errorf("expected the ambiguous name to be gone")
+	}
+	if !reflect.DeepEqual(knxItems.Validation, want) {
+		t.Errorf("validate() with policy disambiguate kept %+v, want %+v", knxItems.Validation, want)
+	}
+}
+
+func TestValidateIncludesVirtualGroupAddresses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.xml")
+	if err := os.WriteFile(path, []byte(collidingExport), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	virtual := []models.VirtualGroupAddress{{Address: "1/1/7", Name: "Lights/Hall/Wall", Datapoint: "1.001", Topic: "zigbee/wall"}}
+
+	knxItems, err := ReadGroupsFromFile(path, ImportOptions{GaTranslation: models.FAT_3_parts, VirtualGroupAddresses: virtual})
+	if err != nil {
+		t.Fatalf("ReadGroupsFromFile() error = %v", err)
+	}
+	if _, ok := knxItems.GetGroupAddress("Lights/Hall/Wall"); ok {
+		t.Errorf("expected the name shared with the virtual group address to be ambiguous")
+	}
+
+	knxItems, err = ReadGroupsFromFile(path, ImportOptions{GaTranslation: models.FAT_3_parts, VirtualGroupAddresses: virtual,
+		Validation: models.ValidationDisambiguate})
+	if err != nil {
+		t.Fatalf("ReadGroupsFromFile() error = %v", err)
+	}
+	if ga, ok := knxItems.GetGroupAddress("Lights/Hall/Wall_1_1_7"); !ok || ga.Address != "1/1/7" {
+		t.Errorf("expected the virtual group address to be disambiguated, got %+v", ga)
+	}
+}
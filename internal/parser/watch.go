@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// fileVersion identifies the content of a file by its modification time and size.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileVersion, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, false
+	}
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}, true
+}
+
+// WatchFile checks path every interval and signals on the returned channel when the file has
+// changed. A change is only signalled once the file is unchanged for one interval, so that a
+// file still being written is not read.
+func WatchFile(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	last, _ := statFile(path)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		seen := last
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// The file may be missing while it is replaced.
+			version, ok := statFile(path)
+			if !ok || version == last {
+				continue
+			}
+			if version != seen {
+				seen = version
+				continue
+			}
+			last = version
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changed
+}
+
+// WatchFiles watches all paths like WatchFile and signals on the returned channel when any of
+// them has changed.
+func WatchFiles(ctx context.Context, paths []string, interval time.Duration) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	for _, path := range paths {
+		go func(fileChanged <-chan struct{}) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-fileChanged:
+				}
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}(WatchFile(ctx, path, interval))
+	}
+	return changed
+}
@@ -9,15 +9,21 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/pakerfeldt/knx-mqtt/internal/models"
 	"github.com/pakerfeldt/knx-mqtt/internal/utils"
 	"github.com/rs/zerolog/log"
+
+	localdpt "github.com/pakerfeldt/knx-mqtt/internal/dpt"
 )
 
 var regexpDpt = regexp.MustCompile(`DPST-(\d+)-(\d+)`)
 
+// regexpMainDpt matches datapoint types given without subtype, e.g. DPT-9.
+var regexpMainDpt = regexp.MustCompile(`DPT-(\d+)`)
+
 // FileID represents the type of KNX file being processed
 type FileID int
 
@@ -29,81 +35,270 @@ const (
 	GroupAddressExport
 	// ETSExport represents an ETS project export file
 	ETSExport
+	// CSVExport represents an ETS CSV group address export file
+	CSVExport
 )
 
-func ReadGroupsFromFile(filePath string, gaTranslation models.FlatAddressTranslation) (*models.KNX, error) {
-	fileid, byteValue, err := readAndIDFile(filePath)
-	if err != nil {
+// SkippedAddress is a group address of the export that was not imported.
+type SkippedAddress struct {
+	Name      string
+	Address   string
+	Reason    string
+	Datapoint string // Set for unsupported datapoint types
+}
+
+// Reasons for skipping a group address that are part of the validation report.
+const (
+	reasonDuplicateAddress = "duplicate group address"
+	reasonUnsupportedDPT   = "unsupported DPT"
+)
+
+// ImportOptions control how the group addresses of an export are imported.
+type ImportOptions struct {
+	GaTranslation   models.FlatAddressTranslation
+	ProjectPassword string
+	// Installation is the name of the installation imported from ETS project files, the first
+	// installation is imported if empty.
+	Installation string
+	Overrides    []models.GroupAddressOverride
+	// DefaultDatapoints maps main types to the datapoint type used when a group address has
+	// no subtype, e.g. "9" to "9.001". Main types missing use the lowest supported subtype.
+	DefaultDatapoints map[string]string
+	// Validation decides what happens when ReadGroupsFromFiles finds problems.
+	Validation models.ValidationPolicy
+	// VirtualGroupAddresses are added to the merged exports before they are validated.
+	VirtualGroupAddresses []models.VirtualGroupAddress
+}
+
+// exportFile is the content of a group address export or an ETS project export.
+type exportFile struct {
+	id FileID
+	// groups is the XML containing the group addresses.
+	groups []byte
+	// project is the project.xml of an ETS project export, if present.
+	project []byte
+	// hardware are the Hardware.xml files of the manufacturers in an ETS project export.
+	hardware [][]byte
+}
+
+// ReadGroupsFromFiles imports the group addresses of all exports and merges them. It fails if
+// the exports define the same group address differently. The merged group addresses are
+// validated and the problems found are handled according to options.Validation.
+func ReadGroupsFromFiles(exports []models.ETSExport, options ImportOptions) (*models.KNX, error) {
+	knxItems := models.EmptyKNX()
+	var skipped []SkippedAddress
+	for _, export := range exports {
+		options.Installation = export.Installation
+		items, fileSkipped, err := readGroups(export.File, options)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", export.File, err)
+		}
+		if err := knxItems.Merge(items); err != nil {
+			return nil, fmt.Errorf("%s: %w", export.File, err)
+		}
+		skipped = append(skipped, fileSkipped...)
+	}
+	if len(exports) > 1 {
+		log.Info().Int("files", len(exports)).Int("imported", len(knxItems.GroupAddresses)).Msg("Merged ETS exports")
+	}
+	if err := knxItems.AddVirtualGroupAddresses(options.VirtualGroupAddresses); err != nil {
+		return nil, fmt.Errorf("error in virtual group addresses: %w", err)
+	}
+
+	if err := validate(&knxItems, skipped, options.Validation); err != nil {
 		return nil, err
 	}
+	return &knxItems, nil
+}
+
+// ReadGroupsFromFile imports the group addresses of a single export, see ReadGroupsFromFiles.
+func ReadGroupsFromFile(filePath string, options ImportOptions) (*models.KNX, error) {
+	return ReadGroupsFromFiles([]models.ETSExport{{File: filePath, Installation: options.Installation}}, options)
+}
+
+// readGroups imports the group addresses of the export and returns those that were skipped.
+func readGroups(filePath string, options ImportOptions) (*models.KNX, []SkippedAddress, error) {
+	file, err := readAndIDFile(filePath, options.ProjectPassword)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	var export models.XmlGroupAddressExport
+	var locations map[string]groupLocation
+	var devices []models.Device
 
-	switch fileid {
+	switch file.id {
 	case GroupAddressExport:
 		log.Info().Msgf("%s identified as a group address export", filePath)
-		err = xml.Unmarshal(byteValue, &export)
+		err = xml.Unmarshal(file.groups, &export)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 	case ETSExport:
 		log.Info().Msgf("%s identified as a ETS export", filePath)
 		var knxfile models.XmlKNX
-		err = xml.Unmarshal(byteValue, &knxfile)
+		err = xml.Unmarshal(file.groups, &knxfile)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		installations := knxfile.Project.Installations
-		if len(installations) == 0 {
-			return nil, fmt.Errorf("no installations in ETS file")
-		} else if len(installations) > 1 {
-			log.Info().Msgf("Found multiple installations in ETS file; using the first one: \"%s\"", installations[0].Name)
+		installation, err := selectInstallation(knxfile.Project.Installations, options.Installation)
+		if err != nil {
+			return nil, nil, err
 		}
-		export = installations[0].GroupAddresses.GroupRanges
+		export = installation.GroupAddresses.GroupRanges
+		locations = installationLocations(installation)
 
 		// Translate DatapointType -> DPTs
 		translateDatapointTypeToDPTs(&export)
+		devices = installationDevices(installation, productNames(file.hardware), groupAddressIDs(export))
+
+	case CSVExport:
+		log.Info().Msgf("%s identified as a CSV group address export", filePath)
+		export, err = parseCSV(file.groups)
+		if err != nil {
+			return nil, nil, err
+		}
 
 	default:
-		return nil, fmt.Errorf("cannot handle file type %v", fileid)
+		return nil, nil, fmt.Errorf("cannot handle file type %v", file.id)
+	}
+
+	if options.GaTranslation == models.FAT_Auto {
+		options.GaTranslation = projectTranslation(file.project)
+	}
+
+	knxItems, skipped := parseExport(export, locations, options)
+	for _, s := range skipped {
+		// Duplicate addresses and unsupported datapoint types are logged with the validation report.
+		if s.Reason != reasonDuplicateAddress && s.Reason != reasonUnsupportedDPT {
+			log.Warn().Str("address", s.Address).Str("name", s.Name).Str("reason", s.Reason).Msg("Skipped group address")
+		}
+	}
+	log.Info().Int("imported", len(knxItems.GroupAddresses)).Int("skipped", len(skipped)).Msg("Imported group addresses")
+	for _, device := range devices {
+		knxItems.AddDevice(device)
 	}
+	if len(devices) > 0 {
+		log.Info().Int("devices", len(devices)).Msg("Imported devices")
+	}
+	return knxItems, skipped, nil
+}
 
+// selectInstallation returns the installation with the given name, or the first one if name is empty.
+func selectInstallation(installations []models.XmlInstallation, name string) (models.XmlInstallation, error) {
+	if len(installations) == 0 {
+		return models.XmlInstallation{}, fmt.Errorf("no installations in ETS file")
+	}
+	if name == "" {
+		if len(installations) > 1 {
+			log.Info().Msgf("Found multiple installations in ETS file; using the first one: \"%s\"", installations[0].Name)
+		}
+		return installations[0], nil
+	}
+	names := make([]string, 0, len(installations))
+	for _, installation := range installations {
+		if installation.Name == name {
+			return installation, nil
+		}
+		names = append(names, fmt.Sprintf("%q", installation.Name))
+	}
+	return models.XmlInstallation{}, fmt.Errorf("no installation %q in ETS file, found %s", name, strings.Join(names, ", "))
+}
+
+// projectTranslation returns the translation matching the group address style of project.xml.
+func projectTranslation(project []byte) models.FlatAddressTranslation {
+	var projectFile models.XmlProjectFile
+	if project != nil {
+		if err := xml.Unmarshal(project, &projectFile); err != nil {
+			log.Warn().Err(err).Msg("Failed to parse project.xml")
+		}
+	}
+	style := projectFile.Project.Information.GroupAddressStyle
+	translation, ok := models.AddressStyleTranslation(style)
+	if !ok {
+		log.Warn().Str("style", style).Msg("Unknown group address style, using flat group addresses")
+		return models.FAT_None
+	}
+	log.Info().Str("style", style).Msg("Using the group address style of the ETS project")
+	return translation
+}
+
+// parseExport walks the group ranges of export recursively and returns the group addresses
+// found at any depth, with the full name built from the names of all enclosing ranges and
+// the location looked up by their Id. The overrides are applied before deciding whether a
+// group address can be imported, so that they can supply a missing datapoint type.
+func parseExport(export models.XmlGroupAddressExport, locations map[string]groupLocation, options ImportOptions) (*models.KNX, []SkippedAddress) {
 	knxItems := models.EmptyKNX()
-	for _, main := range export.GroupRanges {
-		for _, middle := range main.GroupRanges {
-			for _, address := range middle.Addresses {
-				if address.DPTs == "" {
-					log.Warn().Msgf("%s with address %s did not have a DPT specified and will be ignored", address.Name, address.Address)
-					continue
-				}
-				flatAddr, err := models.ParseGroupAddress(address.Address)
-				if err != nil {
-					return nil, err
-				}
-				translatedAddr := address.Address
-				if utils.IsFlatGroupAddress(address.Address) {
-					translatedAddr = models.TranslateFlatAddress(flatAddr, gaTranslation)
+	var skipped []SkippedAddress
+
+	var addAddresses func(path []string, addresses []models.XmlGroupAddress)
+	addAddresses = func(path []string, addresses []models.XmlGroupAddress) {
+		for _, address := range addresses {
+			flatAddr, err := models.ParseGroupAddress(address.Address)
+			if err != nil {
+				skipped = append(skipped, SkippedAddress{Name: address.Name, Address: address.Address, Reason: err.Error()})
+				continue
+			}
+			if _, exists := knxItems.GadToIndex[flatAddr]; exists {
+				skipped = append(skipped, SkippedAddress{Name: address.Name, Address: address.Address, Reason: reasonDuplicateAddress})
+				continue
+			}
+			translatedAddr := address.Address
+			if utils.IsFlatGroupAddress(address.Address) {
+				translatedAddr = models.TranslateFlatAddress(flatAddr, options.GaTranslation)
+			}
+			name := replaceSlashInName(address.Name)
+			groupAddress := models.GroupAddress{
+				Name:        address.Name,
+				FullName:    strings.Join(append(append([]string{}, path...), name), "/"),
+				Address:     translatedAddr,
+				FlatAddress: flatAddr,
+				Datapoint:   convertDptFormat(address.DPTs, options.DefaultDatapoints),
+			}
+			if location, ok := locations[address.ID]; ok && address.ID != "" {
+				groupAddress.Location = location.spaces
+				groupAddress.Function = location.function
+				groupAddress.LocationName = location.locationName(name)
+			}
+			models.ApplyOverrides(&groupAddress, options.Overrides)
+
+			if groupAddress.Datapoint == "" && address.DPTs == "" {
+				skipped = append(skipped, SkippedAddress{Name: address.Name, Address: address.Address, Reason: "no DPT specified"})
+				continue
+			}
+			if _, ok := localdpt.Produce(groupAddress.Datapoint); !ok {
+				datapoint := groupAddress.Datapoint
+				if datapoint == "" {
+					datapoint = address.DPTs
 				}
-				knxItems.AddGroupAddress(models.GroupAddress{
-					Name:        address.Name,
-					FullName:    fmt.Sprintf("%s/%s/%s", replaceSlashInName(main.Name), replaceSlashInName(middle.Name), replaceSlashInName(address.Name)),
-					Address:     translatedAddr,
-					FlatAddress: flatAddr,
-					Datapoint:   convertDptFormat(address.DPTs),
-				})
+				skipped = append(skipped, SkippedAddress{Name: address.Name, Address: translatedAddr, Reason: reasonUnsupportedDPT, Datapoint: datapoint})
+				continue
 			}
+			knxItems.AddGroupAddress(groupAddress)
 		}
 	}
 
-	return &knxItems, nil
+	var walk func(path []string, groupRange models.XmlGroupRange)
+	walk = func(path []string, groupRange models.XmlGroupRange) {
+		path = append(path, replaceSlashInName(groupRange.Name))
+		addAddresses(path, groupRange.Addresses)
+		for _, child := range groupRange.GroupRanges {
+			walk(path, child)
+		}
+	}
+
+	addAddresses(nil, export.Addresses)
+	for _, groupRange := range export.GroupRanges {
+		walk(nil, groupRange)
+	}
+	return &knxItems, skipped
 }
 
-func readAndIDFile(filePath string) (FileID, []byte, error) {
-	fid := NA
+func readAndIDFile(filePath string, projectPassword string) (*exportFile, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fid, nil, err
+		return nil, err
 	}
 	defer file.Close()
 
@@ -111,43 +306,35 @@ func readAndIDFile(filePath string) (FileID, []byte, error) {
 	magic := make([]byte, 4)
 	n, err := file.Read(magic)
 	if err != nil {
-		return fid, nil, err
+		return nil, err
 	}
 
 	// Reset file position to beginning
 	_, err = file.Seek(0, 0)
 	if err != nil {
-		return fid, nil, err
+		return nil, err
 	}
 
-	var maybeZipReader io.Reader
-	maybeZipReader = file
-
 	// Check if magic equals the ZIP signature 50 4b 03 04
 	// If so, assume this is a ETS project export and look for 0.xml
 	zipSignature := []byte{0x50, 0x4b, 0x03, 0x04}
 	if n == 4 && bytes.Equal(magic, zipSignature) {
 		log.Debug().Msg("ZIP signature detected, using gzip reader")
-
-		rc, err := findGroupsInZip(file)
-		if err != nil {
-			return fid, nil, err
-		}
-		defer rc.Close()
-		maybeZipReader = rc
-		fid = ETSExport
-	} else {
-		fid = GroupAddressExport
+		return readProjectZip(file, projectPassword)
 	}
 
-	byteValue, err := io.ReadAll(maybeZipReader)
+	byteValue, err := io.ReadAll(file)
 	if err != nil {
-		return fid, nil, err
+		return nil, err
 	}
-	return fid, byteValue, nil
+	if !isXML(byteValue) {
+		return &exportFile{id: CSVExport, groups: byteValue}, nil
+	}
+	return &exportFile{id: GroupAddressExport, groups: byteValue}, nil
 }
 
-func findGroupsInZip(file *os.File) (io.ReadCloser, error) {
+// readProjectZip reads 0.xml and project.xml from an ETS project export, which may be password protected.
+func readProjectZip(file *os.File, projectPassword string) (*exportFile, error) {
 	fs, err := file.Stat()
 	if err != nil {
 		return nil, err
@@ -159,22 +346,55 @@ func findGroupsInZip(file *os.File) (io.ReadCloser, error) {
 		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
 	}
 
-	// Find a file "0.xml" in any path within the ZIP file
+	export := &exportFile{id: ETSExport}
+	var protected *zip.File
+	// Find the files "0.xml" and "project.xml" in any path within the ZIP file
 	for _, f := range zipReader.File {
-		// Check if the file name is exactly "0.xml" at any path level
-		if filepath.Base(f.Name) == "0.xml" {
+		if isProtectedProject(f) {
+			protected = f
+			continue
+		}
+		switch filepath.Base(f.Name) {
+		case "0.xml":
 			log.Debug().Msgf("Found 0.xml at path: %s", f.Name)
+			export.groups, err = readZipFile(f)
+		case "project.xml":
+			log.Debug().Msgf("Found project.xml at path: %s", f.Name)
+			export.project, err = readZipFile(f)
+		case "Hardware.xml":
+			log.Debug().Msgf("Found Hardware.xml at path: %s", f.Name)
+			var hardware []byte
+			hardware, err = readZipFile(f)
+			export.hardware = append(export.hardware, hardware)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
 
-			rc, err := f.Open()
-			if err != nil {
-				return nil, fmt.Errorf("failed to open 0.xml file: %w", err)
-			}
-
-			return rc, nil
+	if export.groups == nil && protected != nil {
+		log.Debug().Msgf("Found protected project at path: %s", protected.Name)
+		protectedExport, err := readProtectedProject(protected, projectPassword)
+		if err != nil {
+			return nil, err
 		}
+		// The manufacturer data is not part of the encrypted archive.
+		protectedExport.hardware = export.hardware
+		return protectedExport, nil
 	}
+	if export.groups == nil {
+		return nil, fmt.Errorf("no 0.xml file found in the ZIP archive")
+	}
+	return export, nil
+}
 
-	return nil, fmt.Errorf("no 0.xml file found in the ZIP archive")
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s file: %w", filepath.Base(f.Name), err)
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
 }
 
 func replaceSlashInName(name string) string {
@@ -185,11 +405,22 @@ func replaceSlashInName(name string) string {
 	return newName
 }
 
-func convertDptFormat(dpt string) string {
-	match := regexpDpt.FindStringSubmatch(dpt)
-	// TODO: This must also match 14.1200
-	if match != nil {
-		return fmt.Sprintf("%s.%03s", match[1], match[2])
+// convertDptFormat converts a datapoint type of an ETS export, e.g. DPST-9-1 or DPST-14-1200, into
+// the notation of the datapoint registry, e.g. 9.001 or 14.1200. A main type without subtype, e.g.
+// DPT-9, is mapped to its default datapoint type. It returns an empty string if there is none.
+func convertDptFormat(dpt string, defaults map[string]string) string {
+	if match := regexpDpt.FindStringSubmatch(dpt); match != nil {
+		mainType, _ := strconv.Atoi(match[1])
+		subtype, _ := strconv.Atoi(match[2])
+		return fmt.Sprintf("%d.%03d", mainType, subtype)
+	}
+	if match := regexpMainDpt.FindStringSubmatch(dpt); match != nil {
+		mainType := strings.TrimLeft(match[1], "0")
+		if datapoint, ok := defaults[mainType]; ok {
+			return datapoint
+		}
+		datapoint, _ := localdpt.DefaultDatapoint(mainType)
+		return datapoint
 	}
 	return ""
 }
@@ -0,0 +1,312 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/pakerfeldt/knx-mqtt/internal/secure"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const nestedExport = `<GroupAddress-Export xmlns="http://knx.org/xml/ga-export/01">
+  <GroupRange Name="Lights">
+    <GroupAddress Name="All off" Address="1/0/0" DPTs="DPST-1-1" />
+    <GroupRange Name="Kitchen">
+      <GroupAddress Name="Ceiling" Address="1/1/1" DPTs="DPST-1-1" />
+      <GroupAddress Name="No type" Address="1/1/2" />
+      <GroupAddress Name="Ceiling again" Address="1/1/1" DPTs="DPST-1-1" />
+      <GroupRange Name="Island">
+        <GroupAddress Name="Spots/LED" Address="1/1/3" DPTs="DPST-5-1" />
+      </GroupRange>
+    </GroupRange>
+  </GroupRange>
+  <GroupRange Name="Broken">
+    <GroupAddress Name="Invalid" Address="40/0/0" DPTs="DPST-1-1" />
+  </GroupRange>
+</GroupAddress-Export>`
+
+func TestParseExportWalksNestedRanges(t *testing.T) {
+	var export models.XmlGroupAddressExport
+	if err := xml.Unmarshal([]byte(nestedExport), &export); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	knxItems, skipped := parseExport(export, nil, ImportOptions{GaTranslation: models.FAT_3_parts})
+
+	want := map[string]string{
+		"1/0/0": "Lights/All off",
+		"1/1/1": "Lights/Kitchen/Ceiling",
+		"1/1/3": "Lights/Kitchen/Island/Spots_LED",
+	}
+	if len(knxItems.GroupAddresses) != len(want) {
+		t.Errorf("got %d group addresses, want %d: %+v", len(knxItems.GroupAddresses), len(want), knxItems.GroupAddresses)
+	}
+	for address, fullName := range want {
+		ga, ok := knxItems.GetGroupAddress(address)
+		if !ok {
+			t.Errorf("missing group address %s", address)
+			continue
+		}
+		if ga.FullName != fullName {
+			t.Errorf("FullName of %s = %q, want %q", address, ga.FullName, fullName)
+		}
+	}
+
+	reasons := make(map[string]string)
+	for _, s := range skipped {
+		reasons[s.Name] = s.Reason
+	}
+	for _, name := range []string{"No type", "Ceiling again", "Invalid"} {
+		if reasons[name] == "" {
+			t.Errorf("expected %q to be reported as skipped, got %+v", name, skipped)
+		}
+	}
+}
+
+func TestReadGroupsFromFileUsesProjectAddressStyle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "project.knxproj")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(file)
+	for name, content := range map[string]string{
+		"P-0001/project.xml": `<KNX><Project><ProjectInformation Name="Test" GroupAddressStyle="TwoLevel" /></Project></KNX>`,
+		"P-0001/0.xml": `<KNX><Project><Installations><Installation Name="Home"><GroupAddresses><GroupRanges>
+			<GroupRange Name="Lights"><GroupAddress Name="Hall" Address="2049" DatapointType="DPST-1-1" /></GroupRange>
+			</GroupRanges></GroupAddresses></Installation></Installations></Project></KNX>`,
+	} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	for _, tt := range []struct {
+		translation models.FlatAddressTranslation
+		address     string
+	}{
+		{models.FAT_Auto, "1/1"},
+		{models.FAT_3_parts, "1/0/1"},
+		{models.FAT_None, "2049"},
+	} {
+		knxItems, err := ReadGroupsFromFile(path, ImportOptions{GaTranslation: tt.translation})
+		if err != nil {
+			t.Fatalf("ReadGroupsFromFile() error = %v", err)
+		}
+		if len(knxItems.GroupAddresses) != 1 || knxItems.GroupAddresses[0].Address != tt.address {
+			t.Errorf("translation %d: got %+v, want address %s", tt.translation, knxItems.GroupAddresses, tt.address)
+		}
+	}
+}
+
+func TestReadGroupsFromFileDecryptsProtectedProject(t *testing.T) {
+	const password = "secret"
+	// ETS6 encrypts the project archive with the derived password.
+	derived := secure.ProjectArchivePasswords(password)[1]
+
+	var inner bytes.Buffer
+	archive := zip.NewWriter(&inner)
+	for name, content := range map[string]string{
+		"project.xml": `<KNX><Project><ProjectInformation Name="Test" GroupAddressStyle="ThreeLevel" /></Project></KNX>`,
+		"0.xml": `<KNX><Project><Installations><Installation Name="Home"><GroupAddresses><GroupRanges>
+			<GroupRange Name="Lights"><GroupAddress Name="Hall" Address="2049" DatapointType="DPST-1-1" /></GroupRange>
+			</GroupRanges></GroupAddresses></Installation></Installations></Project></KNX>`,
+	} {
+		writeEncrypted(t, archive, name, []byte(content), derived)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "project.knxproj")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer := zip.NewWriter(file)
+	w, err := outer.Create("P-0001.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(inner.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := outer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if _, err := ReadGroupsFromFile(path, ImportOptions{GaTranslation: models.FAT_Auto}); err == nil {
+		t.Error("expected an error without a password")
+	}
+	if _, err := ReadGroupsFromFile(path, ImportOptions{GaTranslation: models.FAT_Auto, ProjectPassword: "wrong"}); err == nil {
+		t.Error("expected an error with a wrong password")
+	}
+	knxItems, err := ReadGroupsFromFile(path, ImportOptions{GaTranslation: models.FAT_Auto, ProjectPassword: password})
+	if err != nil {
+		t.Fatalf("ReadGroupsFromFile() error = %v", err)
+	}
+	if len(knxItems.GroupAddresses) != 1 || knxItems.GroupAddresses[0].Address != "1/0/1" {
+		t.Errorf("got %+v, want address 1/0/1", knxItems.GroupAddresses)
+	}
+}
+
+// writeEncrypted adds a deflated entry encrypted with WinZip AES-256 to archive.
+func writeEncrypted(t *testing.T, archive *zip.Writer, name string, content []byte, password []byte) {
+	t.Helper()
+	var compressed bytes.Buffer
+	deflater, _ := flate.NewWriter(&compressed, flate.DefaultCompression)
+	deflater.Write(content)
+	deflater.Close()
+
+	const keyLen = 32
+	salt := make([]byte, keyLen/2)
+	rand.Read(salt)
+	derived := pbkdf2.Key(password, salt, 1000, 2*keyLen+2, sha1.New)
+	block, err := aes.NewCipher(derived[:keyLen])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := make([]byte, compressed.Len())
+	var counter, keystream [aes.BlockSize]byte
+	for offset := 0; offset < len(ciphertext); offset += aes.BlockSize {
+		binary.LittleEndian.PutUint64(counter[:], uint64(offset/aes.BlockSize+1))
+		block.Encrypt(keystream[:], counter[:])
+		for i := offset; i < min(offset+aes.BlockSize, len(ciphertext)); i++ {
+			ciphertext[i] = compressed.Bytes()[i] ^ keystream[i-offset]
+		}
+	}
+	mac := hmac.New(sha1.New, derived[keyLen:2*keyLen])
+	mac.Write(ciphertext)
+
+	data := append(append(append(salt, derived[2*keyLen:]...), ciphertext...), mac.Sum(nil)[:10]...)
+	// AE-2, strength 3 (AES-256), deflate.
+	extra := []byte{0x01, 0x99, 0x07, 0x00, 0x02, 0x00, 'A', 'E', 0x03, 0x08, 0x00}
+	w, err := archive.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             99,
+		Flags:              0x1,
+		Extra:              extra,
+		CompressedSize64:   uint64(len(data)),
+		UncompressedSize64: uint64(len(content)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseExportAppliesOverrides(t *testing.T) {
+	var export models.XmlGroupAddressExport
+	if err := xml.Unmarshal([]byte(nestedExport), &export); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	knxItems, _ := parseExport(export, nil, ImportOptions{GaTranslation: models.FAT_3_parts, Overrides: []models.GroupAddressOverride{
+		{Match: []string{"Lights/Kitchen/No type"}, Datapoint: "5.001"},
+		{Match: []string{"1/0/0"}, Alias: "Lights/Off", Ignore: true},
+	}})
+
+	ga, ok := knxItems.GetGroupAddress("1/1/2")
+	if !ok || ga.Datapoint != "5.001" {
+		t.Errorf("expected the override to supply the missing DPT, got %+v", ga)
+	}
+	ga, ok = knxItems.GetGroupAddress("Lights/Off")
+	if !ok || !ga.Ignore {
+		t.Errorf("expected 1/0/0 to be renamed and ignored, got %+v", ga)
+	}
+}
+
+func TestConvertDptFormat(t *testing.T) {
+	defaults := map[string]string{"5": "5.004"}
+	tests := []struct {
+		dpt  string
+		want string
+	}{
+		{"DPST-9-1", "9.001"},
+		{"DPST-14-1200", "14.1200"},
+		{"DPST-1-1 DPST-1-2", "1.001"},
+		{"DPT-9", "9.001"},
+		{"DPT-1", "1.001"},
+		{"DPT-5", "5.004"},
+		{"DPT-9999", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := convertDptFormat(tt.dpt, defaults); got != tt.want {
+			t.Errorf("convertDptFormat(%q) = %q, want %q", tt.dpt, got, tt.want)
+		}
+	}
+}
+
+func TestSelectInstallation(t *testing.T) {
+	installations := []models.XmlInstallation{{Name: "House"}, {Name: "Garage"}}
+	for name, want := range map[string]string{"": "House", "Garage": "Garage"} {
+		installation, err := selectInstallation(installations, name)
+		if err != nil || installation.Name != want {
+			t.Errorf("selectInstallation(%q) = %q, %v, want %q", name, installation.Name, err, want)
+		}
+	}
+	if _, err := selectInstallation(installations, "Shed"); err == nil {
+		t.Errorf("expected an error for a missing installation")
+	}
+	if _, err := selectInstallation(nil, ""); err == nil {
+		t.Errorf("expected an error without installations")
+	}
+}
+
+func TestReadGroupsFromFilesMergesExports(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	house := write("house.xml", `<GroupAddress-Export><GroupRange Name="Lights"><GroupRange Name="House">
+		<GroupAddress Name="Hall" Address="1/0/1" DPTs="DPST-1-1" />
+		<GroupAddress Name="Central" Address="1/0/0" DPTs="DPST-1-1" />
+		</GroupRange></GroupRange></GroupAddress-Export>`)
+	garage := write("garage.xml", `<GroupAddress-Export><GroupRange Name="Lights"><GroupRange Name="House">
+		<GroupAddress Name="Central" Address="1/0/0" DPTs="DPST-1-1" />
+		<GroupAddress Name="Garage" Address="1/0/4" DPTs="DPST-1-1" />
+		</GroupRange></GroupRange></GroupAddress-Export>`)
+	conflicting := write("conflicting.xml", `<GroupAddress-Export><GroupRange Name="Lights"><GroupRange Name="House">
+		<GroupAddress Name="Hall" Address="1/0/1" DPTs="DPST-5-1" />
+		</GroupRange></GroupRange></GroupAddress-Export>`)
+
+	options := ImportOptions{GaTranslation: models.FAT_3_parts}
+	knxItems, err := ReadGroupsFromFiles([]models.ETSExport{{File: house}, {File: garage}}, options)
+	if err != nil {
+		t.Fatalf("ReadGroupsFromFiles() error = %v", err)
+	}
+	if len(knxItems.GroupAddresses) != 3 {
+		t.Errorf("got %d group addresses, want 3: %+v", len(knxItems.GroupAddresses), knxItems.GroupAddresses)
+	}
+
+	if _, err := ReadGroupsFromFiles([]models.ETSExport{{File: house}, {File: conflicting}}, options); err == nil {
+		t.Errorf("expected an error for the conflicting definition of 1/0/1")
+	}
+}
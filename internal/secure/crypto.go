@@ -0,0 +1,133 @@
+package secure
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Salts used by ETS to derive keys from passwords.
+const (
+	keyringSalt                  = "1.keyring.ets.knx.org"
+	userPasswordSalt             = "user-password.1.secure.ip.knx.org"
+	deviceAuthenticationCodeSalt = "device-authentication-code.1.secure.ip.knx.org"
+	projectSalt                  = "21.project.ets.knx.org"
+	passwordIterations           = 65536
+	keyLength                    = 16
+)
+
+// pbkdf2SHA256 derives a key from password and salt using PBKDF2 with HMAC-SHA256 (RFC 8018).
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	return pbkdf2.Key(password, salt, iterations, keyLen, sha256.New)
+}
+
+// keyringPasswordHash derives the key protecting the secrets in an ETS keyring.
+func keyringPasswordHash(password string) []byte {
+	return pbkdf2SHA256([]byte(password), []byte(keyringSalt), passwordIterations, keyLength)
+}
+
+// UserPasswordKey derives the key of a secure tunnel user from its password.
+func UserPasswordKey(password string) []byte {
+	return pbkdf2SHA256([]byte(password), []byte(userPasswordSalt), passwordIterations, keyLength)
+}
+
+// DeviceAuthenticationCode derives the device authentication code from its password.
+func DeviceAuthenticationCode(password string) []byte {
+	return pbkdf2SHA256([]byte(password), []byte(deviceAuthenticationCodeSalt), passwordIterations, keyLength)
+}
+
+// ProjectArchivePasswords returns the candidate passwords of the encrypted archive inside a
+// password protected ETS project. ETS 5 uses the project password as is, while ETS 6 derives
+// the archive password from it.
+func ProjectArchivePasswords(password string) [][]byte {
+	utf16le := make([]byte, 0, 2*len(password))
+	for _, unit := range utf16.Encode([]rune(password)) {
+		utf16le = binary.LittleEndian.AppendUint16(utf16le, unit)
+	}
+	derived := pbkdf2SHA256(utf16le, []byte(projectSalt), passwordIterations, 32)
+	return [][]byte{
+		[]byte(password),
+		[]byte(base64.StdEncoding.EncodeToString(derived)),
+	}
+}
+
+// cbcMAC calculates the CBC-MAC used by KNX AES-CCM. The authenticated data is
+// block0 | len(additionalData) | additionalData | payload, zero padded to the block size.
+func cbcMAC(key, block0, additionalData, payload []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, len(block0)+2+len(additionalData)+len(payload)+aes.BlockSize)
+	data = append(data, block0...)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(additionalData)))
+	data = append(data, additionalData...)
+	data = append(data, payload...)
+	if rem := len(data) % aes.BlockSize; rem != 0 {
+		data = append(data, make([]byte, aes.BlockSize-rem)...)
+	}
+
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(out, data)
+	return out[len(out)-aes.BlockSize:], nil
+}
+
+// ctrCrypt encrypts or decrypts a MAC and payload using AES-CTR. The MAC uses
+// counter0 as its counter block, the payload continues with the following counters.
+func ctrCrypt(key, counter0, mac, payload []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	stream := cipher.NewCTR(block, counter0)
+	outMAC := make([]byte, len(mac))
+	stream.XORKeyStream(outMAC, mac)
+	outPayload := make([]byte, len(payload))
+	stream.XORKeyStream(outPayload, payload)
+	return outMAC, outPayload, nil
+}
+
+// decryptCBC decrypts data with AES-128-CBC without removing any padding.
+func decryptCBC(key, iv, data []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid ciphertext length %d", len(data))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	return out, nil
+}
+
+func xorBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+func equalMAC(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// uint48 encodes the lower 48 bits of value as big endian bytes.
+func uint48(value uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, value)[2:]
+}
+
+// parseUint48 decodes a 6 byte big endian value.
+func parseUint48(data []byte) uint64 {
+	var buf [8]byte
+	copy(buf[2:], data)
+	return binary.BigEndian.Uint64(buf[:])
+}
@@ -0,0 +1,79 @@
+package secure
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		salt       string
+		iterations int
+		keyLen     int
+		want       string
+	}{
+		{
+			name:       "RFC 7914 test vector",
+			password:   "passwd",
+			salt:       "salt",
+			iterations: 1,
+			keyLen:     64,
+			want:       "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783",
+		},
+		{
+			name:       "Keyring password",
+			password:   "password",
+			salt:       keyringSalt,
+			iterations: passwordIterations,
+			keyLen:     keyLength,
+			want:       "574b93fe2641a1dcb67304bcee8b9718",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen)
+			if !bytes.Equal(got, mustHex(t, tt.want)) {
+				t.Errorf("pbkdf2SHA256() = %x, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserPasswordKey(t *testing.T) {
+	want := mustHex(t, "d80161460ae85161477f7d92cf648763")
+	if got := UserPasswordKey("trustme"); !bytes.Equal(got, want) {
+		t.Errorf("UserPasswordKey() = %x, want %x", got, want)
+	}
+}
+
+func TestCTRCryptRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 16)
+	counter0 := append(make([]byte, 14), 0xff, 0x00)
+	mac := bytes.Repeat([]byte{0x01}, 16)
+	payload := []byte("some knx payload that spans more than one block")
+
+	encMAC, encPayload, err := ctrCrypt(key, counter0, mac, payload)
+	if err != nil {
+		t.Fatalf("ctrCrypt() error = %v", err)
+	}
+	decMAC, decPayload, err := ctrCrypt(key, counter0, encMAC, encPayload)
+	if err != nil {
+		t.Fatalf("ctrCrypt() error = %v", err)
+	}
+	if !bytes.Equal(decMAC, mac) || !bytes.Equal(decPayload, payload) {
+		t.Errorf("ctrCrypt() did not round trip: mac %x payload %q", decMAC, decPayload)
+	}
+}
@@ -0,0 +1,311 @@
+package secure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+const (
+	// secureServiceBits are the lower six bits of APCI_SEC (0x03F1), which knx-go
+	// leaves in the first data byte of an Escape APDU.
+	secureServiceBits = 0x31
+	// scfEncrypted is the security control field of an S-A_Data telegram
+	// authenticated and encrypted with AES-CCM.
+	scfEncrypted       = 0x10
+	scfAlgorithmMask   = 0x70
+	scfServiceMask     = 0x07
+	dataSecureMACSize  = 4
+	dataSecureOverhead = 1 + 1 + sequenceSize + dataSecureMACSize
+	maxSequenceNumber  = 1<<48 - 1
+)
+
+// DefaultPersistInterval is how often a changed Data Secure state is written to disk.
+const DefaultPersistInterval = time.Minute
+
+// IsSecureEvent reports whether event carries a KNX Data Secure APDU.
+func IsSecureEvent(event knxgo.GroupEvent) bool {
+	return event.Command == knxgo.GroupCommand(cemi.Escape) && len(event.Data) > 0 && event.Data[0]&0x3f == secureServiceBits
+}
+
+// dataSecureState is the replay protection state written to disk.
+type dataSecureState struct {
+	// SequenceNumber is the last sequence number used for sending.
+	SequenceNumber uint64 `json:"sequenceNumber"`
+	// Received holds the last accepted sequence number per sending device.
+	Received map[string]uint64 `json:"received"`
+}
+
+// DataSecure encrypts and decrypts KNX Data Secure group telegrams (S-A_Data)
+// using the group keys of a keyring.
+type DataSecure struct {
+	mu        sync.Mutex
+	groupKeys map[models.FlatGroupAddress][]byte
+	source    *cemi.IndividualAddr
+	statePath string
+	state     dataSecureState
+	dirty     bool
+}
+
+// NewDataSecure sets up Data Secure for the group keys in keyring. Outgoing telegrams are
+// sent from source, which may be empty if only receiving is required. If statePath is
+// not empty the sequence numbers are loaded from that file and persisted to it by Run and Save.
+func NewDataSecure(keyring *Keyring, source string, statePath string) (*DataSecure, error) {
+	d := &DataSecure{
+		groupKeys: keyring.GroupKeys,
+		statePath: statePath,
+		state:     dataSecureState{Received: make(map[string]uint64)},
+	}
+
+	if source != "" {
+		address, err := cemi.NewIndividualAddrString(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid individual address %q: %w", source, err)
+		}
+		d.source = &address
+	}
+
+	if statePath != "" {
+		data, err := os.ReadFile(statePath)
+		if err == nil {
+			if err := json.Unmarshal(data, &d.state); err != nil {
+				return nil, fmt.Errorf("failed to parse Data Secure state: %w", err)
+			}
+			if d.state.Received == nil {
+				d.state.Received = make(map[string]uint64)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read Data Secure state: %w", err)
+		}
+	}
+
+	// Never fall behind the counter known to ETS or a clock based counter, so that
+	// a lost state file does not cause our telegrams to be rejected as replays.
+	if device, ok := keyring.Devices[source]; ok && device.SequenceNumber > d.state.SequenceNumber {
+		d.state.SequenceNumber = device.SequenceNumber
+	}
+	if now := uint64(time.Now().UnixMilli()) & maxSequenceNumber; now > d.state.SequenceNumber {
+		d.state.SequenceNumber = now
+	}
+
+	return d, nil
+}
+
+// IsSecure reports whether destination is a Data Secure group address.
+func (d *DataSecure) IsSecure(destination cemi.GroupAddr) bool {
+	_, ok := d.groupKeys[models.FlatGroupAddress(destination)]
+	return ok
+}
+
+// Decrypt authenticates and decrypts a secure group telegram and returns the plain event.
+// Telegrams with a sequence number not newer than the last one accepted from the same
+// source are rejected as replays.
+func (d *DataSecure) Decrypt(event knxgo.GroupEvent) (knxgo.GroupEvent, error) {
+	key, ok := d.groupKeys[models.FlatGroupAddress(event.Destination)]
+	if !ok {
+		return knxgo.GroupEvent{}, fmt.Errorf("no Data Secure key for group address %s", event.Destination)
+	}
+	if len(event.Data) < dataSecureOverhead+2 {
+		return knxgo.GroupEvent{}, errors.New("secure APDU too short")
+	}
+
+	scf := event.Data[1]
+	if scf&scfServiceMask != 0 {
+		return knxgo.GroupEvent{}, fmt.Errorf("unsupported secure service %#02x", scf)
+	}
+	if scf&scfAlgorithmMask != scfEncrypted {
+		return knxgo.GroupEvent{}, fmt.Errorf("unsupported security algorithm %#02x", scf)
+	}
+	sequence := event.Data[2 : 2+sequenceSize]
+	encrypted := event.Data[2+sequenceSize : len(event.Data)-dataSecureMACSize]
+	encryptedMAC := make([]byte, macSize)
+	copy(encryptedMAC, event.Data[len(event.Data)-dataSecureMACSize:])
+
+	mac, apdu, err := ctrCrypt(key, dataSecureCounter0(sequence, event.Source, event.Destination), encryptedMAC, encrypted)
+	if err != nil {
+		return knxgo.GroupEvent{}, err
+	}
+	expected, err := cbcMAC(key, dataSecureBlock0(sequence, event.Source, event.Destination, len(apdu)), []byte{scf}, apdu)
+	if err != nil {
+		return knxgo.GroupEvent{}, err
+	}
+	if !equalMAC(mac[:dataSecureMACSize], expected[:dataSecureMACSize]) {
+		return knxgo.GroupEvent{}, errors.New("MAC mismatch")
+	}
+
+	if err := d.accept(event.Source, parseUint48(sequence)); err != nil {
+		return knxgo.GroupEvent{}, err
+	}
+
+	command, data := splitAPDU(apdu)
+	return knxgo.GroupEvent{
+		Command:     command,
+		Source:      event.Source,
+		Destination: event.Destination,
+		Data:        data,
+	}, nil
+}
+
+// accept records sequence as the latest from source if it is newer than the previous one.
+func (d *DataSecure) accept(source cemi.IndividualAddr, sequence uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if last, ok := d.state.Received[source.String()]; ok && sequence <= last {
+		return fmt.Errorf("replayed sequence number %d from %s", sequence, source)
+	}
+	d.state.Received[source.String()] = sequence
+	d.dirty = true
+	return nil
+}
+
+// Encrypt turns a plain group event into a secure group telegram sent from the configured source.
+func (d *DataSecure) Encrypt(event knxgo.GroupEvent) (knxgo.GroupEvent, error) {
+	key, ok := d.groupKeys[models.FlatGroupAddress(event.Destination)]
+	if !ok {
+		return knxgo.GroupEvent{}, fmt.Errorf("no Data Secure key for group address %s", event.Destination)
+	}
+	if d.source == nil {
+		return knxgo.GroupEvent{}, errors.New("no individual address configured for sending Data Secure telegrams")
+	}
+
+	sequence := uint48(d.nextSequence())
+	apdu := joinAPDU(event.Command, event.Data)
+
+	mac, err := cbcMAC(key, dataSecureBlock0(sequence, *d.source, event.Destination, len(apdu)), []byte{scfEncrypted}, apdu)
+	if err != nil {
+		return knxgo.GroupEvent{}, err
+	}
+	mac, encrypted, err := ctrCrypt(key, dataSecureCounter0(sequence, *d.source, event.Destination), mac, apdu)
+	if err != nil {
+		return knxgo.GroupEvent{}, err
+	}
+
+	data := make([]byte, 0, dataSecureOverhead+len(encrypted))
+	data = append(data, secureServiceBits, scfEncrypted)
+	data = append(data, sequence...)
+	data = append(data, encrypted...)
+	data = append(data, mac[:dataSecureMACSize]...)
+
+	return knxgo.GroupEvent{
+		Command:     knxgo.GroupCommand(cemi.Escape),
+		Source:      *d.source,
+		Destination: event.Destination,
+		Data:        data,
+	}, nil
+}
+
+func (d *DataSecure) nextSequence() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state.SequenceNumber++
+	d.dirty = true
+	return d.state.SequenceNumber
+}
+
+// Run persists the state every interval while it has changes until ctx is done. Sequence numbers
+// sent since the last write are not lost on a crash, as the next one is never behind the clock.
+func (d *DataSecure) Run(ctx context.Context, interval time.Duration) {
+	if d.statePath == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultPersistInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Save(); err != nil {
+				log.Error().Err(err).Msg("Failed to write Data Secure state")
+			}
+		}
+	}
+}
+
+// Save writes the state to disk if it changed since it was last saved.
+func (d *DataSecure) Save() error {
+	if d.statePath == "" {
+		return nil
+	}
+
+	d.mu.Lock()
+	if !d.dirty {
+		d.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(d.state)
+	d.dirty = false
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated state behind.
+	tmp, err := os.CreateTemp(filepath.Dir(d.statePath), filepath.Base(d.statePath)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), d.statePath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		d.mu.Lock()
+		d.dirty = true
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// dataSecureBlock0 returns the first CBC-MAC block of an S-A_Data telegram to a group address.
+func dataSecureBlock0(sequence []byte, source cemi.IndividualAddr, destination cemi.GroupAddr, payloadLength int) []byte {
+	block := make([]byte, 0, 16)
+	block = append(block, sequence...)
+	block = append(block, byte(source>>8), byte(source), byte(destination>>8), byte(destination))
+	// Address type group and standard frame format, followed by TPCI/APCI_SEC.
+	block = append(block, 0x00, 0x80, 0x03, 0xf1)
+	return append(block, 0x00, byte(payloadLength))
+}
+
+// dataSecureCounter0 returns the first CTR counter block of an S-A_Data telegram.
+func dataSecureCounter0(sequence []byte, source cemi.IndividualAddr, destination cemi.GroupAddr) []byte {
+	counter := make([]byte, 0, 16)
+	counter = append(counter, sequence...)
+	counter = append(counter, byte(source>>8), byte(source), byte(destination>>8), byte(destination))
+	return append(counter, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00)
+}
+
+// joinAPDU packs a group command and its data into the APDU that is protected by Data Secure.
+func joinAPDU(command knxgo.GroupCommand, data []byte) []byte {
+	apdu := []byte{byte(command>>2) & 3, byte(command&3) << 6}
+	if len(data) > 0 {
+		apdu[1] |= data[0] & 0x3f
+		apdu = append(apdu, data[1:]...)
+	}
+	return apdu
+}
+
+// splitAPDU is the inverse of joinAPDU.
+func splitAPDU(apdu []byte) (knxgo.GroupCommand, []byte) {
+	command := knxgo.GroupCommand((apdu[0]&3)<<2 | apdu[1]>>6)
+	data := append([]byte{apdu[1] & 0x3f}, apdu[2:]...)
+	return command, data
+}
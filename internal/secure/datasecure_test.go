@@ -0,0 +1,207 @@
+package secure
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+var (
+	secureGroup = cemi.NewGroupAddr3(1, 2, 3)
+	plainGroup  = cemi.NewGroupAddr3(1, 2, 4)
+)
+
+func testDataSecureKeyring() *Keyring {
+	return &Keyring{
+		Devices: map[string]Device{},
+		GroupKeys: map[models.FlatGroupAddress][]byte{
+			models.FlatGroupAddress(secureGroup): bytes.Repeat([]byte{0x5a}, 16),
+		},
+	}
+}
+
+func newTestDataSecure(t *testing.T, source string, statePath string) *DataSecure {
+	t.Helper()
+	d, err := NewDataSecure(testDataSecureKeyring(), source, statePath)
+	if err != nil {
+		t.Fatalf("NewDataSecure() error = %v", err)
+	}
+	return d
+}
+
+func TestDataSecureRoundTrip(t *testing.T) {
+	sender := newTestDataSecure(t, "1.1.5", "")
+	receiver := newTestDataSecure(t, "", "")
+
+	tests := []struct {
+		name  string
+		event knxgo.GroupEvent
+	}{
+		{"Write small value", knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: secureGroup, Data: []byte{1}}},
+		{"Write two bytes", knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: secureGroup, Data: []byte{0, 0x0c, 0x1a}}},
+		{"Response", knxgo.GroupEvent{Command: knxgo.GroupResponse, Destination: secureGroup, Data: []byte{0, 42}}},
+		{"Read", knxgo.GroupEvent{Command: knxgo.GroupRead, Destination: secureGroup, Data: []byte{0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := sender.Encrypt(tt.event)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if !IsSecureEvent(encrypted) {
+				t.Fatalf("expected a secure event, got %+v", encrypted)
+			}
+			if encrypted.Source != cemi.NewIndividualAddr3(1, 1, 5) {
+				t.Errorf("Source = %s, want 1.1.5", encrypted.Source)
+			}
+
+			decrypted, err := receiver.Decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if decrypted.Command != tt.event.Command || !bytes.Equal(decrypted.Data, tt.event.Data) {
+				t.Errorf("Decrypt() = %+v, want %+v", decrypted, tt.event)
+			}
+		})
+	}
+}
+
+func TestDataSecureRejectsTampering(t *testing.T) {
+	sender := newTestDataSecure(t, "1.1.5", "")
+	encrypted, err := sender.Encrypt(knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: secureGroup, Data: []byte{1}})
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	modify := func(change func(event *knxgo.GroupEvent)) knxgo.GroupEvent {
+		event := encrypted
+		event.Data = append([]byte(nil), encrypted.Data...)
+		change(&event)
+		return event
+	}
+	tests := []struct {
+		name  string
+		event knxgo.GroupEvent
+	}{
+		{"Payload", modify(func(e *knxgo.GroupEvent) { e.Data[len(e.Data)-5] ^= 1 })},
+		{"MAC", modify(func(e *knxgo.GroupEvent) { e.Data[len(e.Data)-1] ^= 1 })},
+		{"Sequence number", modify(func(e *knxgo.GroupEvent) { e.Data[7] ^= 1 })},
+		{"Source", modify(func(e *knxgo.GroupEvent) { e.Source = cemi.NewIndividualAddr3(1, 1, 6) })},
+		{"Authentication only", modify(func(e *knxgo.GroupEvent) { e.Data[1] = 0x00 })},
+		{"Too short", modify(func(e *knxgo.GroupEvent) { e.Data = e.Data[:8] })},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newTestDataSecure(t, "", "").Decrypt(tt.event); err == nil {
+				t.Error("expected Decrypt() to fail")
+			}
+		})
+	}
+
+	t.Run("Unknown group key", func(t *testing.T) {
+		event := modify(func(e *knxgo.GroupEvent) { e.Destination = plainGroup })
+		if _, err := newTestDataSecure(t, "", "").Decrypt(event); err == nil {
+			t.Error("expected Decrypt() to fail")
+		}
+	})
+}
+
+func TestDataSecureReplayProtection(t *testing.T) {
+	sender := newTestDataSecure(t, "1.1.5", "")
+	receiver := newTestDataSecure(t, "", "")
+	event := knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: secureGroup, Data: []byte{1}}
+
+	first, _ := sender.Encrypt(event)
+	second, _ := sender.Encrypt(event)
+
+	if _, err := receiver.Decrypt(second); err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if _, err := receiver.Decrypt(second); err == nil {
+		t.Error("expected replayed telegram to be rejected")
+	}
+	if _, err := receiver.Decrypt(first); err == nil {
+		t.Error("expected older telegram to be rejected")
+	}
+}
+
+func TestDataSecurePersistsState(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "data-secure.json")
+	sender := newTestDataSecure(t, "1.1.5", "")
+	event := knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: secureGroup, Data: []byte{1}}
+
+	old, _ := sender.Encrypt(event)
+	receiver := newTestDataSecure(t, "1.1.1", statePath)
+	if _, err := receiver.Decrypt(old); err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	sent, err := receiver.Encrypt(event)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if _, err := os.Stat(statePath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("state written before Save(), Stat() error = %v", err)
+	}
+	if err := receiver.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	restarted := newTestDataSecure(t, "1.1.1", statePath)
+	if _, err := restarted.Decrypt(old); err == nil {
+		t.Error("expected telegram replayed after restart to be rejected")
+	}
+	next, err := restarted.Encrypt(event)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if parseUint48(next.Data[2:8]) <= parseUint48(sent.Data[2:8]) {
+		t.Errorf("sequence number %d after restart is not greater than %d", parseUint48(next.Data[2:8]), parseUint48(sent.Data[2:8]))
+	}
+}
+
+func TestDataSecureEncryptRequiresSource(t *testing.T) {
+	d := newTestDataSecure(t, "", "")
+	if _, err := d.Encrypt(knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: secureGroup, Data: []byte{1}}); err == nil {
+		t.Error("expected Encrypt() to fail without a source address")
+	}
+}
+
+func TestGroupEventFromMessageAcceptsSecureAPDU(t *testing.T) {
+	sender := newTestDataSecure(t, "1.1.5", "")
+	encrypted, err := sender.Encrypt(knxgo.GroupEvent{Command: knxgo.GroupWrite, Destination: secureGroup, Data: []byte{1}})
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	// Pack and unpack the frame like it travels over the bus.
+	ind := &cemi.LDataInd{LData: BuildGroupOutbound(encrypted)}
+	buffer := make([]byte, ind.Size())
+	ind.Pack(buffer)
+	if buffer[len(buffer)-len(encrypted.Data)-1] != 0x03 || buffer[len(buffer)-len(encrypted.Data)] != 0xf1 {
+		t.Errorf("expected APCI_SEC in packed frame %x", buffer)
+	}
+	var unpacked cemi.LDataInd
+	if _, err := unpacked.Unpack(buffer); err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+
+	event, ok := GroupEventFromMessage(&unpacked)
+	if !ok {
+		t.Fatal("expected secure APDU to be accepted")
+	}
+	decrypted, err := newTestDataSecure(t, "", "").Decrypt(event)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if decrypted.Command != knxgo.GroupWrite || !bytes.Equal(decrypted.Data, []byte{1}) {
+		t.Errorf("unexpected decrypted event %+v", decrypted)
+	}
+}
@@ -0,0 +1,291 @@
+package secure
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/vapourismo/knx-go/knx/knxnet"
+)
+
+// KNXnet/IP Secure services.
+const (
+	SecureWrapperService       knxnet.ServiceID = 0x0950
+	SessionRequestService      knxnet.ServiceID = 0x0951
+	SessionResponseService     knxnet.ServiceID = 0x0952
+	SessionAuthenticateService knxnet.ServiceID = 0x0953
+	SessionStatusService       knxnet.ServiceID = 0x0954
+	TimerNotifyService         knxnet.ServiceID = 0x0955
+)
+
+// SessionStatus is the status reported in a SESSION_STATUS frame.
+type SessionStatus uint8
+
+const (
+	StatusAuthenticationSuccess SessionStatus = 0
+	StatusAuthenticationFailed  SessionStatus = 1
+	StatusUnauthenticated       SessionStatus = 2
+	StatusTimeout               SessionStatus = 3
+	StatusKeepAlive             SessionStatus = 4
+	StatusClose                 SessionStatus = 5
+)
+
+func (s SessionStatus) String() string {
+	switch s {
+	case StatusAuthenticationSuccess:
+		return "authentication success"
+	case StatusAuthenticationFailed:
+		return "authentication failed"
+	case StatusUnauthenticated:
+		return "unauthenticated"
+	case StatusTimeout:
+		return "timeout"
+	case StatusKeepAlive:
+		return "keep alive"
+	case StatusClose:
+		return "close"
+	default:
+		return fmt.Sprintf("unknown status %d", uint8(s))
+	}
+}
+
+const (
+	publicKeySize  = 32
+	macSize        = 16
+	sequenceSize   = 6
+	serialSize     = 6
+	wrapperSize    = 2 + sequenceSize + serialSize + 2 + macSize
+	headerSize     = 6
+	hostInfoSize   = 8
+	messageTagSize = 2
+)
+
+// header returns the KNXnet/IP header of a frame with the given service and total length.
+func header(service knxnet.ServiceID, totalLength int) []byte {
+	return []byte{6, 0x10, byte(service >> 8), byte(service), byte(totalLength >> 8), byte(totalLength)}
+}
+
+// SessionReq starts a secure session with the client's public key.
+type SessionReq struct {
+	Control   knxnet.HostInfo
+	PublicKey []byte
+}
+
+func (SessionReq) Service() knxnet.ServiceID { return SessionRequestService }
+
+func (SessionReq) Size() uint { return hostInfoSize + publicKeySize }
+
+func (req *SessionReq) Pack(buffer []byte) {
+	req.Control.Pack(buffer)
+	copy(buffer[hostInfoSize:], req.PublicKey)
+}
+
+// SessionRes is the server's answer to a SessionReq.
+type SessionRes struct {
+	SessionID uint16
+	PublicKey []byte
+	MAC       []byte
+}
+
+func (res *SessionRes) Unpack(data []byte) error {
+	if len(data) < 2+publicKeySize+macSize {
+		return errors.New("session response too short")
+	}
+	res.SessionID = binary.BigEndian.Uint16(data)
+	res.PublicKey = append([]byte(nil), data[2:2+publicKeySize]...)
+	res.MAC = append([]byte(nil), data[2+publicKeySize:2+publicKeySize+macSize]...)
+	return nil
+}
+
+// SessionAuth authenticates a user within a secure session.
+type SessionAuth struct {
+	UserID uint8
+	MAC    []byte
+}
+
+func (SessionAuth) Service() knxnet.ServiceID { return SessionAuthenticateService }
+
+func (SessionAuth) Size() uint { return 2 + macSize }
+
+func (auth *SessionAuth) Pack(buffer []byte) {
+	buffer[0] = 0
+	buffer[1] = auth.UserID
+	copy(buffer[2:], auth.MAC)
+}
+
+// SessionStat reports the state of a secure session.
+type SessionStat struct {
+	Status SessionStatus
+}
+
+func (SessionStat) Service() knxnet.ServiceID { return SessionStatusService }
+
+func (SessionStat) Size() uint { return 2 }
+
+func (stat *SessionStat) Pack(buffer []byte) {
+	buffer[0] = byte(stat.Status)
+	buffer[1] = 0
+}
+
+func (stat *SessionStat) Unpack(data []byte) error {
+	if len(data) < 1 {
+		return errors.New("session status too short")
+	}
+	stat.Status = SessionStatus(data[0])
+	return nil
+}
+
+// Wrapper is a SECURE_WRAPPER frame carrying an encrypted KNXnet/IP frame.
+type Wrapper struct {
+	SessionID  uint16
+	Sequence   []byte
+	Serial     []byte
+	MessageTag uint16
+	Encrypted  []byte
+	MAC        []byte
+}
+
+func (Wrapper) Service() knxnet.ServiceID { return SecureWrapperService }
+
+func (w *Wrapper) Size() uint { return uint(wrapperSize + len(w.Encrypted)) }
+
+func (w *Wrapper) Pack(buffer []byte) {
+	binary.BigEndian.PutUint16(buffer, w.SessionID)
+	copy(buffer[2:], w.Sequence)
+	copy(buffer[2+sequenceSize:], w.Serial)
+	binary.BigEndian.PutUint16(buffer[2+sequenceSize+serialSize:], w.MessageTag)
+	copy(buffer[2+sequenceSize+serialSize+messageTagSize:], w.Encrypted)
+	copy(buffer[2+sequenceSize+serialSize+messageTagSize+len(w.Encrypted):], w.MAC)
+}
+
+func (w *Wrapper) Unpack(data []byte) error {
+	if len(data) < wrapperSize {
+		return errors.New("secure wrapper too short")
+	}
+	w.SessionID = binary.BigEndian.Uint16(data)
+	w.Sequence = append([]byte(nil), data[2:2+sequenceSize]...)
+	w.Serial = append([]byte(nil), data[2+sequenceSize:2+sequenceSize+serialSize]...)
+	w.MessageTag = binary.BigEndian.Uint16(data[2+sequenceSize+serialSize:])
+	encryptedEnd := len(data) - macSize
+	w.Encrypted = append([]byte(nil), data[2+sequenceSize+serialSize+messageTagSize:encryptedEnd]...)
+	w.MAC = append([]byte(nil), data[encryptedEnd:]...)
+	return nil
+}
+
+// counter0 returns the first CTR counter block of the frame.
+func (w *Wrapper) counter0() []byte {
+	counter := make([]byte, 0, 16)
+	counter = append(counter, w.Sequence...)
+	counter = append(counter, w.Serial...)
+	counter = binary.BigEndian.AppendUint16(counter, w.MessageTag)
+	return append(counter, 0xff, 0x00)
+}
+
+// block0 returns the first CBC-MAC block of the frame for a payload of the given length.
+func (w *Wrapper) block0(payloadLength int) []byte {
+	block := make([]byte, 0, 16)
+	block = append(block, w.Sequence...)
+	block = append(block, w.Serial...)
+	block = binary.BigEndian.AppendUint16(block, w.MessageTag)
+	return binary.BigEndian.AppendUint16(block, uint16(payloadLength))
+}
+
+// Seal encrypts the packed KNXnet/IP frame plain into the wrapper using key.
+func (w *Wrapper) Seal(key []byte, plain []byte) error {
+	w.Encrypted = make([]byte, len(plain))
+	additional := append(header(SecureWrapperService, headerSize+int(w.Size())), byte(w.SessionID>>8), byte(w.SessionID))
+	mac, err := cbcMAC(key, w.block0(len(plain)), additional, plain)
+	if err != nil {
+		return err
+	}
+	w.MAC, w.Encrypted, err = ctrCrypt(key, w.counter0(), mac, plain)
+	return err
+}
+
+// Open decrypts and authenticates the wrapped KNXnet/IP frame using key.
+func (w *Wrapper) Open(key []byte) ([]byte, error) {
+	mac, plain, err := ctrCrypt(key, w.counter0(), w.MAC, w.Encrypted)
+	if err != nil {
+		return nil, err
+	}
+	additional := append(header(SecureWrapperService, headerSize+int(w.Size())), byte(w.SessionID>>8), byte(w.SessionID))
+	expected, err := cbcMAC(key, w.block0(len(plain)), additional, plain)
+	if err != nil {
+		return nil, err
+	}
+	if !equalMAC(mac, expected) {
+		return nil, errors.New("secure wrapper MAC mismatch")
+	}
+	return plain, nil
+}
+
+// TimerNotify synchronises the multicast timer of secure routing devices.
+type TimerNotify struct {
+	Timer      []byte
+	Serial     []byte
+	MessageTag uint16
+	MAC        []byte
+}
+
+func (TimerNotify) Service() knxnet.ServiceID { return TimerNotifyService }
+
+func (TimerNotify) Size() uint { return sequenceSize + serialSize + messageTagSize + macSize }
+
+func (t *TimerNotify) Pack(buffer []byte) {
+	copy(buffer, t.Timer)
+	copy(buffer[sequenceSize:], t.Serial)
+	binary.BigEndian.PutUint16(buffer[sequenceSize+serialSize:], t.MessageTag)
+	copy(buffer[sequenceSize+serialSize+messageTagSize:], t.MAC)
+}
+
+func (t *TimerNotify) Unpack(data []byte) error {
+	if len(data) < int(t.Size()) {
+		return errors.New("timer notify too short")
+	}
+	t.Timer = append([]byte(nil), data[:sequenceSize]...)
+	t.Serial = append([]byte(nil), data[sequenceSize:sequenceSize+serialSize]...)
+	t.MessageTag = binary.BigEndian.Uint16(data[sequenceSize+serialSize:])
+	t.MAC = append([]byte(nil), data[sequenceSize+serialSize+messageTagSize:sequenceSize+serialSize+messageTagSize+macSize]...)
+	return nil
+}
+
+func (t *TimerNotify) wrapper() *Wrapper {
+	return &Wrapper{Sequence: t.Timer, Serial: t.Serial, MessageTag: t.MessageTag}
+}
+
+// Sign calculates the MAC of the timer notification using the backbone key.
+func (t *TimerNotify) Sign(key []byte) error {
+	w := t.wrapper()
+	mac, err := cbcMAC(key, w.block0(0), header(TimerNotifyService, headerSize+int(t.Size())), nil)
+	if err != nil {
+		return err
+	}
+	t.MAC, _, err = ctrCrypt(key, w.counter0(), mac, nil)
+	return err
+}
+
+// Verify checks the MAC of the timer notification using the backbone key.
+func (t *TimerNotify) Verify(key []byte) error {
+	w := t.wrapper()
+	mac, _, err := ctrCrypt(key, w.counter0(), t.MAC, nil)
+	if err != nil {
+		return err
+	}
+	expected, err := cbcMAC(key, w.block0(0), header(TimerNotifyService, headerSize+int(t.Size())), nil)
+	if err != nil {
+		return err
+	}
+	if !equalMAC(mac, expected) {
+		return errors.New("timer notify MAC mismatch")
+	}
+	return nil
+}
+
+// unknownService returns the raw body of a service that knx-go does not know about.
+func unknownService(srv knxnet.Service, id knxnet.ServiceID) ([]byte, bool) {
+	unknown, ok := srv.(*knxnet.UnknownService)
+	if !ok || unknown.Service() != id {
+		return nil, false
+	}
+	return unknown.Data, true
+}
@@ -0,0 +1,103 @@
+package secure
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/vapourismo/knx-go/knx/knxnet"
+)
+
+func TestWrapperSealOpen(t *testing.T) {
+	key := bytes.Repeat([]byte{0x11}, 16)
+	plain := knxnet.AllocAndPack(&knxnet.ConnStateReq{Channel: 3})
+
+	sealed := &Wrapper{
+		SessionID:  7,
+		Sequence:   uint48(42),
+		Serial:     []byte{0x00, 0xfa, 0x12, 0x34, 0x56, 0x78},
+		MessageTag: 0xaffe,
+	}
+	if err := sealed.Seal(key, plain); err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if bytes.Equal(sealed.Encrypted, plain) {
+		t.Fatal("expected payload to be encrypted")
+	}
+
+	var received Wrapper
+	if err := received.Unpack(knxnet.AllocAndPack(sealed)[headerSize:]); err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+	got, err := received.Open(key)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("Open() = %x, want %x", got, plain)
+	}
+
+	tampered := received
+	tampered.Encrypted = append([]byte(nil), received.Encrypted...)
+	tampered.Encrypted[0] ^= 0x01
+	if _, err := tampered.Open(key); err == nil {
+		t.Error("expected MAC mismatch for modified payload")
+	}
+
+	tampered = received
+	tampered.SessionID = 8
+	if _, err := tampered.Open(key); err == nil {
+		t.Error("expected MAC mismatch for modified session id")
+	}
+
+	if _, err := received.Open(bytes.Repeat([]byte{0x22}, 16)); err == nil {
+		t.Error("expected MAC mismatch for wrong key")
+	}
+}
+
+func TestTimerNotifySignVerify(t *testing.T) {
+	key := bytes.Repeat([]byte{0x33}, 16)
+	notify := &TimerNotify{
+		Timer:      uint48(123456789),
+		Serial:     []byte{1, 2, 3, 4, 5, 6},
+		MessageTag: 0x0102,
+	}
+	if err := notify.Sign(key); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	var received TimerNotify
+	if err := received.Unpack(knxnet.AllocAndPack(notify)[headerSize:]); err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+	if err := received.Verify(key); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if parseUint48(received.Timer) != 123456789 {
+		t.Errorf("Timer = %d, want 123456789", parseUint48(received.Timer))
+	}
+
+	received.Timer = uint48(123456790)
+	if err := received.Verify(key); err == nil {
+		t.Error("expected MAC mismatch for modified timer")
+	}
+}
+
+func TestMulticastTimer(t *testing.T) {
+	timer := newMulticastTimer()
+	first := timer.next()
+	second := timer.next()
+	if second <= first {
+		t.Errorf("next() = %d after %d, want strictly increasing", second, first)
+	}
+
+	timer.adopt(first + 1_000_000)
+	if value := timer.value(); value < first+1_000_000 {
+		t.Errorf("value() = %d after adopting %d", value, first+1_000_000)
+	}
+
+	before := timer.value()
+	timer.adopt(1)
+	if value := timer.value(); value < before {
+		t.Errorf("timer went backwards from %d to %d", before, value)
+	}
+}
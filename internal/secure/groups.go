@@ -0,0 +1,52 @@
+package secure
+
+import (
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// defaultGroupLData mirrors the frame settings knx-go uses for group communication.
+var defaultGroupLData = cemi.LData{
+	Control1: cemi.Control1NoRepeat | cemi.Control1NoSysBroadcast | cemi.Control1WantAck | cemi.Control1Prio(cemi.PrioLow),
+	Control2: cemi.Control2GroupAddr | cemi.Control2Hops(6),
+}
+
+// BuildGroupOutbound constructs the L_Data frame for a group event.
+func BuildGroupOutbound(event knxgo.GroupEvent) cemi.LData {
+	ldata := defaultGroupLData
+	ldata.Data = &cemi.AppData{
+		Command: cemi.APCI(event.Command),
+		Data:    event.Data,
+	}
+	ldata.Source = event.Source
+	ldata.Destination = uint16(event.Destination)
+
+	if len(event.Data) <= 15 {
+		ldata.Control1 |= cemi.Control1StdFrame
+	}
+
+	return ldata
+}
+
+// GroupEventFromMessage extracts a group event from an L_Data.ind frame.
+// Besides plain group commands it accepts KNX Data Secure telegrams, see IsSecureEvent.
+func GroupEventFromMessage(message cemi.Message) (knxgo.GroupEvent, bool) {
+	ind, ok := message.(*cemi.LDataInd)
+	if !ok || !ind.Control2.IsGroupAddr() {
+		return knxgo.GroupEvent{}, false
+	}
+	app, ok := ind.Data.(*cemi.AppData)
+	if !ok {
+		return knxgo.GroupEvent{}, false
+	}
+	event := knxgo.GroupEvent{
+		Command:     knxgo.GroupCommand(app.Command),
+		Source:      ind.Source,
+		Destination: cemi.GroupAddr(ind.Destination),
+		Data:        app.Data,
+	}
+	if !app.Command.IsGroupCommand() && !IsSecureEvent(event) {
+		return knxgo.GroupEvent{}, false
+	}
+	return event, true
+}
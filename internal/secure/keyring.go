@@ -0,0 +1,339 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// Keyring holds the decrypted secrets of an ETS keyring export (.knxkeys).
+type Keyring struct {
+	Project    string
+	Created    string
+	Backbone   *Backbone
+	Interfaces []Interface
+	Devices    map[string]Device
+	GroupKeys  map[models.FlatGroupAddress][]byte
+}
+
+// Backbone holds the settings of a secure IP backbone (KNXnet/IP Secure routing).
+type Backbone struct {
+	MulticastAddress string
+	Latency          time.Duration
+	Key              []byte
+}
+
+// Interface is a tunnelling, USB or backbone interface as defined in the keyring.
+type Interface struct {
+	Type              string
+	Host              string
+	IndividualAddress string
+	UserID            uint8
+	Password          string
+	Authentication    string
+	Groups            map[models.FlatGroupAddress][]string
+}
+
+// Device holds the secrets of a device in the keyring.
+type Device struct {
+	IndividualAddress  string
+	SerialNumber       string
+	ToolKey            []byte
+	SequenceNumber     uint64
+	ManagementPassword string
+	Authentication     string
+}
+
+type xmlKeyring struct {
+	XMLName        xml.Name       `xml:"Keyring"`
+	Project        string         `xml:"Project,attr"`
+	Created        string         `xml:"Created,attr"`
+	Signature      string         `xml:"Signature,attr"`
+	Backbone       *xmlBackbone   `xml:"Backbone"`
+	Interfaces     []xmlInterface `xml:"Interface"`
+	GroupAddresses []xmlGroupKey  `xml:"GroupAddresses>Group"`
+	Devices        []xmlDevice    `xml:"Devices>Device"`
+}
+
+type xmlBackbone struct {
+	MulticastAddress string `xml:"MulticastAddress,attr"`
+	Latency          string `xml:"Latency,attr"`
+	Key              string `xml:"Key,attr"`
+}
+
+type xmlInterface struct {
+	Type              string           `xml:"Type,attr"`
+	Host              string           `xml:"Host,attr"`
+	IndividualAddress string           `xml:"IndividualAddress,attr"`
+	UserID            string           `xml:"UserID,attr"`
+	Password          string           `xml:"Password,attr"`
+	Authentication    string           `xml:"Authentication,attr"`
+	Groups            []xmlGroupSender `xml:"Group"`
+}
+
+type xmlGroupSender struct {
+	Address string `xml:"Address,attr"`
+	Senders string `xml:"Senders,attr"`
+}
+
+type xmlGroupKey struct {
+	Address string `xml:"Address,attr"`
+	Key     string `xml:"Key,attr"`
+}
+
+type xmlDevice struct {
+	IndividualAddress  string `xml:"IndividualAddress,attr"`
+	SerialNumber       string `xml:"SerialNumber,attr"`
+	ToolKey            string `xml:"ToolKey,attr"`
+	SequenceNumber     string `xml:"SequenceNumber,attr"`
+	ManagementPassword string `xml:"ManagementPassword,attr"`
+	Authentication     string `xml:"Authentication,attr"`
+}
+
+// LoadKeyring reads and decrypts an ETS keyring file using the password it was exported with.
+func LoadKeyring(filePath string, password string) (*Keyring, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	return ParseKeyring(data, password)
+}
+
+// ParseKeyring decrypts the contents of an ETS keyring file.
+func ParseKeyring(data []byte, password string) (*Keyring, error) {
+	var raw xmlKeyring
+	if err := xml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring: %w", err)
+	}
+
+	passwordHash := keyringPasswordHash(password)
+	if raw.Signature != "" {
+		valid, err := verifyKeyringSignature(data, passwordHash, raw.Signature)
+		if err != nil {
+			return nil, err
+		}
+		if !valid {
+			log.Warn().Msg("Keyring signature could not be verified, the keyring password may be wrong")
+		}
+	}
+
+	createdHash := sha256.Sum256([]byte(raw.Created))
+	d := keyringDecrypter{passwordHash: passwordHash, createdHash: createdHash[:16]}
+
+	keyring := &Keyring{
+		Project:   raw.Project,
+		Created:   raw.Created,
+		Devices:   make(map[string]Device),
+		GroupKeys: make(map[models.FlatGroupAddress][]byte),
+	}
+
+	if raw.Backbone != nil {
+		key, err := d.key(raw.Backbone.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt backbone key: %w", err)
+		}
+		latency := time.Duration(0)
+		if raw.Backbone.Latency != "" {
+			ms, err := strconv.ParseUint(raw.Backbone.Latency, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid backbone latency %q: %w", raw.Backbone.Latency, err)
+			}
+			latency = time.Duration(ms) * time.Millisecond
+		}
+		keyring.Backbone = &Backbone{
+			MulticastAddress: raw.Backbone.MulticastAddress,
+			Latency:          latency,
+			Key:              key,
+		}
+	}
+
+	for _, ri := range raw.Interfaces {
+		iface := Interface{
+			Type:              ri.Type,
+			Host:              ri.Host,
+			IndividualAddress: ri.IndividualAddress,
+			Groups:            make(map[models.FlatGroupAddress][]string),
+		}
+		if ri.UserID != "" {
+			userID, err := strconv.ParseUint(ri.UserID, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid user id %q for interface %s: %w", ri.UserID, ri.IndividualAddress, err)
+			}
+			iface.UserID = uint8(userID)
+		}
+		var err error
+		if iface.Password, err = d.password(ri.Password); err != nil {
+			return nil, fmt.Errorf("failed to decrypt password of interface %s: %w", ri.IndividualAddress, err)
+		}
+		if iface.Authentication, err = d.password(ri.Authentication); err != nil {
+			return nil, fmt.Errorf("failed to decrypt authentication code of interface %s: %w", ri.IndividualAddress, err)
+		}
+		for _, group := range ri.Groups {
+			address, err := models.ParseGroupAddress(group.Address)
+			if err != nil {
+				return nil, fmt.Errorf("invalid group address in interface %s: %w", ri.IndividualAddress, err)
+			}
+			iface.Groups[address] = strings.Fields(group.Senders)
+		}
+		keyring.Interfaces = append(keyring.Interfaces, iface)
+	}
+
+	for _, group := range raw.GroupAddresses {
+		address, err := models.ParseGroupAddress(group.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group address in keyring: %w", err)
+		}
+		key, err := d.key(group.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key of group address %s: %w", group.Address, err)
+		}
+		keyring.GroupKeys[address] = key
+	}
+
+	for _, rd := range raw.Devices {
+		device := Device{
+			IndividualAddress: rd.IndividualAddress,
+			SerialNumber:      rd.SerialNumber,
+		}
+		var err error
+		if rd.ToolKey != "" {
+			if device.ToolKey, err = d.key(rd.ToolKey); err != nil {
+				return nil, fmt.Errorf("failed to decrypt tool key of device %s: %w", rd.IndividualAddress, err)
+			}
+		}
+		if rd.SequenceNumber != "" {
+			if device.SequenceNumber, err = strconv.ParseUint(rd.SequenceNumber, 10, 48); err != nil {
+				return nil, fmt.Errorf("invalid sequence number of device %s: %w", rd.IndividualAddress, err)
+			}
+		}
+		if device.ManagementPassword, err = d.password(rd.ManagementPassword); err != nil {
+			return nil, fmt.Errorf("failed to decrypt management password of device %s: %w", rd.IndividualAddress, err)
+		}
+		if device.Authentication, err = d.password(rd.Authentication); err != nil {
+			return nil, fmt.Errorf("failed to decrypt authentication code of device %s: %w", rd.IndividualAddress, err)
+		}
+		keyring.Devices[device.IndividualAddress] = device
+	}
+
+	return keyring, nil
+}
+
+// Tunnel returns the tunnelling interface with the given individual address,
+// or the first tunnelling interface with credentials if address is empty.
+func (k *Keyring) Tunnel(address string) (*Interface, error) {
+	for i := range k.Interfaces {
+		iface := &k.Interfaces[i]
+		if iface.Type != "Tunneling" || iface.Password == "" {
+			continue
+		}
+		if address == "" || iface.IndividualAddress == address {
+			return iface, nil
+		}
+	}
+	if address == "" {
+		return nil, errors.New("no secure tunnelling interface found in keyring")
+	}
+	return nil, fmt.Errorf("no secure tunnelling interface with address %s found in keyring", address)
+}
+
+// keyringDecrypter decrypts keys and passwords protected by the keyring password.
+type keyringDecrypter struct {
+	passwordHash []byte
+	createdHash  []byte
+}
+
+// key decrypts a base64 encoded 16 byte key.
+func (d keyringDecrypter) key(encoded string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return decryptCBC(d.passwordHash, d.createdHash, data)
+}
+
+// password decrypts a base64 encoded password. Passwords are prefixed with
+// 8 random bytes and padded with the number of padding bytes.
+func (d keyringDecrypter) password(encoded string) (string, error) {
+	if encoded == "" {
+		return "", nil
+	}
+	data, err := d.key(encoded)
+	if err != nil {
+		return "", err
+	}
+	padding := int(data[len(data)-1])
+	if padding == 0 || len(data) < 8+padding {
+		return "", errors.New("invalid password padding")
+	}
+	return string(data[8 : len(data)-padding]), nil
+}
+
+// verifyKeyringSignature checks the keyring signature against the password hash.
+func verifyKeyringSignature(data []byte, passwordHash []byte, signature string) (bool, error) {
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, fmt.Errorf("invalid keyring signature: %w", err)
+	}
+	digest, err := keyringSignature(data, passwordHash)
+	if err != nil {
+		return false, err
+	}
+	if len(expected) == 0 || len(expected) > len(digest) {
+		return false, nil
+	}
+	return bytes.Equal(digest[:len(expected)], expected), nil
+}
+
+// keyringSignature computes the keyring signature, which is a hash over all
+// elements and their sorted attributes followed by the keyring password hash.
+func keyringSignature(data []byte, passwordHash []byte) ([]byte, error) {
+	hash := sha256.New()
+	appendString := func(value string) {
+		hash.Write([]byte{byte(len(value))})
+		hash.Write([]byte(value))
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse keyring: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			hash.Write([]byte{1})
+			appendString(t.Name.Local)
+			attrs := make([]xml.Attr, 0, len(t.Attr))
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" || attr.Name.Local == "Signature" {
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name.Local < attrs[j].Name.Local })
+			for _, attr := range attrs {
+				appendString(attr.Name.Local)
+				appendString(attr.Value)
+			}
+		case xml.EndElement:
+			hash.Write([]byte{2})
+		}
+	}
+	appendString(base64.StdEncoding.EncodeToString(passwordHash))
+	return hash.Sum(nil), nil
+}
@@ -0,0 +1,170 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKeyringPassword = "keyring-password"
+const testKeyringCreated = "2024-03-13T08:02:41"
+
+// testEncrypter produces keyring attributes the way ETS does.
+type testEncrypter struct {
+	key []byte
+	iv  []byte
+}
+
+func newTestEncrypter() testEncrypter {
+	created := sha256.Sum256([]byte(testKeyringCreated))
+	return testEncrypter{key: keyringPasswordHash(testKeyringPassword), iv: created[:16]}
+}
+
+func (e testEncrypter) encrypt(data []byte) string {
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		panic(err)
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, e.iv).CryptBlocks(out, data)
+	return base64.StdEncoding.EncodeToString(out)
+}
+
+func (e testEncrypter) password(password string) string {
+	data := append(bytes.Repeat([]byte{0xaa}, 8), password...)
+	padding := aes.BlockSize - len(data)%aes.BlockSize
+	data = append(data, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	return e.encrypt(data)
+}
+
+func testKeyring(signature string) string {
+	e := newTestEncrypter()
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<Keyring Project="Test Project" CreatedBy="ETS 6" Created="%s" Signature="%s" xmlns="http://knx.org/xml/keyring/1">
+  <Backbone MulticastAddress="224.0.23.12" Latency="1500" Key="%s" />
+  <Interface Type="Tunneling" Host="1.1.10" IndividualAddress="1.1.11" UserID="2" Password="%s">
+    <Group Address="2307" Senders="1.1.1 1.1.2" />
+  </Interface>
+  <Interface Type="Tunneling" Host="1.1.10" IndividualAddress="1.1.12" UserID="3" Password="%s" />
+  <GroupAddresses>
+    <Group Address="2307" Key="%s" />
+    <Group Address="1/2/4" Key="%s" />
+  </GroupAddresses>
+  <Devices>
+    <Device IndividualAddress="1.1.10" SerialNumber="00FA10010710" SequenceNumber="123" ToolKey="%s" ManagementPassword="%s" Authentication="%s" />
+  </Devices>
+</Keyring>`,
+		testKeyringCreated, signature,
+		e.encrypt(bytes.Repeat([]byte{0x01}, 16)),
+		e.password("tunnel-one"),
+		e.password("tunnel-two"),
+		e.encrypt(bytes.Repeat([]byte{0x02}, 16)),
+		e.encrypt(bytes.Repeat([]byte{0x03}, 16)),
+		e.encrypt(bytes.Repeat([]byte{0x04}, 16)),
+		e.password("management"),
+		e.password("authentication"),
+	)
+}
+
+func TestParseKeyring(t *testing.T) {
+	keyring, err := ParseKeyring([]byte(testKeyring("")), testKeyringPassword)
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+
+	if keyring.Project != "Test Project" {
+		t.Errorf("Project = %q, want %q", keyring.Project, "Test Project")
+	}
+	if keyring.Backbone == nil {
+		t.Fatal("expected backbone")
+	}
+	if !bytes.Equal(keyring.Backbone.Key, bytes.Repeat([]byte{0x01}, 16)) {
+		t.Errorf("Backbone.Key = %x", keyring.Backbone.Key)
+	}
+	if keyring.Backbone.Latency != 1500*time.Millisecond {
+		t.Errorf("Backbone.Latency = %v, want 1.5s", keyring.Backbone.Latency)
+	}
+
+	if len(keyring.Interfaces) != 2 {
+		t.Fatalf("got %d interfaces, want 2", len(keyring.Interfaces))
+	}
+	iface := keyring.Interfaces[0]
+	if iface.UserID != 2 || iface.Password != "tunnel-one" || iface.Host != "1.1.10" {
+		t.Errorf("unexpected interface %+v", iface)
+	}
+	if senders := iface.Groups[2307]; len(senders) != 2 || senders[1] != "1.1.2" {
+		t.Errorf("unexpected group senders %v", senders)
+	}
+
+	if key := keyring.GroupKeys[2307]; !bytes.Equal(key, bytes.Repeat([]byte{0x02}, 16)) {
+		t.Errorf("GroupKeys[2307] = %x", key)
+	}
+	if key := keyring.GroupKeys[1<<11|2<<8|4]; !bytes.Equal(key, bytes.Repeat([]byte{0x03}, 16)) {
+		t.Errorf("GroupKeys[1/2/4] = %x", key)
+	}
+
+	device, ok := keyring.Devices["1.1.10"]
+	if !ok {
+		t.Fatal("expected device 1.1.10")
+	}
+	if device.Authentication != "authentication" || device.ManagementPassword != "management" {
+		t.Errorf("unexpected device passwords %+v", device)
+	}
+	if device.SequenceNumber != 123 {
+		t.Errorf("SequenceNumber = %d, want 123", device.SequenceNumber)
+	}
+}
+
+func TestVerifyKeyringSignature(t *testing.T) {
+	passwordHash := keyringPasswordHash(testKeyringPassword)
+	digest, err := keyringSignature([]byte(testKeyring("")), passwordHash)
+	if err != nil {
+		t.Fatalf("keyringSignature() error = %v", err)
+	}
+	signature := base64.StdEncoding.EncodeToString(digest[:16])
+	signed := []byte(testKeyring(signature))
+
+	// The signature attribute itself is not part of the signed data.
+	if valid, err := verifyKeyringSignature(signed, passwordHash, signature); err != nil || !valid {
+		t.Errorf("verifyKeyringSignature() = %v, %v; want valid", valid, err)
+	}
+	if valid, _ := verifyKeyringSignature(signed, keyringPasswordHash("wrong"), signature); valid {
+		t.Error("expected signature to be invalid with the wrong password")
+	}
+	tampered := []byte(strings.Replace(string(signed), "Test Project", "Other Project", 1))
+	if valid, _ := verifyKeyringSignature(tampered, passwordHash, signature); valid {
+		t.Error("expected signature of a modified keyring to be invalid")
+	}
+}
+
+func TestParseKeyringWrongPassword(t *testing.T) {
+	_, err := ParseKeyring([]byte(testKeyring("")), "wrong")
+	if err == nil {
+		t.Error("expected an error when decrypting passwords with the wrong keyring password")
+	}
+}
+
+func TestKeyringTunnel(t *testing.T) {
+	keyring, err := ParseKeyring([]byte(testKeyring("")), testKeyringPassword)
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+
+	iface, err := keyring.Tunnel("")
+	if err != nil || iface.IndividualAddress != "1.1.11" {
+		t.Errorf("Tunnel(\"\") = %v, %v; want 1.1.11", iface, err)
+	}
+	iface, err = keyring.Tunnel("1.1.12")
+	if err != nil || iface.UserID != 3 {
+		t.Errorf("Tunnel(\"1.1.12\") = %v, %v; want user 3", iface, err)
+	}
+	if _, err := keyring.Tunnel("1.1.99"); err == nil {
+		t.Error("expected error for unknown tunnel")
+	}
+}
@@ -0,0 +1,266 @@
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+	"github.com/vapourismo/knx-go/knx/knxnet"
+)
+
+const (
+	// defaultLatency is the synchronisation latency tolerance used if the keyring does not specify one.
+	defaultLatency = 1000 * time.Millisecond
+	// postSendPause is the pause after sending on the multicast group, as done by knx-go's router.
+	postSendPause = 20 * time.Millisecond
+)
+
+// multicastTimer is the shared timer of a secure backbone. It only moves forward.
+type multicastTimer struct {
+	mu     sync.Mutex
+	start  time.Time
+	offset uint64
+	last   uint64
+}
+
+func newMulticastTimer() *multicastTimer {
+	return &multicastTimer{start: time.Now()}
+}
+
+func (t *multicastTimer) valueLocked() uint64 {
+	return t.offset + uint64(time.Since(t.start).Milliseconds())
+}
+
+// value returns the current timer value.
+func (t *multicastTimer) value() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.valueLocked()
+}
+
+// next returns a timer value for an outgoing frame, strictly greater than the previous one.
+func (t *multicastTimer) next() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	value := t.valueLocked()
+	if value <= t.last {
+		t.offset += t.last + 1 - value
+		value = t.last + 1
+	}
+	t.last = value
+	return value
+}
+
+// adopt advances the timer to value if it is ahead of the local timer.
+func (t *multicastTimer) adopt(value uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if current := t.valueLocked(); value > current {
+		t.offset += value - current
+	}
+}
+
+// GroupRouter is a KNXnet/IP Secure routing connection providing group communication.
+type GroupRouter struct {
+	sock    *knxnet.RouterSocket
+	key     []byte
+	latency time.Duration
+	serial  []byte
+	timer   *multicastTimer
+
+	sendMu  sync.Mutex
+	inbound chan knxgo.GroupEvent
+	done    chan struct{}
+	once    sync.Once
+	wait    sync.WaitGroup
+}
+
+// NewGroupRouter joins the secure backbone at multicastAddress using the backbone key.
+// It synchronises the multicast timer with the other devices before returning.
+func NewGroupRouter(multicastAddress string, key []byte, latency time.Duration) (*GroupRouter, error) {
+	if len(key) != keyLength {
+		return nil, fmt.Errorf("invalid backbone key length %d", len(key))
+	}
+	if latency <= 0 {
+		latency = defaultLatency
+	}
+
+	sock, err := knxnet.ListenRouter(multicastAddress)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		sock.Close()
+		return nil, err
+	}
+
+	r := &GroupRouter{
+		sock:    sock,
+		key:     key,
+		latency: latency,
+		serial:  serial,
+		timer:   newMulticastTimer(),
+		inbound: make(chan knxgo.GroupEvent),
+		done:    make(chan struct{}),
+	}
+
+	r.wait.Add(1)
+	go r.serve()
+
+	// Request the current timer value from the other devices on the backbone.
+	var tag [2]byte
+	if _, err := rand.Read(tag[:]); err != nil {
+		r.Close()
+		return nil, err
+	}
+	if err := r.sendTimerNotify(binary.BigEndian.Uint16(tag[:])); err != nil {
+		r.Close()
+		return nil, err
+	}
+	time.Sleep(latency)
+
+	return r, nil
+}
+
+func (r *GroupRouter) sendTimerNotify(messageTag uint16) error {
+	notify := &TimerNotify{
+		Timer:      uint48(r.timer.next()),
+		Serial:     r.serial,
+		MessageTag: messageTag,
+	}
+	if err := notify.Sign(r.key); err != nil {
+		return err
+	}
+	return r.sock.Send(notify)
+}
+
+// serve processes incoming frames until the router is closed.
+func (r *GroupRouter) serve() {
+	defer r.wait.Done()
+	defer close(r.inbound)
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case srv, open := <-r.sock.Inbound():
+			if !open {
+				return
+			}
+
+			if data, ok := unknownService(srv, TimerNotifyService); ok {
+				r.handleTimerNotify(data)
+				continue
+			}
+			data, ok := unknownService(srv, SecureWrapperService)
+			if !ok {
+				// Unsecured frames are not accepted on a secure backbone.
+				continue
+			}
+			inner, ok := r.unwrap(data)
+			if !ok {
+				continue
+			}
+			if ind, ok := inner.(*knxnet.RoutingInd); ok {
+				if event, ok := GroupEventFromMessage(ind.Payload); ok {
+					select {
+					case r.inbound <- event:
+					case <-r.done:
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// checkTimer validates a received timer value against the local timer, adopting newer values.
+// It returns false for outdated values, in which case the sender is told the current value.
+func (r *GroupRouter) checkTimer(received uint64, messageTag uint16) bool {
+	local := r.timer.value()
+	if received+uint64(r.latency.Milliseconds()) < local {
+		if err := r.sendTimerNotify(messageTag); err != nil {
+			log.Error().Err(err).Msg("Failed to send KNX secure timer notification")
+		}
+		return false
+	}
+	r.timer.adopt(received)
+	return true
+}
+
+func (r *GroupRouter) handleTimerNotify(data []byte) {
+	var notify TimerNotify
+	if err := notify.Unpack(data); err != nil {
+		log.Warn().Err(err).Msg("Discarding KNX secure timer notification")
+		return
+	}
+	if err := notify.Verify(r.key); err != nil {
+		log.Warn().Err(err).Msg("Discarding KNX secure timer notification")
+		return
+	}
+	r.checkTimer(parseUint48(notify.Timer), notify.MessageTag)
+}
+
+func (r *GroupRouter) unwrap(data []byte) (knxnet.Service, bool) {
+	var wrapper Wrapper
+	if err := wrapper.Unpack(data); err != nil {
+		log.Warn().Err(err).Msg("Discarding KNX secure frame")
+		return nil, false
+	}
+	if wrapper.SessionID != 0 {
+		return nil, false
+	}
+	plain, err := wrapper.Open(r.key)
+	if err != nil {
+		log.Warn().Err(err).Msg("Discarding KNX secure frame")
+		return nil, false
+	}
+	if !r.checkTimer(parseUint48(wrapper.Sequence), wrapper.MessageTag) {
+		log.Debug().Msg("Discarding KNX secure frame with outdated timer")
+		return nil, false
+	}
+	var inner knxnet.Service
+	if _, err := knxnet.Unpack(plain, &inner); err != nil {
+		log.Warn().Err(err).Msg("Discarding KNX secure frame")
+		return nil, false
+	}
+	return inner, true
+}
+
+// Send transmits a group event on the secure backbone.
+func (r *GroupRouter) Send(event knxgo.GroupEvent) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	ind := &knxnet.RoutingInd{Payload: &cemi.LDataInd{LData: BuildGroupOutbound(event)}}
+	wrapper := &Wrapper{
+		Sequence: uint48(r.timer.next()),
+		Serial:   r.serial,
+	}
+	if err := wrapper.Seal(r.key, knxnet.AllocAndPack(ind)); err != nil {
+		return err
+	}
+	err := r.sock.Send(wrapper)
+	time.Sleep(postSendPause)
+	return err
+}
+
+// Inbound returns the channel on which group communication can be received.
+func (r *GroupRouter) Inbound() <-chan knxgo.GroupEvent {
+	return r.inbound
+}
+
+// Close leaves the multicast group.
+func (r *GroupRouter) Close() {
+	r.once.Do(func() {
+		close(r.done)
+		r.sock.Close()
+		r.wait.Wait()
+	})
+}
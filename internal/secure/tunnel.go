@@ -0,0 +1,408 @@
+package secure
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+	"github.com/vapourismo/knx-go/knx/knxnet"
+)
+
+const (
+	responseTimeout   = 10 * time.Second
+	heartbeatInterval = 10 * time.Second
+)
+
+// TunnelCredentials holds the derived keys needed to open a secure tunnel.
+type TunnelCredentials struct {
+	UserID uint8
+	// UserPasswordKey is derived from the tunnel password using UserPasswordKey.
+	UserPasswordKey []byte
+	// DeviceAuthenticationCode is derived using DeviceAuthenticationCode. If empty,
+	// the gateway's identity is not verified.
+	DeviceAuthenticationCode []byte
+}
+
+// GroupTunnel is a KNXnet/IP Secure tunnelling connection providing group communication.
+type GroupTunnel struct {
+	sock       knxnet.Socket
+	control    knxnet.HostInfo
+	serial     []byte
+	sessionID  uint16
+	sessionKey []byte
+	channel    uint8
+
+	sendMu       sync.Mutex
+	sequence     uint64
+	lastReceived uint64
+	received     bool
+
+	inbound   chan knxgo.GroupEvent
+	heartbeat chan knxnet.ErrCode
+	done      chan struct{}
+	once      sync.Once
+	wait      sync.WaitGroup
+}
+
+// NewGroupTunnel establishes a secure session with the gateway at address over TCP,
+// authenticates using credentials and opens a tunnelling connection on top of it.
+func NewGroupTunnel(address string, credentials TunnelCredentials) (*GroupTunnel, error) {
+	sock, err := knxnet.DialTunnelTCP(address)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		sock.Close()
+		return nil, err
+	}
+
+	t := &GroupTunnel{
+		sock:      sock,
+		control:   knxnet.HostInfo{Protocol: knxnet.TCP4},
+		serial:    serial,
+		inbound:   make(chan knxgo.GroupEvent),
+		heartbeat: make(chan knxnet.ErrCode, 1),
+		done:      make(chan struct{}),
+	}
+
+	if err := t.authenticate(credentials); err != nil {
+		sock.Close()
+		return nil, err
+	}
+	if err := t.requestConn(); err != nil {
+		t.sendWrapped(&SessionStat{Status: StatusClose})
+		sock.Close()
+		return nil, err
+	}
+
+	t.wait.Add(2)
+	go t.serve()
+	go t.performHeartbeats()
+
+	return t, nil
+}
+
+// authenticate performs the secure session handshake.
+func (t *GroupTunnel) authenticate(credentials TunnelCredentials) error {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	publicKey := privateKey.PublicKey().Bytes()
+
+	if err := t.sock.Send(&SessionReq{Control: t.control, PublicKey: publicKey}); err != nil {
+		return err
+	}
+
+	data, err := t.await(func(srv knxnet.Service) ([]byte, bool) {
+		return unknownService(srv, SessionResponseService)
+	})
+	if err != nil {
+		return fmt.Errorf("no session response: %w", err)
+	}
+	var res SessionRes
+	if err := res.Unpack(data); err != nil {
+		return err
+	}
+
+	serverKey, err := ecdh.X25519().NewPublicKey(res.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid server public key: %w", err)
+	}
+	secret, err := privateKey.ECDH(serverKey)
+	if err != nil {
+		return err
+	}
+	sessionKey := sha256.Sum256(secret)
+	t.sessionKey = sessionKey[:16]
+	t.sessionID = res.SessionID
+
+	keyXor := xorBytes(publicKey, res.PublicKey)
+	block0 := make([]byte, 16)
+	handshakeCounter := append(make([]byte, 14), 0xff, 0x00)
+
+	if len(credentials.DeviceAuthenticationCode) > 0 {
+		mac, _, err := ctrCrypt(credentials.DeviceAuthenticationCode, handshakeCounter, res.MAC, nil)
+		if err != nil {
+			return err
+		}
+		additional := header(SessionResponseService, headerSize+2+publicKeySize+macSize)
+		additional = binary.BigEndian.AppendUint16(additional, res.SessionID)
+		additional = append(additional, keyXor...)
+		expected, err := cbcMAC(credentials.DeviceAuthenticationCode, block0, additional, nil)
+		if err != nil {
+			return err
+		}
+		if !equalMAC(mac, expected) {
+			return errors.New("session response could not be verified, check the device authentication code")
+		}
+	} else {
+		log.Warn().Msg("No device authentication code available, the identity of the KNX gateway is not verified")
+	}
+
+	additional := header(SessionAuthenticateService, headerSize+int(SessionAuth{}.Size()))
+	additional = append(additional, 0, credentials.UserID)
+	additional = append(additional, keyXor...)
+	mac, err := cbcMAC(credentials.UserPasswordKey, block0, additional, nil)
+	if err != nil {
+		return err
+	}
+	mac, _, err = ctrCrypt(credentials.UserPasswordKey, handshakeCounter, mac, nil)
+	if err != nil {
+		return err
+	}
+	if err := t.sendWrapped(&SessionAuth{UserID: credentials.UserID, MAC: mac}); err != nil {
+		return err
+	}
+
+	data, err = t.awaitWrapped(func(srv knxnet.Service) ([]byte, bool) {
+		return unknownService(srv, SessionStatusService)
+	})
+	if err != nil {
+		return fmt.Errorf("no session status: %w", err)
+	}
+	var status SessionStat
+	if err := status.Unpack(data); err != nil {
+		return err
+	}
+	if status.Status != StatusAuthenticationSuccess {
+		return fmt.Errorf("secure session authentication failed: %s", status.Status)
+	}
+	return nil
+}
+
+// requestConn opens the tunnelling connection within the secure session.
+func (t *GroupTunnel) requestConn() error {
+	req := &knxnet.ConnReq{Control: t.control, Tunnel: t.control, Layer: knxnet.TunnelLayerData}
+	if err := t.sendWrapped(req); err != nil {
+		return err
+	}
+
+	var res *knxnet.ConnRes
+	_, err := t.awaitWrapped(func(srv knxnet.Service) ([]byte, bool) {
+		var ok bool
+		res, ok = srv.(*knxnet.ConnRes)
+		return nil, ok
+	})
+	if err != nil {
+		return fmt.Errorf("no connection response: %w", err)
+	}
+	if res.Status != knxnet.NoError {
+		return res.Status
+	}
+	t.channel = res.Channel
+	return nil
+}
+
+// await waits for a service accepted by match during connection setup.
+func (t *GroupTunnel) await(match func(knxnet.Service) ([]byte, bool)) ([]byte, error) {
+	timeout := time.After(responseTimeout)
+	for {
+		select {
+		case <-timeout:
+			return nil, errors.New("response timeout reached")
+		case srv, open := <-t.sock.Inbound():
+			if !open {
+				return nil, errors.New("connection closed by gateway")
+			}
+			if data, ok := match(srv); ok {
+				return data, nil
+			}
+		}
+	}
+}
+
+// awaitWrapped waits for a secured service accepted by match during connection setup.
+func (t *GroupTunnel) awaitWrapped(match func(knxnet.Service) ([]byte, bool)) ([]byte, error) {
+	return t.await(func(srv knxnet.Service) ([]byte, bool) {
+		inner, err := t.unwrap(srv)
+		if err != nil {
+			return nil, false
+		}
+		return match(inner)
+	})
+}
+
+// sendWrapped encrypts service within the secure session and sends it.
+func (t *GroupTunnel) sendWrapped(service knxnet.ServicePackable) error {
+	t.sendMu.Lock()
+	defer t.sendMu.Unlock()
+
+	wrapper := &Wrapper{
+		SessionID: t.sessionID,
+		Sequence:  uint48(t.sequence),
+		Serial:    t.serial,
+	}
+	t.sequence++
+	if err := wrapper.Seal(t.sessionKey, knxnet.AllocAndPack(service)); err != nil {
+		return err
+	}
+	return t.sock.Send(wrapper)
+}
+
+// unwrap decrypts a secure wrapper received within the session.
+func (t *GroupTunnel) unwrap(srv knxnet.Service) (knxnet.Service, error) {
+	data, ok := unknownService(srv, SecureWrapperService)
+	if !ok {
+		return nil, fmt.Errorf("unexpected unsecured service %v", srv.Service())
+	}
+	var wrapper Wrapper
+	if err := wrapper.Unpack(data); err != nil {
+		return nil, err
+	}
+	if wrapper.SessionID != t.sessionID {
+		return nil, fmt.Errorf("unexpected session id %d", wrapper.SessionID)
+	}
+	plain, err := wrapper.Open(t.sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	sequence := parseUint48(wrapper.Sequence)
+	if t.received && sequence <= t.lastReceived {
+		return nil, fmt.Errorf("replayed sequence number %d", sequence)
+	}
+	t.lastReceived = sequence
+	t.received = true
+
+	var inner knxnet.Service
+	if _, err := knxnet.Unpack(plain, &inner); err != nil {
+		return nil, err
+	}
+	return inner, nil
+}
+
+// serve processes incoming frames until the session ends.
+func (t *GroupTunnel) serve() {
+	defer t.wait.Done()
+	defer close(t.inbound)
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case srv, open := <-t.sock.Inbound():
+			if !open {
+				log.Warn().Msg("Secure KNX tunnel closed by gateway")
+				return
+			}
+			inner, err := t.unwrap(srv)
+			if err != nil {
+				log.Warn().Err(err).Msg("Discarding KNX secure frame")
+				continue
+			}
+
+			switch msg := inner.(type) {
+			case *knxnet.TunnelReq:
+				if msg.Channel != t.channel {
+					continue
+				}
+				if event, ok := GroupEventFromMessage(msg.Payload); ok {
+					select {
+					case t.inbound <- event:
+					case <-t.done:
+						return
+					}
+				}
+			case *knxnet.ConnStateRes:
+				if msg.Channel == t.channel {
+					select {
+					case t.heartbeat <- msg.Status:
+					default:
+					}
+				}
+			case *knxnet.DiscReq:
+				if msg.Channel == t.channel {
+					t.sendWrapped(&knxnet.DiscRes{Channel: t.channel})
+					log.Warn().Msg("Secure KNX tunnel disconnected by gateway")
+					return
+				}
+			default:
+				if data, ok := unknownService(inner, SessionStatusService); ok {
+					var status SessionStat
+					if status.Unpack(data) == nil && status.Status != StatusKeepAlive {
+						log.Warn().Str("status", status.Status.String()).Msg("Secure KNX session ended by gateway")
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// performHeartbeats periodically checks the connection state and closes
+// the socket if the gateway stops responding.
+func (t *GroupTunnel) performHeartbeats() {
+	defer t.wait.Done()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			err := t.sendWrapped(&knxnet.ConnStateReq{Channel: t.channel, Control: t.control})
+			if err == nil {
+				select {
+				case <-t.done:
+					return
+				case status := <-t.heartbeat:
+					if status != knxnet.NoError {
+						err = status
+					}
+				case <-time.After(responseTimeout):
+					err = errors.New("response timeout reached")
+				}
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("Secure KNX tunnel heartbeat failed")
+				t.sock.Close()
+				return
+			}
+		}
+	}
+}
+
+// Send transmits a group event through the tunnel.
+func (t *GroupTunnel) Send(event knxgo.GroupEvent) error {
+	return t.sendWrapped(&knxnet.TunnelReq{
+		Channel: t.channel,
+		Payload: &cemi.LDataReq{LData: BuildGroupOutbound(event)},
+	})
+}
+
+// Inbound returns the channel on which group communication can be received.
+// It is closed when the secure session ends.
+func (t *GroupTunnel) Inbound() <-chan knxgo.GroupEvent {
+	return t.inbound
+}
+
+// Close disconnects the tunnel and ends the secure session.
+func (t *GroupTunnel) Close() {
+	t.once.Do(func() {
+		t.sendWrapped(&knxnet.DiscReq{Channel: t.channel, Control: t.control})
+		t.sendWrapped(&SessionStat{Status: StatusClose})
+		close(t.done)
+		t.sock.Close()
+		t.wait.Wait()
+	})
+}
+
+func randomSerial() ([]byte, error) {
+	serial := make([]byte, serialSize)
+	if _, err := rand.Read(serial); err != nil {
+		return nil, err
+	}
+	return serial, nil
+}
@@ -0,0 +1,288 @@
+package secure
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	knxgo "github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+	"github.com/vapourismo/knx-go/knx/knxnet"
+)
+
+// fakeGateway implements the gateway side of a secure tunnelling session.
+type fakeGateway struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	sessionKey []byte
+	sequence   uint64
+}
+
+const fakeSessionID = 0x0102
+
+func (g *fakeGateway) readFrame() (knxnet.ServiceID, []byte, error) {
+	g.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	head := make([]byte, headerSize)
+	if _, err := io.ReadFull(g.reader, head); err != nil {
+		return 0, nil, err
+	}
+	body := make([]byte, int(binary.BigEndian.Uint16(head[4:]))-headerSize)
+	if _, err := io.ReadFull(g.reader, body); err != nil {
+		return 0, nil, err
+	}
+	return knxnet.ServiceID(binary.BigEndian.Uint16(head[2:])), body, nil
+}
+
+// readWrapped reads a secure wrapper and returns the decrypted inner frame.
+func (g *fakeGateway) readWrapped() (knxnet.Service, error) {
+	service, body, err := g.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	if service != SecureWrapperService {
+		return nil, fmt.Errorf("expected secure wrapper, got %#04x", service)
+	}
+	var wrapper Wrapper
+	if err := wrapper.Unpack(body); err != nil {
+		return nil, err
+	}
+	if wrapper.SessionID != fakeSessionID {
+		return nil, fmt.Errorf("unexpected session id %d", wrapper.SessionID)
+	}
+	plain, err := wrapper.Open(g.sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	var inner knxnet.Service
+	if _, err := knxnet.Unpack(plain, &inner); err != nil {
+		return nil, err
+	}
+	return inner, nil
+}
+
+func (g *fakeGateway) writeWrapped(service knxnet.ServicePackable) error {
+	wrapper := &Wrapper{
+		SessionID: fakeSessionID,
+		Sequence:  uint48(g.sequence),
+		Serial:    []byte{0, 0xfa, 0, 0, 0, 1},
+	}
+	g.sequence++
+	if err := wrapper.Seal(g.sessionKey, knxnet.AllocAndPack(service)); err != nil {
+		return err
+	}
+	_, err := g.conn.Write(knxnet.AllocAndPack(wrapper))
+	return err
+}
+
+// handshake answers the session request and checks the user authentication
+// against userID and password.
+func (g *fakeGateway) handshake(userID uint8, password string, authenticationCode []byte) error {
+	service, body, err := g.readFrame()
+	if err != nil {
+		return err
+	}
+	if service != SessionRequestService {
+		return fmt.Errorf("expected session request, got %#04x", service)
+	}
+	clientKey, err := ecdh.X25519().NewPublicKey(body[hostInfoSize:])
+	if err != nil {
+		return err
+	}
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	secret, err := privateKey.ECDH(clientKey)
+	if err != nil {
+		return err
+	}
+	sessionKey := sha256.Sum256(secret)
+	g.sessionKey = sessionKey[:16]
+
+	publicKey := privateKey.PublicKey().Bytes()
+	keyXor := xorBytes(clientKey.Bytes(), publicKey)
+	handshakeCounter := append(make([]byte, 14), 0xff, 0x00)
+
+	response := header(SessionResponseService, headerSize+2+publicKeySize+macSize)
+	response = binary.BigEndian.AppendUint16(response, fakeSessionID)
+	mac, _ := cbcMAC(authenticationCode, make([]byte, 16), append(append([]byte(nil), response...), keyXor...), nil)
+	mac, _, _ = ctrCrypt(authenticationCode, handshakeCounter, mac, nil)
+	response = append(response, publicKey...)
+	if _, err := g.conn.Write(append(response, mac...)); err != nil {
+		return err
+	}
+
+	inner, err := g.readWrapped()
+	if err != nil {
+		return err
+	}
+	data, ok := unknownService(inner, SessionAuthenticateService)
+	if !ok {
+		return fmt.Errorf("expected session authenticate, got %v", inner)
+	}
+	userPasswordKey := UserPasswordKey(password)
+	additional := header(SessionAuthenticateService, headerSize+int(SessionAuth{}.Size()))
+	additional = append(additional, 0, userID)
+	additional = append(additional, keyXor...)
+	expected, _ := cbcMAC(userPasswordKey, make([]byte, 16), additional, nil)
+	received, _, _ := ctrCrypt(userPasswordKey, handshakeCounter, data[2:], nil)
+
+	status := StatusAuthenticationSuccess
+	if data[1] != userID || !bytes.Equal(received, expected) {
+		status = StatusAuthenticationFailed
+	}
+	return g.writeWrapped(&SessionStat{Status: status})
+}
+
+// startFakeGateway accepts a single connection and runs serve on it.
+// The error returned by serve is delivered on the returned channel.
+func startFakeGateway(t *testing.T, serve func(g *fakeGateway) error) (string, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	result := make(chan error, 1)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			result <- err
+			return
+		}
+		defer conn.Close()
+		result <- serve(&fakeGateway{conn: conn, reader: bufio.NewReader(conn)})
+	}()
+	return listener.Addr().String(), result
+}
+
+func TestGroupTunnel(t *testing.T) {
+	credentials := TunnelCredentials{
+		UserID:                   2,
+		UserPasswordKey:          UserPasswordKey("trustme"),
+		DeviceAuthenticationCode: DeviceAuthenticationCode("authentication"),
+	}
+	destination := cemi.NewGroupAddr3(1, 2, 3)
+	sent := make(chan *cemi.LDataReq, 1)
+
+	address, gateway := startFakeGateway(t, func(g *fakeGateway) error {
+		if err := g.handshake(2, "trustme", DeviceAuthenticationCode("authentication")); err != nil {
+			return err
+		}
+
+		inner, err := g.readWrapped()
+		if err != nil {
+			return err
+		}
+		if _, ok := inner.(*knxnet.ConnReq); !ok {
+			return fmt.Errorf("expected connection request, got %v", inner)
+		}
+		if err := g.writeWrapped(&knxnet.ConnRes{Channel: 9, Control: knxnet.HostInfo{Protocol: knxnet.TCP4}}); err != nil {
+			return err
+		}
+
+		ldata := BuildGroupOutbound(knxgo.GroupEvent{
+			Command:     knxgo.GroupWrite,
+			Source:      cemi.NewIndividualAddr3(1, 1, 5),
+			Destination: destination,
+			Data:        []byte{1},
+		})
+		if err := g.writeWrapped(&knxnet.TunnelReq{Channel: 9, Payload: &cemi.LDataInd{LData: ldata}}); err != nil {
+			return err
+		}
+
+		inner, err = g.readWrapped()
+		if err != nil {
+			return err
+		}
+		req, ok := inner.(*knxnet.TunnelReq)
+		if !ok || req.Channel != 9 {
+			return fmt.Errorf("expected tunnel request on channel 9, got %v", inner)
+		}
+		if ldataReq, ok := req.Payload.(*cemi.LDataReq); ok {
+			sent <- ldataReq
+		}
+
+		inner, err = g.readWrapped()
+		if err != nil {
+			return err
+		}
+		if _, ok := inner.(*knxnet.DiscReq); !ok {
+			return fmt.Errorf("expected disconnect request, got %v", inner)
+		}
+		return nil
+	})
+
+	tunnel, err := NewGroupTunnel(address, credentials)
+	if err != nil {
+		t.Fatalf("NewGroupTunnel() error = %v", err)
+	}
+
+	select {
+	case event := <-tunnel.Inbound():
+		if event.Destination != destination || event.Command != knxgo.GroupWrite || !bytes.Equal(event.Data, []byte{1}) {
+			t.Errorf("unexpected inbound event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for inbound event")
+	}
+
+	if err := tunnel.Send(knxgo.GroupEvent{Command: knxgo.GroupRead, Destination: destination}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	select {
+	case req := <-sent:
+		app, ok := req.Data.(*cemi.AppData)
+		if cemi.GroupAddr(req.Destination) != destination || !ok || app.Command != cemi.GroupValueRead {
+			t.Errorf("unexpected outbound frame %+v", req)
+		}
+	case err := <-gateway:
+		t.Fatalf("gateway error = %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for outbound event")
+	}
+
+	tunnel.Close()
+	if err := <-gateway; err != nil {
+		t.Errorf("gateway error = %v", err)
+	}
+}
+
+func TestGroupTunnelAuthenticationFailure(t *testing.T) {
+	address, _ := startFakeGateway(t, func(g *fakeGateway) error {
+		return g.handshake(2, "trustme", DeviceAuthenticationCode("authentication"))
+	})
+
+	_, err := NewGroupTunnel(address, TunnelCredentials{
+		UserID:                   2,
+		UserPasswordKey:          UserPasswordKey("wrong"),
+		DeviceAuthenticationCode: DeviceAuthenticationCode("authentication"),
+	})
+	if err == nil {
+		t.Fatal("expected authentication to fail with the wrong password")
+	}
+}
+
+func TestGroupTunnelUnverifiedGateway(t *testing.T) {
+	address, _ := startFakeGateway(t, func(g *fakeGateway) error {
+		return g.handshake(2, "trustme", DeviceAuthenticationCode("other"))
+	})
+
+	_, err := NewGroupTunnel(address, TunnelCredentials{
+		UserID:                   2,
+		UserPasswordKey:          UserPasswordKey("trustme"),
+		DeviceAuthenticationCode: DeviceAuthenticationCode("authentication"),
+	})
+	if err == nil {
+		t.Fatal("expected the gateway to fail verification")
+	}
+}
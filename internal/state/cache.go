@@ -0,0 +1,135 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultPersistInterval is how often a changed cache is written to disk.
+const DefaultPersistInterval = time.Minute
+
+// Entry is the last known state of a group address.
+type Entry struct {
+	Value     interface{} `json:"value,omitempty"`
+	Bytes     []byte      `json:"bytes"`
+	Timestamp time.Time   `json:"timestamp"`
+	Source    string      `json:"source"`
+}
+
+// Cache keeps the last value seen on the bus per group address.
+type Cache struct {
+	mu      sync.RWMutex
+	entries map[models.FlatGroupAddress]Entry
+	path    string
+	dirty   bool
+}
+
+// NewCache creates a state cache. If path is not empty, entries are loaded from and
+// persisted to that file.
+func NewCache(path string) (*Cache, error) {
+	c := &Cache{
+		entries: make(map[models.FlatGroupAddress]Entry),
+		path:    path,
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read state cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse state cache: %w", err)
+	}
+	log.Info().Int("entries", len(c.entries)).Str("file", path).Msg("Loaded state cache")
+	return c, nil
+}
+
+// Update stores entry as the current state of address.
+func (c *Cache) Update(address models.FlatGroupAddress, entry Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[address] = entry
+	c.dirty = true
+}
+
+// Get returns the current state of address.
+func (c *Cache) Get(address models.FlatGroupAddress) (Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[address]
+	return entry, ok
+}
+
+// Run persists the cache every interval while it has changes until ctx is done.
+func (c *Cache) Run(ctx context.Context, interval time.Duration) {
+	if c.path == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultPersistInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Save(); err != nil {
+				log.Error().Err(err).Msg("Failed to persist state cache")
+			}
+		}
+	}
+}
+
+// Save writes the cache to disk if it changed since it was last saved.
+func (c *Cache) Save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	c.dirty = false
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated cache behind.
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+	}
+	return err
+}
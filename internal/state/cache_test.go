@@ -0,0 +1,58 @@
+package state
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pakerfeldt/knx-mqtt/internal/models"
+)
+
+func TestCacheUpdateAndGet(t *testing.T) {
+	cache, err := NewCache("")
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	if _, ok := cache.Get(1); ok {
+		t.Error("expected empty cache")
+	}
+
+	cache.Update(1, Entry{Value: true, Bytes: []byte{1}, Source: "1.1.5"})
+	cache.Update(1, Entry{Value: false, Bytes: []byte{0}, Source: "1.1.6"})
+
+	entry, ok := cache.Get(1)
+	if !ok {
+		t.Fatal("expected cached entry")
+	}
+	if entry.Value != false || !bytes.Equal(entry.Bytes, []byte{0}) || entry.Source != "1.1.6" {
+		t.Errorf("Get() = %+v, want latest update", entry)
+	}
+}
+
+func TestCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	address := models.FlatGroupAddress(1<<11 | 2<<8 | 3)
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	cache, err := NewCache(path)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	cache.Update(address, Entry{Value: 21.5, Bytes: []byte{0x0c, 0x33}, Timestamp: timestamp, Source: "1.1.5"})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	restored, err := NewCache(path)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	entry, ok := restored.Get(address)
+	if !ok {
+		t.Fatal("expected entry to be restored")
+	}
+	if !bytes.Equal(entry.Bytes, []byte{0x0c, 0x33}) || !entry.Timestamp.Equal(timestamp) || entry.Source != "1.1.5" {
+		t.Errorf("restored entry = %+v", entry)
+	}
+}
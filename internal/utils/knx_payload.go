@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RawUnit is the unit of JSON payloads giving the raw bus value of a scaled datapoint type,
+// e.g. {"value":128,"unit":"raw"} for 5.001.
+const RawUnit = "raw"
+
+// ParseCommandPayload returns the value and unit of a payload written from MQTT. Besides the value as string,
+// a JSON object with the value and optionally its unit is accepted, e.g. {"value":21.5}, {"value":"on"} or
+// {"value":50,"unit":"%"}. The value is returned in the string form expected by PackString. Other payloads,
+// including the JSON objects of composite datapoint types, are returned unchanged without unit.
+func ParseCommandPayload(payload []byte) (string, string, error) {
+	trimmed := bytes.TrimSpace(payload)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		return string(payload), "", nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return string(payload), "", nil
+	}
+	rawValue, ok := fields["value"]
+	if !ok {
+		return string(payload), "", nil
+	}
+
+	var unit string
+	for key, raw := range fields {
+		switch key {
+		case "value":
+		case "unit":
+			if err := json.Unmarshal(raw, &unit); err != nil {
+				return "", "", fmt.Errorf("unit is not a string: %s", raw)
+			}
+		default:
+			return "", "", fmt.Errorf("unknown field \"%s\" in payload", key)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(rawValue))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, unit, nil
+	case json.Number:
+		return v.String(), unit, nil
+	case bool:
+		return strconv.FormatBool(v), unit, nil
+	case map[string]interface{}:
+		// Composite value, e.g. {"value":{"r":255,"g":0,"b":0}}
+		return string(rawValue), unit, nil
+	case nil:
+		return "", "", fmt.Errorf("value is null")
+	default:
+		return "", "", fmt.Errorf("value %s is not a single value", rawValue)
+	}
+}
+
+// PackRaw packs the raw bus value of a scaled datapoint type, e.g. 0-255 for 5.001.
+func PackRaw(datatype string, value string) ([]byte, error) {
+	switch datatype {
+	case "5.001", "5.003":
+		raw, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("raw value of %s must be 0-255: %s", datatype, value)
+		}
+		return []byte{0, uint8(raw)}, nil
+	}
+	return nil, fmt.Errorf("raw values are not supported for %s", datatype)
+}
+
+// parseBool parses the value of DPT 1.x. Besides the forms of strconv.ParseBool, on/off and yes/no are accepted.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+	return strconv.ParseBool(strings.TrimSpace(value))
+}
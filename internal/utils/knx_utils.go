@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -13,15 +15,10 @@ import (
 )
 
 var regexpGad = regexp.MustCompile(`^\d+\/\d+\/\d+$`)
-var regexpGadOrFlat = regexp.MustCompile(`^\d+\/\d+\/\d+|\d+$`)
+var regexpGadOrFlat = regexp.MustCompile(`^(\d+/\d+(/\d+)?|\d+)$`)
 var regexpFlatGad = regexp.MustCompile(`^\d+$`)
 
-var regexpTimeOfDay = regexp.MustCompile(`^(Monday|Tuesday|Wednesday|Thursday|Friday|Saturday|Sunday)?\s*(\d{2}):(\d{2}):(\d{2})$`)
-var regexpDate = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
 var regexpDateTime = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})(?:\s+(Monday|Tuesday|Wednesday|Thursday|Friday|Saturday|Sunday))?\s+(\d{2}):(\d{2}):(\d{2})(?:\s+\(Summer Time\))?(?:\s+\[(.*)\])?$`)
-var regexpRgb = regexp.MustCompile(`^#([A-Fa-f0-9]{2})([A-Fa-f0-9]{2})([A-Fa-f0-9]{2})$`)
-var regexpXyy = regexp.MustCompile(`^x:\s*(\d+)\s*y:\s*(\d+)\s*Y:\s*(\d+)\s*ColorValid:\s*(true|false),\s*BrightnessValid:\s*(true|false)$`)
-var regexpRgbw = regexp.MustCompile(`^Red:\s*(\d+)\s*Green:\s*(\d+)\s*Blue:\s*(\d+)\s*White:\s*(\d+)\s*RedValid:\s*(true|false),\s*GreenValid:\s*(true|false),\s*BlueValid:\s*(true|false),\s*WhiteValid:\s*(true|false)$`)
 
 var weekdays = map[string]uint8{
 	"Monday": 1, "Tuesday": 2, "Wednesday": 3,
@@ -42,7 +39,7 @@ func IsRegularOrFlatGroupAddress(address string) bool {
 
 func PackString(datatype string, value string) ([]byte, error) {
 	if packFunc, exists := boolPackFunctions[datatype]; exists {
-		boolValue, err := strconv.ParseBool(value)
+		boolValue, err := parseBool(value)
 		if err != nil {
 			return nil, fmt.Errorf("could not convert to boolean: %s", value)
 		}
@@ -109,6 +106,16 @@ func PackString(datatype string, value string) ([]byte, error) {
 		return packFunc(value)
 	}
 
+	// Datapoint types of the local registry parse their text or JSON form themselves
+	if datapoint, ok := localdpt.Produce(datatype); ok {
+		if unmarshaler, ok := datapoint.(encoding.TextUnmarshaler); ok {
+			if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+				return nil, fmt.Errorf("cannot convert \"%s\" to DPT %s: %w", value, datatype, err)
+			}
+			return datapoint.Pack(), nil
+		}
+	}
+
 	return nil, fmt.Errorf("unsupported datatype: %s", datatype)
 }
 
@@ -253,9 +260,6 @@ var uint8PackFunctions = map[string]func(uint8) []byte{
 	"5.005": func(value uint8) []byte { return dpt.DPT_5005(value).Pack() },
 
 	"17.001": func(value uint8) []byte { return dpt.DPT_17001(value).Pack() },
-	"18.001": func(value uint8) []byte { return dpt.DPT_18001(value).Pack() },
-	"20.102": func(value uint8) []byte { return dpt.DPT_20102(value).Pack() },
-	"20.105": func(value uint8) []byte { return dpt.DPT_20105(value).Pack() },
 }
 
 var int8PackFunctions = map[string]func(int8) []byte{
@@ -308,12 +312,18 @@ var int32PackFunctions = map[string]func(int32) []byte{
 
 var stringPackFunctions = map[string]func(string) ([]byte, error){
 	"19.001": func(value string) ([]byte, error) {
-		// Try to parse as ISO format first (e.g. "2023-05-15T14:30:45")
+		// Try to parse as ISO format first (e.g. "2023-05-15T14:30:45+02:00")
 		t, err := time.Parse(time.RFC3339, value)
 		if err == nil {
 			return localdpt.FromTime(t).Pack(), nil
 		}
 
+		// ISO format without time zone is local time (e.g. "2023-05-15T14:30:45")
+		t, err = time.ParseInLocation("2006-01-02T15:04:05", value, time.Local)
+		if err == nil {
+			return localdpt.FromTime(t).Pack(), nil
+		}
+
 		// Try to parse using our custom format
 		matches := regexpDateTime.FindStringSubmatch(value)
 		if matches != nil {
@@ -380,171 +390,53 @@ var stringPackFunctions = map[string]func(string) ([]byte, error){
 		return nil, fmt.Errorf("cannot convert \"%s\" to DPT 19.001 (Date/Time): unrecognized format", value)
 	},
 	"10.001": func(value string) ([]byte, error) {
-		matches := regexpTimeOfDay.FindStringSubmatch(value)
-		if matches == nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 10.001 (Time): unrecognized format", value)
-		}
-
-		var weekday uint8
-		if day, ok := weekdays[matches[1]]; ok {
-			weekday = day
-		}
-
-		hour, _ := strconv.ParseUint(matches[2], 10, 8)
-		minute, _ := strconv.ParseUint(matches[3], 10, 8)
-		second, _ := strconv.ParseUint(matches[4], 10, 8)
-
-		datapoint := dpt.DPT_10001{
-			Weekday: weekday,
-			Hour:    uint8(hour),
-			Minutes: uint8(minute),
-			Seconds: uint8(second),
+		timeOfDay, err := localdpt.ParseTimeOfDay(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 10.001 (Time): %w", value, err)
 		}
+		datapoint, _ := timeOfDay.Datapoint()
 		return datapoint.Pack(), nil
 	},
 	"11.001": func(value string) ([]byte, error) {
-		matches := regexpDate.FindStringSubmatch(value)
-		if matches == nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 11.001 (Date): unrecognized format", value)
-		}
-
-		year, _ := strconv.ParseUint(matches[1], 10, 16)
-		month, _ := strconv.ParseUint(matches[2], 10, 8)
-		day, _ := strconv.ParseUint(matches[3], 10, 8)
-
-		datapoint := dpt.DPT_11001{
-			Year:  uint16(year),
-			Month: uint8(month),
-			Day:   uint8(day),
+		date, err := localdpt.ParseDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 11.001 (Date): %w", value, err)
 		}
-
+		datapoint, _ := date.Datapoint()
 		return datapoint.Pack(), nil
 	},
 	"16.000": func(value string) ([]byte, error) { return dpt.DPT_16000(value).Pack(), nil },
 	"16.001": func(value string) ([]byte, error) { return dpt.DPT_16001(value).Pack(), nil },
 	"28.001": func(value string) ([]byte, error) { return dpt.DPT_28001(value).Pack(), nil },
 	"232.600": func(value string) ([]byte, error) {
-		matches := regexpRgb.FindStringSubmatch(value)
-		if matches == nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 232.600 (Color RGB): unrecognized format", value)
-		}
-
-		red, err := strconv.ParseUint(matches[1], 16, 8)
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 232.600 (Color RGB): red component not a positive integer", value)
-		}
-		green, err := strconv.ParseUint(matches[2], 16, 8)
+		color, err := localdpt.ParseColorRGB(value)
 		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 232.600 (Color RGB): green component not a positive integer", value)
+			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 232.600 (Color RGB): %w", value, err)
 		}
-		blue, err := strconv.ParseUint(matches[3], 16, 8)
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 232.600 (Color RGB): blue component not a positive integer", value)
-		}
-
-		datapoint := dpt.DPT_232600{
-			Red:   uint8(red),
-			Green: uint8(green),
-			Blue:  uint8(blue),
-		}
-
-		return datapoint.Pack(), nil
+		return color.Datapoint().Pack(), nil
 	},
 	"242.600": func(value string) ([]byte, error) {
-		matches := regexpXyy.FindStringSubmatch(value)
-		if matches == nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 242.600 (Color xyY): unrecognized format", value)
-		}
-
-		// Parse the extracted strings to appropriate types
-		x, err := strconv.ParseUint(matches[1], 10, 16)
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 242.600 (Color xyY): x component not a positive integer", value)
-		}
-		y, err := strconv.ParseUint(matches[2], 10, 16)
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 242.600 (Color xyY): y component not a positive integer", value)
-		}
-		yBrightness, err := strconv.ParseUint(matches[3], 10, 8)
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 242.600 (Color xyY): Y brightness component not a positive integer", value)
-		}
-		colorValid, err := strconv.ParseBool(matches[4])
+		color, err := localdpt.ParseColorXyY(value)
 		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 242.600 (Color xyY): color valid component not a boolean", value)
+			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 242.600 (Color xyY): %w", value, err)
 		}
-		brightnessValid, err := strconv.ParseBool(matches[5])
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 242.600 (Color xyY): brightness valid component not a boolean", value)
-		}
-
-		datapoint := dpt.DPT_242600{
-			X:               uint16(x),
-			Y:               uint16(y),
-			YBrightness:     uint8(yBrightness),
-			ColorValid:      colorValid,
-			BrightnessValid: brightnessValid,
-		}
-
-		return datapoint.Pack(), nil
+		return color.Datapoint().Pack(), nil
 	},
 	"251.600": func(value string) ([]byte, error) {
-		matches := regexpRgbw.FindStringSubmatch(value)
-		if matches == nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): unrecognized format", value)
-		}
-
-		// Parse the extracted strings to appropriate types
-		red, err := strconv.ParseUint(matches[1], 10, 8)
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): red component not a positive integer", value)
-		}
-		green, err := strconv.ParseUint(matches[2], 10, 8)
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): green component not a positive integer", value)
-		}
-		blue, err := strconv.ParseUint(matches[3], 10, 8)
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): blue component not a positive integer", value)
-		}
-		white, err := strconv.ParseUint(matches[4], 10, 8)
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): white component not a positive integer", value)
-		}
-		redValid, err := strconv.ParseBool(matches[5])
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): red valid component not a boolean", value)
-		}
-		greenValid, err := strconv.ParseBool(matches[6])
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): green valid component not a boolean", value)
-		}
-		blueValid, err := strconv.ParseBool(matches[7])
-		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): blue valid component not a boolean", value)
-		}
-		whiteValid, err := strconv.ParseBool(matches[8])
+		color, err := localdpt.ParseColorRGBW(value)
 		if err != nil {
-			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): white valid component not a boolean", value)
-		}
-
-		// Create the DPT_251600 instance
-		datapoint := dpt.DPT_251600{
-			Red:        uint8(red),
-			Green:      uint8(green),
-			Blue:       uint8(blue),
-			White:      uint8(white),
-			RedValid:   redValid,
-			GreenValid: greenValid,
-			BlueValid:  blueValid,
-			WhiteValid: whiteValid,
+			return nil, fmt.Errorf("cannot convert \"%s\" to DPT 251.600 (Color RGBW): %w", value, err)
 		}
-
-		return datapoint.Pack(), nil
+		return color.Datapoint().Pack(), nil
 	},
 }
 
 func ExtractDatapointValue(dp dpt.Datapoint, dptType string) interface{} {
+	// Datapoint types of the local registry provide their value themselves
+	if valuer, ok := dp.(localdpt.Valuer); ok {
+		return valuer.Value()
+	}
+
 	mainType := strings.SplitN(dptType, ".", 2)[0]
 	rv := reflect.ValueOf(dp)
 	if rv.Kind() == reflect.Ptr {
@@ -567,6 +459,14 @@ func ExtractDatapointValue(dp dpt.Datapoint, dptType string) interface{} {
 		if rv.Kind() == reflect.Float32 {
 			return float32(rv.Float())
 		}
+	case "10":
+		if d, ok := dp.(*dpt.DPT_10001); ok {
+			return localdpt.NewTimeOfDay(*d)
+		}
+	case "11":
+		if d, ok := dp.(*dpt.DPT_11001); ok {
+			return localdpt.NewDate(*d)
+		}
 	case "12":
 		if rv.Kind() == reflect.Uint32 {
 			return uint32(rv.Uint())
@@ -575,7 +475,12 @@ func ExtractDatapointValue(dp dpt.Datapoint, dptType string) interface{} {
 		if rv.Kind() == reflect.Int32 {
 			return int32(rv.Int())
 		}
-	case "17", "18", "20":
+	case "16", "28":
+		// Strings are kept as they are, including leading and trailing spaces
+		if rv.Kind() == reflect.String {
+			return rv.String()
+		}
+	case "17":
 		if rv.Kind() == reflect.Uint8 {
 			return uint8(rv.Uint())
 		}
@@ -589,6 +494,18 @@ func ExtractDatapointValue(dp dpt.Datapoint, dptType string) interface{} {
 		}
 		// Fallback to string representation if not our custom type
 		return StringWithoutSuffix(dp)
+	case "232":
+		if d, ok := dp.(*dpt.DPT_232600); ok {
+			return localdpt.NewColorRGB(*d)
+		}
+	case "242":
+		if d, ok := dp.(*dpt.DPT_242600); ok {
+			return localdpt.NewColorXyY(*d)
+		}
+	case "251":
+		if d, ok := dp.(*dpt.DPT_251600); ok {
+			return localdpt.NewColorRGBW(*d)
+		}
 	}
 
 	switch dptType {
@@ -608,3 +525,15 @@ func ExtractDatapointValue(dp dpt.Datapoint, dptType string) interface{} {
 
 	return StringWithoutSuffix(dp)
 }
+
+// FormatValue returns a value extracted by ExtractDatapointValue as payload.
+// Structured values, e.g. colors, are formatted as JSON objects.
+func FormatValue(value interface{}) string {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Struct, reflect.Map:
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}